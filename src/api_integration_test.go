@@ -1,14 +1,17 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/gin-gonic/gin"
+	"github.com/zzn2/demo/appstore/auth"
 )
 
 const app1v1 = `
@@ -148,6 +151,48 @@ description: |
  Some application content, and description
 `
 
+const appWithBadLinksAndLicense = `
+title: App7
+version: 0.0.1
+maintainers:
+- name: firstmaintainer app7
+  email: firstmaintainer@hotmail.com
+company: Random Inc.
+website: ftp://website.com
+source: https://website.com/download
+license: MIT OR Apache2
+description: |
+ ### Interesting Title
+ Some application content, and description
+`
+
+const appWithUnknownKeys = `
+unknown: dummy
+title: App6
+version: 0.0.1
+maintainers:
+- name: firstmaintainer app6
+  email: malformed
+  phone: 123
+company: Random Inc.
+website: https://website.com
+source: https://github.com/random/repo
+license: Apache-2.0
+description: |
+ ### Interesting Title
+ Some application content, and description
+`
+
+const savedSearchApp1 = `
+name: app1-latest
+filter: title=App1
+sort:
+- -version
+fields:
+- title
+- version
+`
+
 type Request struct {
 	method string
 	url    string
@@ -170,18 +215,19 @@ var scenarios = []struct {
 		},
 		201,
 		`{
-			"Title":"App1",
-			"Version":"0.0.1",
-			"Maintainers":
+			"title":"App1",
+			"version":"0.0.1",
+			"maintainers":
 			[
-				{"Name":"firstmaintainer app1","Email":"firstmaintainer@hotmail.com"},
-				{"Name":"secondmaintainer app1","Email":"secondmaintainer@gmail.com"}
+				{"name":"firstmaintainer app1","email":"firstmaintainer@hotmail.com"},
+				{"name":"secondmaintainer app1","email":"secondmaintainer@gmail.com"}
 			],
-			"Company":"Random Inc.",
-			"Website":"https://website.com",
-			"Source":"https://github.com/random/repo",
-			"License":"Apache-2.0",
-			"Description":"### Interesting Title\nSome application content, and description\n"
+			"company":"Random Inc.",
+			"website":"https://website.com",
+			"source":"https://github.com/random/repo",
+			"license":"Apache-2.0",
+			"description":"### Interesting Title\nSome application content, and description\n",
+			"slug":"app1","links":{"self":"/v1/apps/app1/versions/0.0.1","latest":"/v1/apps/app1","versions":"/v1/apps?title=App1"}
 		}`,
 	},
 	{
@@ -192,28 +238,68 @@ var scenarios = []struct {
 		},
 		201,
 		`{
-			"Title":"App1",
-			"Version":"0.0.2",
-			"Maintainers":
+			"title":"App1",
+			"version":"0.0.2",
+			"maintainers":
 			[
-				{"Name":"firstmaintainer app1","Email":"firstmaintainer@hotmail.com"},
-				{"Name":"secondmaintainer app1","Email":"secondmaintainer@gmail.com"}
+				{"name":"firstmaintainer app1","email":"firstmaintainer@hotmail.com"},
+				{"name":"secondmaintainer app1","email":"secondmaintainer@gmail.com"}
 			],
-			"Company":"Random Inc.",
-			"Website":"https://website.com",
-			"Source":"https://github.com/random/repo",
-			"License":"Apache-2.0",
-			"Description":"### Interesting Title\nSome application content, and description\n"
+			"company":"Random Inc.",
+			"website":"https://website.com",
+			"source":"https://github.com/random/repo",
+			"license":"Apache-2.0",
+			"description":"### Interesting Title\nSome application content, and description\n",
+			"slug":"app1","links":{"self":"/v1/apps/app1/versions/0.0.2","latest":"/v1/apps/app1","versions":"/v1/apps?title=App1"}
 		}`,
 	},
 	{
-		"Create app with existing name and version, response 409 Conflict",
+		"Create app with existing name and version and identical content, response 200 with the existing app",
 		[]Request{
 			{"POST", "/apps", app1v1},
 			{"POST", "/apps", app1v1},
 		},
+		200,
+		`{"title":"App1","version":"0.0.1","maintainers":[{"name":"firstmaintainer app1","email":"firstmaintainer@hotmail.com"},{"name":"secondmaintainer app1","email":"secondmaintainer@gmail.com"}],"company":"Random Inc.","website":"https://website.com","source":"https://github.com/random/repo","license":"Apache-2.0","description":"### Interesting Title\nSome application content, and description\n","slug":"app1","links":{"self":"/v1/apps/app1/versions/0.0.1","latest":"/v1/apps/app1","versions":"/v1/apps?title=App1"}}`,
+	},
+	{
+		"Create app with existing name and version and content differing only in whitespace, response 200 with the existing app",
+		[]Request{
+			{"POST", "/apps", app1v1},
+			{"POST", "/apps", strings.Replace(app1v1, "company: Random Inc.", "company: \"  Random   Inc. \"", 1)},
+		},
+		200,
+		`{"title":"App1","version":"0.0.1","maintainers":[{"name":"firstmaintainer app1","email":"firstmaintainer@hotmail.com"},{"name":"secondmaintainer app1","email":"secondmaintainer@gmail.com"}],"company":"Random Inc.","website":"https://website.com","source":"https://github.com/random/repo","license":"Apache-2.0","description":"### Interesting Title\nSome application content, and description\n","slug":"app1","links":{"self":"/v1/apps/app1/versions/0.0.1","latest":"/v1/apps/app1","versions":"/v1/apps?title=App1"}}`,
+	},
+	{
+		"Create app with existing name and version and different content, response 409 Conflict with diff",
+		[]Request{
+			{"POST", "/apps", app1v1},
+			{"POST", "/apps", strings.Replace(app1v1, "company: Random Inc.", "company: Other Inc.", 1)},
+		},
+		409,
+		`{"detail":"App 'App1' with version '0.0.1' already exists.","diff":[{"field":"Company","existing":"Random Inc.","posted":"Other Inc."}],"status":409,"title":"App version already exists","type":"/problems/duplicate-version"}`,
+	},
+	{
+		"Create app with existing title in different case, response 409 Conflict",
+		[]Request{
+			{"POST", "/apps", app1v1},
+			{"POST", "/apps", strings.Replace(app1v2, "title: App1", "title: app1", 1)},
+		},
 		409,
-		`{"error":"App 'App1' with version '0.0.1' already exists."}`,
+		`{"detail":"App title 'app1' conflicts with the existing app 'App1'. Titles must be unique regardless of case and punctuation.","status":409,"title":"App title conflicts with another app","type":"/problems/title-conflict"}`,
+	},
+	{
+		"Create app with title without letters or digits, response 400",
+		[]Request{
+			{"POST", "/apps", strings.Replace(app1v1, "title: App1", "title: '!!!'", 1)},
+		},
+		400,
+		`{
+			"detail":"The request body has 1 invalid field(s): 'title' must contain at least one letter or digit.",
+			"errors":[{"path":"title","rule":"slug","message":"'title' must contain at least one letter or digit."}],
+			"status":400,"title":"Validation failed","type":"/problems/validation-failed"
+		}`,
 	},
 	{
 		"Create app without title, response 400",
@@ -221,7 +307,11 @@ var scenarios = []struct {
 			{"POST", "/apps", appWithoutTitle},
 		},
 		400,
-		`{"error":"Key: 'Meta.Title' Error:Field validation for 'Title' failed on the 'required' tag"}`,
+		`{
+			"detail":"The request body has 1 invalid field(s): 'title' is required.",
+			"errors":[{"path":"title","rule":"required","message":"'title' is required."}],
+			"status":400,"title":"Validation failed","type":"/problems/validation-failed"
+		}`,
 	},
 	{
 		"Create app without version, response 400",
@@ -229,7 +319,11 @@ var scenarios = []struct {
 			{"POST", "/apps", appWithoutVersion},
 		},
 		400,
-		`{"error":"App 'App4' lacks of version or the version could not be '0.0.0'.)"}`,
+		`{
+			"detail":"The request body has 1 invalid field(s): 'version' is required.",
+			"errors":[{"path":"version","rule":"required","message":"'version' is required."}],
+			"status":400,"title":"Validation failed","type":"/problems/validation-failed"
+		}`,
 	},
 	{
 		"Create app with bad version, response 400",
@@ -237,7 +331,7 @@ var scenarios = []struct {
 			{"POST", "/apps", appWithBadVersion},
 		},
 		400,
-		`{"error":"Failed to parse version '0.0.a': Invalid character(s) found in number \"a\""}`,
+		`{"detail":"Failed to parse version '0.0.a': Invalid character(s) found in number \"a\"","status":400,"title":"Invalid version","type":"/problems/invalid-version"}`,
 	},
 	{
 		"Create app with bad maintainer email, response 400",
@@ -245,7 +339,11 @@ var scenarios = []struct {
 			{"POST", "/apps", appWithBadMaintainerEmail},
 		},
 		400,
-		`{"error":"Key: 'Meta.Maintainers[0].Email' Error:Field validation for 'Email' failed on the 'email' tag"}`,
+		`{
+			"detail":"The request body has 1 invalid field(s): 'maintainers[0].email' must be a valid email address.",
+			"errors":[{"path":"maintainers[0].email","rule":"email","message":"'maintainers[0].email' must be a valid email address."}],
+			"status":400,"title":"Validation failed","type":"/problems/validation-failed"
+		}`,
 	},
 	{
 		"Create app with multiple bad fields, response 400",
@@ -253,7 +351,46 @@ var scenarios = []struct {
 			{"POST", "/apps", appWithMultipleBadFields},
 		},
 		400,
-		`{"error":"Key: 'Meta.Maintainers' Error:Field validation for 'Maintainers' failed on the 'required' tag\nKey: 'Meta.Website' Error:Field validation for 'Website' failed on the 'url' tag"}`,
+		`{
+			"detail":"The request body has 2 invalid field(s): 'maintainers' is required. 'website' must be an http or https URL.",
+			"errors":[
+				{"path":"maintainers","rule":"required","message":"'maintainers' is required."},
+				{"path":"website","rule":"httpurl","message":"'website' must be an http or https URL."}
+			],
+			"status":400,"title":"Validation failed","type":"/problems/validation-failed"
+		}`,
+	},
+	{
+		"Create app with bad website, source and license, response 400",
+		[]Request{
+			{"POST", "/apps", appWithBadLinksAndLicense},
+		},
+		400,
+		`{
+			"detail":"The request body has 3 invalid field(s): 'website' must be an http or https URL. 'source' must be the http or https URL of a repository, e.g. 'https://github.com/owner/repo', or end with '.git'. 'license' must be a valid SPDX license expression, e.g. 'MIT OR Apache-2.0': Unknown license 'Apache2'.",
+			"errors":[
+				{"path":"website","rule":"httpurl","message":"'website' must be an http or https URL."},
+				{"path":"source","rule":"vcsurl","message":"'source' must be the http or https URL of a repository, e.g. 'https://github.com/owner/repo', or end with '.git'."},
+				{"path":"license","rule":"spdx","message":"'license' must be a valid SPDX license expression, e.g. 'MIT OR Apache-2.0': Unknown license 'Apache2'."}
+			],
+			"status":400,"title":"Validation failed","type":"/problems/validation-failed"
+		}`,
+	},
+	{
+		"Create app with unknown keys, response 400",
+		[]Request{
+			{"POST", "/apps", appWithUnknownKeys},
+		},
+		400,
+		`{
+			"detail":"The request body has 3 invalid field(s): 'unknown' is not a known field. 'maintainers[0].phone' is not a known field. 'maintainers[0].email' must be a valid email address.",
+			"errors":[
+				{"path":"unknown","rule":"unknown","message":"'unknown' is not a known field."},
+				{"path":"maintainers[0].phone","rule":"unknown","message":"'maintainers[0].phone' is not a known field."},
+				{"path":"maintainers[0].email","rule":"email","message":"'maintainers[0].email' must be a valid email address."}
+			],
+			"status":400,"title":"Validation failed","type":"/problems/validation-failed"
+		}`,
 	},
 
 	// -----------------------------------------------------------
@@ -269,18 +406,19 @@ var scenarios = []struct {
 		},
 		200,
 		`{
-			"Title":"App1",
-			"Version":"0.0.2",
-			"Maintainers":
+			"title":"App1",
+			"version":"0.0.2",
+			"maintainers":
 			[
-				{"Name":"firstmaintainer app1","Email":"firstmaintainer@hotmail.com"},
-				{"Name":"secondmaintainer app1","Email":"secondmaintainer@gmail.com"}
+				{"name":"firstmaintainer app1","email":"firstmaintainer@hotmail.com"},
+				{"name":"secondmaintainer app1","email":"secondmaintainer@gmail.com"}
 			],
-			"Company":"Random Inc.",
-			"Website":"https://website.com",
-			"Source":"https://github.com/random/repo",
-			"License":"Apache-2.0",
-			"Description":"### Interesting Title\nSome application content, and description\n"
+			"company":"Random Inc.",
+			"website":"https://website.com",
+			"source":"https://github.com/random/repo",
+			"license":"Apache-2.0",
+			"description":"### Interesting Title\nSome application content, and description\n",
+			"slug":"app1","links":{"self":"/v1/apps/app1/versions/0.0.2","latest":"/v1/apps/app1","versions":"/v1/apps?title=App1"}
 		}`,
 	},
 	{
@@ -293,18 +431,19 @@ var scenarios = []struct {
 		},
 		200,
 		`{
-			"Title":"App1",
-			"Version":"0.0.1",
-			"Maintainers":
+			"title":"App1",
+			"version":"0.0.1",
+			"maintainers":
 			[
-				{"Name":"firstmaintainer app1","Email":"firstmaintainer@hotmail.com"},
-				{"Name":"secondmaintainer app1","Email":"secondmaintainer@gmail.com"}
+				{"name":"firstmaintainer app1","email":"firstmaintainer@hotmail.com"},
+				{"name":"secondmaintainer app1","email":"secondmaintainer@gmail.com"}
 			],
-			"Company":"Random Inc.",
-			"Website":"https://website.com",
-			"Source":"https://github.com/random/repo",
-			"License":"Apache-2.0",
-			"Description":"### Interesting Title\nSome application content, and description\n"
+			"company":"Random Inc.",
+			"website":"https://website.com",
+			"source":"https://github.com/random/repo",
+			"license":"Apache-2.0",
+			"description":"### Interesting Title\nSome application content, and description\n",
+			"slug":"app1","links":{"self":"/v1/apps/app1/versions/0.0.1","latest":"/v1/apps/app1","versions":"/v1/apps?title=App1"}
 		}`,
 	},
 	{
@@ -315,18 +454,19 @@ var scenarios = []struct {
 		},
 		200,
 		`{
-			"Title":"App3 with space in title",
-			"Version":"0.0.1",
-			"Maintainers":
+			"title":"App3 with space in title",
+			"version":"0.0.1",
+			"maintainers":
 			[
-				{"Name":"firstmaintainer app3","Email":"firstmaintainer@hotmail.com"},
-				{"Name":"secondmaintainer app3","Email":"secondmaintainer@gmail.com"}
+				{"name":"firstmaintainer app3","email":"firstmaintainer@hotmail.com"},
+				{"name":"secondmaintainer app3","email":"secondmaintainer@gmail.com"}
 			],
-			"Company":"Random Inc.",
-			"Website":"https://website.com",
-			"Source":"https://github.com/random/repo",
-			"License":"Apache-2.0",
-			"Description":"### Interesting Title\nSome application content, and description\n"
+			"company":"Random Inc.",
+			"website":"https://website.com",
+			"source":"https://github.com/random/repo",
+			"license":"Apache-2.0",
+			"description":"### Interesting Title\nSome application content, and description\n",
+			"slug":"app3-with-space-in-title","links":{"self":"/v1/apps/app3-with-space-in-title/versions/0.0.1","latest":"/v1/apps/app3-with-space-in-title","versions":"/v1/apps?title=App3+with+space+in+title"}
 		}`,
 	},
 	{
@@ -338,7 +478,18 @@ var scenarios = []struct {
 			{"GET", "/apps/App6", ""},
 		},
 		404,
-		`{"error":"App with title 'App6' does not exist."}`,
+		`{"detail":"App with title 'App6' does not exist.","status":404,"suggestions":["App1","App2"],"title":"App not found","type":"/problems/app-not-found"}`,
+	},
+	{
+		"Show a misspelled app, response 404 with suggestions",
+		[]Request{
+			{"POST", "/apps", app1v1},
+			{"POST", "/apps", app2v1},
+			{"POST", "/apps", app3WithSpaceInTitle},
+			{"GET", "/apps/Ap1", ""},
+		},
+		404,
+		`{"detail":"App with title 'Ap1' does not exist.","status":404,"suggestions":["App1"],"title":"App not found","type":"/problems/app-not-found"}`,
 	},
 	{
 		"Show a non-exist version of an existing app, response 404",
@@ -349,7 +500,7 @@ var scenarios = []struct {
 			{"GET", "/apps/App1/versions/0.0.3", ""},
 		},
 		404,
-		`{"error":"App with title 'App1' and version '0.0.3' does not exist."}`,
+		`{"detail":"App with title 'App1' and version '0.0.3' does not exist.","status":404,"title":"App not found","type":"/problems/app-not-found"}`,
 	},
 
 	// -----------------------------------------------------------
@@ -374,9 +525,9 @@ var scenarios = []struct {
 		},
 		200,
 		`[
-			{"Title":"App1","Version":"0.0.1","Maintainers":[{"Name":"firstmaintainer app1","Email":"firstmaintainer@hotmail.com"},{"Name":"secondmaintainer app1","Email":"secondmaintainer@gmail.com"}],"Company":"Random Inc.","Website":"https://website.com","Source":"https://github.com/random/repo","License":"Apache-2.0","Description":"### Interesting Title\nSome application content, and description\n"},
-			{"Title":"App1","Version":"0.0.2","Maintainers":[{"Name":"firstmaintainer app1","Email":"firstmaintainer@hotmail.com"},{"Name":"secondmaintainer app1","Email":"secondmaintainer@gmail.com"}],"Company":"Random Inc.","Website":"https://website.com","Source":"https://github.com/random/repo","License":"Apache-2.0","Description":"### Interesting Title\nSome application content, and description\n"},
-			{"Title":"App2","Version":"0.0.1","Maintainers":[{"Name":"firstmaintainer app2","Email":"firstmaintainer@hotmail.com"},{"Name":"secondmaintainer app2","Email":"secondmaintainer@gmail.com"}],"Company":"Random Inc.","Website":"https://website.com","Source":"https://github.com/random/repo","License":"Apache-2.0","Description":"### Interesting Title\nSome application content, and description\n"}
+			{"title":"App1","version":"0.0.1","maintainers":[{"name":"firstmaintainer app1","email":"firstmaintainer@hotmail.com"},{"name":"secondmaintainer app1","email":"secondmaintainer@gmail.com"}],"company":"Random Inc.","website":"https://website.com","source":"https://github.com/random/repo","license":"Apache-2.0","description":"### Interesting Title\nSome application content, and description\n","slug":"app1","links":{"self":"/v1/apps/app1/versions/0.0.1","latest":"/v1/apps/app1","versions":"/v1/apps?title=App1"}},
+			{"title":"App1","version":"0.0.2","maintainers":[{"name":"firstmaintainer app1","email":"firstmaintainer@hotmail.com"},{"name":"secondmaintainer app1","email":"secondmaintainer@gmail.com"}],"company":"Random Inc.","website":"https://website.com","source":"https://github.com/random/repo","license":"Apache-2.0","description":"### Interesting Title\nSome application content, and description\n","slug":"app1","links":{"self":"/v1/apps/app1/versions/0.0.2","latest":"/v1/apps/app1","versions":"/v1/apps?title=App1"}},
+			{"title":"App2","version":"0.0.1","maintainers":[{"name":"firstmaintainer app2","email":"firstmaintainer@hotmail.com"},{"name":"secondmaintainer app2","email":"secondmaintainer@gmail.com"}],"company":"Random Inc.","website":"https://website.com","source":"https://github.com/random/repo","license":"Apache-2.0","description":"### Interesting Title\nSome application content, and description\n","slug":"app2","links":{"self":"/v1/apps/app2/versions/0.0.1","latest":"/v1/apps/app2","versions":"/v1/apps?title=App2"}}
 		]`,
 	},
 	{
@@ -389,8 +540,8 @@ var scenarios = []struct {
 		},
 		200,
 		`[
-			{"Title":"App1","Version":"0.0.1","Maintainers":[{"Name":"firstmaintainer app1","Email":"firstmaintainer@hotmail.com"},{"Name":"secondmaintainer app1","Email":"secondmaintainer@gmail.com"}],"Company":"Random Inc.","Website":"https://website.com","Source":"https://github.com/random/repo","License":"Apache-2.0","Description":"### Interesting Title\nSome application content, and description\n"},
-			{"Title":"App1","Version":"0.0.2","Maintainers":[{"Name":"firstmaintainer app1","Email":"firstmaintainer@hotmail.com"},{"Name":"secondmaintainer app1","Email":"secondmaintainer@gmail.com"}],"Company":"Random Inc.","Website":"https://website.com","Source":"https://github.com/random/repo","License":"Apache-2.0","Description":"### Interesting Title\nSome application content, and description\n"}
+			{"title":"App1","version":"0.0.1","maintainers":[{"name":"firstmaintainer app1","email":"firstmaintainer@hotmail.com"},{"name":"secondmaintainer app1","email":"secondmaintainer@gmail.com"}],"company":"Random Inc.","website":"https://website.com","source":"https://github.com/random/repo","license":"Apache-2.0","description":"### Interesting Title\nSome application content, and description\n","slug":"app1","links":{"self":"/v1/apps/app1/versions/0.0.1","latest":"/v1/apps/app1","versions":"/v1/apps?title=App1"}},
+			{"title":"App1","version":"0.0.2","maintainers":[{"name":"firstmaintainer app1","email":"firstmaintainer@hotmail.com"},{"name":"secondmaintainer app1","email":"secondmaintainer@gmail.com"}],"company":"Random Inc.","website":"https://website.com","source":"https://github.com/random/repo","license":"Apache-2.0","description":"### Interesting Title\nSome application content, and description\n","slug":"app1","links":{"self":"/v1/apps/app1/versions/0.0.2","latest":"/v1/apps/app1","versions":"/v1/apps?title=App1"}}
 		]`,
 	},
 	{
@@ -403,7 +554,7 @@ var scenarios = []struct {
 		},
 		200,
 		`[
-			{"Title":"App2","Version":"0.0.1","Maintainers":[{"Name":"firstmaintainer app2","Email":"firstmaintainer@hotmail.com"},{"Name":"secondmaintainer app2","Email":"secondmaintainer@gmail.com"}],"Company":"Random Inc.","Website":"https://website.com","Source":"https://github.com/random/repo","License":"Apache-2.0","Description":"### Interesting Title\nSome application content, and description\n"}
+			{"title":"App2","version":"0.0.1","maintainers":[{"name":"firstmaintainer app2","email":"firstmaintainer@hotmail.com"},{"name":"secondmaintainer app2","email":"secondmaintainer@gmail.com"}],"company":"Random Inc.","website":"https://website.com","source":"https://github.com/random/repo","license":"Apache-2.0","description":"### Interesting Title\nSome application content, and description\n","slug":"app2","links":{"self":"/v1/apps/app2/versions/0.0.1","latest":"/v1/apps/app2","versions":"/v1/apps?title=App2"}}
 		]`,
 	},
 	{
@@ -427,9 +578,21 @@ var scenarios = []struct {
 		},
 		200,
 		`[
-			{"Title":"App1","Version":"0.0.1","Maintainers":[{"Name":"firstmaintainer app1","Email":"firstmaintainer@hotmail.com"},{"Name":"secondmaintainer app1","Email":"secondmaintainer@gmail.com"}],"Company":"Random Inc.","Website":"https://website.com","Source":"https://github.com/random/repo","License":"Apache-2.0","Description":"### Interesting Title\nSome application content, and description\n"},
-			{"Title":"App1","Version":"0.0.2","Maintainers":[{"Name":"firstmaintainer app1","Email":"firstmaintainer@hotmail.com"},{"Name":"secondmaintainer app1","Email":"secondmaintainer@gmail.com"}],"Company":"Random Inc.","Website":"https://website.com","Source":"https://github.com/random/repo","License":"Apache-2.0","Description":"### Interesting Title\nSome application content, and description\n"},
-			{"Title":"App2","Version":"0.0.1","Maintainers":[{"Name":"firstmaintainer app2","Email":"firstmaintainer@hotmail.com"},{"Name":"secondmaintainer app2","Email":"secondmaintainer@gmail.com"}],"Company":"Random Inc.","Website":"https://website.com","Source":"https://github.com/random/repo","License":"Apache-2.0","Description":"### Interesting Title\nSome application content, and description\n"}
+			{"title":"App1","version":"0.0.1","maintainers":[{"name":"firstmaintainer app1","email":"firstmaintainer@hotmail.com"},{"name":"secondmaintainer app1","email":"secondmaintainer@gmail.com"}],"company":"Random Inc.","website":"https://website.com","source":"https://github.com/random/repo","license":"Apache-2.0","description":"### Interesting Title\nSome application content, and description\n","slug":"app1","links":{"self":"/v1/apps/app1/versions/0.0.1","latest":"/v1/apps/app1","versions":"/v1/apps?title=App1"}},
+			{"title":"App1","version":"0.0.2","maintainers":[{"name":"firstmaintainer app1","email":"firstmaintainer@hotmail.com"},{"name":"secondmaintainer app1","email":"secondmaintainer@gmail.com"}],"company":"Random Inc.","website":"https://website.com","source":"https://github.com/random/repo","license":"Apache-2.0","description":"### Interesting Title\nSome application content, and description\n","slug":"app1","links":{"self":"/v1/apps/app1/versions/0.0.2","latest":"/v1/apps/app1","versions":"/v1/apps?title=App1"}},
+			{"title":"App2","version":"0.0.1","maintainers":[{"name":"firstmaintainer app2","email":"firstmaintainer@hotmail.com"},{"name":"secondmaintainer app2","email":"secondmaintainer@gmail.com"}],"company":"Random Inc.","website":"https://website.com","source":"https://github.com/random/repo","license":"Apache-2.0","description":"### Interesting Title\nSome application content, and description\n","slug":"app2","links":{"self":"/v1/apps/app2/versions/0.0.1","latest":"/v1/apps/app2","versions":"/v1/apps?title=App2"}}
+		]`,
+	},
+	{
+		"List apps, fuzzy match",
+		[]Request{
+			{"POST", "/apps", app1v1},
+			{"POST", "/apps", app2v1},
+			{"GET", "/apps?title[fuzzy]=Ap1", ""},
+		},
+		200,
+		`[
+			{"title":"App1","version":"0.0.1","maintainers":[{"name":"firstmaintainer app1","email":"firstmaintainer@hotmail.com"},{"name":"secondmaintainer app1","email":"secondmaintainer@gmail.com"}],"company":"Random Inc.","website":"https://website.com","source":"https://github.com/random/repo","license":"Apache-2.0","description":"### Interesting Title\nSome application content, and description\n","slug":"app1","links":{"self":"/v1/apps/app1/versions/0.0.1","latest":"/v1/apps/app1","versions":"/v1/apps?title=App1"}}
 		]`,
 	},
 	{
@@ -442,7 +605,7 @@ var scenarios = []struct {
 		},
 		200,
 		`[
-			{"Title":"App1","Version":"0.0.1","Maintainers":[{"Name":"firstmaintainer app1","Email":"firstmaintainer@hotmail.com"},{"Name":"secondmaintainer app1","Email":"secondmaintainer@gmail.com"}],"Company":"Random Inc.","Website":"https://website.com","Source":"https://github.com/random/repo","License":"Apache-2.0","Description":"### Interesting Title\nSome application content, and description\n"}
+			{"title":"App1","version":"0.0.1","maintainers":[{"name":"firstmaintainer app1","email":"firstmaintainer@hotmail.com"},{"name":"secondmaintainer app1","email":"secondmaintainer@gmail.com"}],"company":"Random Inc.","website":"https://website.com","source":"https://github.com/random/repo","license":"Apache-2.0","description":"### Interesting Title\nSome application content, and description\n","slug":"app1","links":{"self":"/v1/apps/app1/versions/0.0.1","latest":"/v1/apps/app1","versions":"/v1/apps?title=App1"}}
 		]`,
 	},
 	{
@@ -455,7 +618,7 @@ var scenarios = []struct {
 		},
 		200,
 		`[
-			{"Title":"App1","Version":"0.0.2","Maintainers":[{"Name":"firstmaintainer app1","Email":"firstmaintainer@hotmail.com"},{"Name":"secondmaintainer app1","Email":"secondmaintainer@gmail.com"}],"Company":"Random Inc.","Website":"https://website.com","Source":"https://github.com/random/repo","License":"Apache-2.0","Description":"### Interesting Title\nSome application content, and description\n"}
+			{"title":"App1","version":"0.0.2","maintainers":[{"name":"firstmaintainer app1","email":"firstmaintainer@hotmail.com"},{"name":"secondmaintainer app1","email":"secondmaintainer@gmail.com"}],"company":"Random Inc.","website":"https://website.com","source":"https://github.com/random/repo","license":"Apache-2.0","description":"### Interesting Title\nSome application content, and description\n","slug":"app1","links":{"self":"/v1/apps/app1/versions/0.0.2","latest":"/v1/apps/app1","versions":"/v1/apps?title=App1"}}
 		]`,
 	},
 	{
@@ -467,7 +630,7 @@ var scenarios = []struct {
 			{"GET", "/apps?title[lt]=App1", ""},
 		},
 		400,
-		`{"error":"Failed to create rule: Type 'string' does not support 'LessThan' operator"}`,
+		`{"detail":"Failed to create rule: Type 'string' does not support 'LessThan' operator","field":"title","param":"title[lt]","status":400,"title":"Operator does not support the field type","type":"/problems/operator-type-mismatch"}`,
 	},
 	{
 		"List apps, filter with bad field names",
@@ -478,7 +641,7 @@ var scenarios = []struct {
 			{"GET", "/apps?title=App1&dummy=unknown", ""},
 		},
 		400,
-		`{"error":"Failed to create rule: Field with name 'dummy' does not exist."}`,
+		`{"detail":"Failed to create rule: Field with name 'dummy' does not exist.","field":"dummy","param":"dummy","status":400,"title":"Unknown field","type":"/problems/unknown-field"}`,
 	},
 	{
 		"List apps, filter with bad operator",
@@ -489,7 +652,210 @@ var scenarios = []struct {
 			{"GET", "/apps?title=App1&version[dummy]=0.0.1", ""},
 		},
 		400,
-		`{"error":"Unrecognized operator type 'dummy'"}`,
+		`{"detail":"Unrecognized operator type 'dummy'","field":"version","param":"version[dummy]","status":400,"title":"Unknown operator","type":"/problems/unknown-operator"}`,
+	},
+	{
+		"List apps, filter with an operator only available in policies",
+		[]Request{
+			{"POST", "/apps", app1v1},
+			{"GET", "/apps?description[minlen]=10", ""},
+		},
+		400,
+		`{"detail":"Unrecognized operator type 'minlen'","field":"description","param":"description[minlen]","status":400,"title":"Unknown operator","type":"/problems/unknown-operator"}`,
+	},
+
+	// -----------------------------------------------------------
+	// Bulk import
+	// -----------------------------------------------------------
+	{
+		"Import apps in bulk, report result of each record",
+		[]Request{
+			{"POST", "/apps", app2v1},
+			{"POST", "/apps/_bulk", app1v1 + "---" + app2v1 + "---" + appWithBadMaintainerEmail},
+		},
+		200,
+		`{"line":2,"status":"created","title":"App1","version":"0.0.1"}
+		{"line":17,"status":"conflict","title":"App2","version":"0.0.1","error":"App 'App2' with version '0.0.1' already exists."}
+		{"line":32,"status":"invalid","title":"App5","error":"The request body has 1 invalid field(s): 'maintainers[0].email' must be a valid email address.","errors":[{"path":"maintainers[0].email","rule":"email","message":"'maintainers[0].email' must be a valid email address."}]}
+		`,
+	},
+	{
+		"Import apps in bulk, title without letters or digits is invalid",
+		[]Request{
+			{"POST", "/apps/_bulk", strings.Replace(app1v1, "title: App1", `title: "!!!"`, 1)},
+		},
+		200,
+		`{"line":2,"status":"invalid","title":"!!!","error":"The request body has 1 invalid field(s): 'title' must contain at least one letter or digit.","errors":[{"path":"title","rule":"slug","message":"'title' must contain at least one letter or digit."}]}
+		`,
+	},
+	{
+		"Import apps in bulk, unknown keys are invalid as in POST /apps",
+		[]Request{
+			{"POST", "/apps/_bulk", app1v1 + "unknown: value\n"},
+		},
+		200,
+		`{"line":2,"status":"invalid","title":"App1","error":"The request body has 1 invalid field(s): 'unknown' is not a known field.","errors":[{"path":"unknown","rule":"unknown","message":"'unknown' is not a known field."}]}
+		`,
+	},
+	{
+		"Import apps in bulk, created apps could be retrieved",
+		[]Request{
+			{"POST", "/apps/_bulk", app1v1 + "---" + app1v2},
+			{"GET", "/apps/App1", ""},
+		},
+		200,
+		`{
+			"title":"App1",
+			"version":"0.0.2",
+			"maintainers":
+			[
+				{"name":"firstmaintainer app1","email":"firstmaintainer@hotmail.com"},
+				{"name":"secondmaintainer app1","email":"secondmaintainer@gmail.com"}
+			],
+			"company":"Random Inc.",
+			"website":"https://website.com",
+			"source":"https://github.com/random/repo",
+			"license":"Apache-2.0",
+			"description":"### Interesting Title\nSome application content, and description\n",
+			"slug":"app1","links":{"self":"/v1/apps/app1/versions/0.0.2","latest":"/v1/apps/app1","versions":"/v1/apps?title=App1"}
+		}`,
+	},
+	{
+		"Import apps in bulk atomically, nothing created when any record rejected",
+		[]Request{
+			{"POST", "/apps/_bulk?atomic=true", app1v1 + "---" + app2v1 + "---" + app1v1},
+		},
+		200,
+		`{"line":2,"status":"skipped","title":"App1","version":"0.0.1"}
+		{"line":17,"status":"skipped","title":"App2","version":"0.0.1"}
+		{"line":32,"status":"conflict","title":"App1","version":"0.0.1","error":"App 'App1' with version '0.0.1' already exists."}
+		`,
+	},
+	{
+		"Import apps in bulk atomically, list apps after rejected",
+		[]Request{
+			{"POST", "/apps/_bulk?atomic=true", app1v1 + "---" + appWithBadVersion},
+			{"GET", "/apps", ""},
+		},
+		200,
+		`[]`,
+	},
+
+	// -----------------------------------------------------------
+	// Saved searches
+	// -----------------------------------------------------------
+	{
+		"Save a search, response 201 and the search detail",
+		[]Request{
+			{"POST", "/searches", savedSearchApp1},
+		},
+		201,
+		`{"Name":"app1-latest","Filter":{"rules":[{"field":"title","op":"eq","value":"App1"}]},"Sort":["-version"],"Fields":["title","version"]}`,
+	},
+	{
+		"Save a search with existing name, response 409",
+		[]Request{
+			{"POST", "/searches", savedSearchApp1},
+			{"POST", "/searches", savedSearchApp1},
+		},
+		409,
+		`{"detail":"Search 'app1-latest' already exists.","status":409,"title":"Conflict","type":"about:blank"}`,
+	},
+	{
+		"Save a search with bad filter, response 400",
+		[]Request{
+			{"POST", "/searches", "name: bad\nfilter: dummy=1"},
+		},
+		400,
+		`{"detail":"Failed to create rule: Field with name 'dummy' does not exist.","field":"dummy","param":"dummy","status":400,"title":"Unknown field","type":"/problems/unknown-field"}`,
+	},
+	{
+		"List saved searches",
+		[]Request{
+			{"POST", "/searches", savedSearchApp1},
+			{"GET", "/searches", ""},
+		},
+		200,
+		`[{"Name":"app1-latest","Filter":{"rules":[{"field":"title","op":"eq","value":"App1"}]},"Sort":["-version"],"Fields":["title","version"]}]`,
+	},
+	{
+		"Run a saved search, sorted and with selected fields",
+		[]Request{
+			{"POST", "/apps", app1v1},
+			{"POST", "/apps", app1v2},
+			{"POST", "/apps", app2v1},
+			{"POST", "/searches", savedSearchApp1},
+			{"GET", "/searches/app1-latest/results", ""},
+		},
+		200,
+		`[{"links":{"self":"/v1/apps/app1/versions/0.0.2","latest":"/v1/apps/app1","versions":"/v1/apps?title=App1"},"slug":"app1","title":"App1","version":"0.0.2"},` +
+			`{"links":{"self":"/v1/apps/app1/versions/0.0.1","latest":"/v1/apps/app1","versions":"/v1/apps?title=App1"},"slug":"app1","title":"App1","version":"0.0.1"}]`,
+	},
+	{
+		"Run a non-exist saved search, response 404",
+		[]Request{
+			{"GET", "/searches/dummy/results", ""},
+		},
+		404,
+		`{"detail":"Search with name 'dummy' does not exist.","status":404,"title":"Not Found","type":"about:blank"}`,
+	},
+
+	// -----------------------------------------------------------
+	// Snapshots
+	// -----------------------------------------------------------
+	{
+		"Restore a snapshot, apps added after it are discarded",
+		[]Request{
+			{"POST", "/apps", app1v1},
+			{"POST", "/_snapshots?label=before-app2", ""},
+			{"POST", "/apps", app2v1},
+			{"POST", "/_snapshots/1/restore", ""},
+			{"GET", "/apps?title[like]=App", ""},
+		},
+		200,
+		`[{"title":"App1","version":"0.0.1","maintainers":[{"name":"firstmaintainer app1","email":"firstmaintainer@hotmail.com"},{"name":"secondmaintainer app1","email":"secondmaintainer@gmail.com"}],"company":"Random Inc.","website":"https://website.com","source":"https://github.com/random/repo","license":"Apache-2.0","description":"### Interesting Title\nSome application content, and description\n","slug":"app1","links":{"self":"/v1/apps/app1/versions/0.0.1","latest":"/v1/apps/app1","versions":"/v1/apps?title=App1"}}]`,
+	},
+	{
+		"Restore a non-exist snapshot, response 404",
+		[]Request{
+			{"POST", "/_snapshots/42/restore", ""},
+		},
+		404,
+		`{"detail":"Snapshot '42' does not exist.","status":404,"title":"Not Found","type":"about:blank"}`,
+	},
+
+	// -----------------------------------------------------------
+	// Type-ahead suggestions
+	// -----------------------------------------------------------
+	{
+		"Suggest titles by prefix, most popular first",
+		[]Request{
+			{"POST", "/apps", app2v1},
+			{"POST", "/apps", app1v1},
+			{"POST", "/apps", app1v2},
+			{"POST", "/apps", app3WithSpaceInTitle},
+			{"GET", "/_suggest?prefix=ap&field=title&limit=2", ""},
+		},
+		200,
+		`["App1","App3 with space in title"]`,
+	},
+	{
+		"Suggest maintainers by prefix",
+		[]Request{
+			{"POST", "/apps", app1v1},
+			{"POST", "/apps", app2v1},
+			{"GET", "/_suggest?prefix=first&field=maintainer&rank=recency", ""},
+		},
+		200,
+		`["firstmaintainer app2","firstmaintainer app1"]`,
+	},
+	{
+		"Suggest with unsupported field, response 400",
+		[]Request{
+			{"GET", "/_suggest?prefix=a&field=company", ""},
+		},
+		400,
+		`{"detail":"Field 'company' does not support suggestions.","status":400,"title":"Bad Request","type":"about:blank"}`,
 	},
 }
 
@@ -506,7 +872,7 @@ func TestScenarios(t *testing.T) {
 		var resp *http.Response
 		var err error
 		if req.method == "POST" {
-			resp, err = http.Post(formatUrl(req.url), "application/json", strings.NewReader(req.data))
+			resp, err = http.Post(formatUrl(req.url), "application/x-yaml", strings.NewReader(req.data))
 		} else {
 			resp, err = http.Get(formatUrl(req.url))
 		}
@@ -540,13 +906,221 @@ func TestScenarios(t *testing.T) {
 				t.Errorf("Expected status code to be %d but got %d", tt.expectedStatusCode, statusCode)
 			}
 
-			if responseBody != trimAndMergeToOneLine(tt.expectedResponseBody) {
+			// Both sides are merged into one line so that multi-line responses (e.g. NDJSON) could be compared as well.
+			if trimAndMergeToOneLine(responseBody) != trimAndMergeToOneLine(tt.expectedResponseBody) {
 				t.Errorf("Expected response body to be '%s' but got '%s'", tt.expectedResponseBody, responseBody)
 			}
 		})
 	}
 }
 
+func TestContentNegotiation(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	ts := httptest.NewServer(setupServer())
+	defer ts.Close()
+
+	const app1v1JSON = `{"title":"App1","version":"0.0.1","maintainers":[{"name":"firstmaintainer app1","email":"firstmaintainer@hotmail.com"}],` +
+		`"company":"Random Inc.","website":"https://website.com","source":"https://github.com/random/repo","license":"Apache-2.0","description":"Some description"}`
+
+	var tests = []struct {
+		scenarioTitle        string
+		method               string
+		url                  string
+		contentType          string
+		accept               string
+		data                 string
+		expectedStatusCode   int
+		expectedContentType  string
+		expectedResponseBody string
+	}{
+		{
+			"Create app with JSON body",
+			"POST", "/apps", "application/json", "", app1v1JSON,
+			201, "application/json; charset=utf-8",
+			`{"title":"App1","version":"0.0.1","maintainers":[{"name":"firstmaintainer app1","email":"firstmaintainer@hotmail.com"}],"company":"Random Inc.","website":"https://website.com","source":"https://github.com/random/repo","license":"Apache-2.0","description":"Some description","slug":"app1","links":{"self":"/v1/apps/app1/versions/0.0.1","latest":"/v1/apps/app1","versions":"/v1/apps?title=App1"}}`,
+		},
+		{
+			"Create app with YAML body and respond YAML",
+			"POST", "/apps", "application/yaml", "application/x-yaml", app2v1,
+			201, "application/x-yaml; charset=utf-8",
+			"title: App2\nversion: 0.0.1\nmaintainers:\n- name: firstmaintainer app2\n  email: firstmaintainer@hotmail.com\n- name: secondmaintainer app2\n  email: secondmaintainer@gmail.com\n" +
+				"company: Random Inc.\nwebsite: https://website.com\nsource: https://github.com/random/repo\nlicense: Apache-2.0\ndescription: |\n  ### Interesting Title\n  Some application content, and description\n" +
+				"slug: app2\nlinks:\n  self: /v1/apps/app2/versions/0.0.1\n  latest: /v1/apps/app2\n  versions: /v1/apps?title=App2\n",
+		},
+		{
+			"Get app in the negotiated YAML media type, with the same field names as JSON",
+			"GET", "/apps/App2", "", "text/yaml", "",
+			200, "text/yaml; charset=utf-8",
+			"title: App2\nversion: 0.0.1\nmaintainers:\n- name: firstmaintainer app2\n  email: firstmaintainer@hotmail.com\n- name: secondmaintainer app2\n  email: secondmaintainer@gmail.com\n" +
+				"company: Random Inc.\nwebsite: https://website.com\nsource: https://github.com/random/repo\nlicense: Apache-2.0\ndescription: |\n  ### Interesting Title\n  Some application content, and description\n" +
+				"slug: app2\nlinks:\n  self: /v1/apps/app2/versions/0.0.1\n  latest: /v1/apps/app2\n  versions: /v1/apps?title=App2\n",
+		},
+		{
+			"Create app with JSON body which is actually YAML, response 400",
+			"POST", "/apps", "application/json", "", app1v1,
+			400, "application/problem+json",
+			`{"detail":"invalid character 'i' in literal true (expecting 'r')","status":400,"title":"Bad Request","type":"about:blank"}`,
+		},
+		{
+			"Create app with unsupported content type, response 415",
+			"POST", "/apps", "text/plain", "", app1v1,
+			415, "application/problem+json",
+			`{"detail":"Content-Type 'text/plain' is not supported. Supported content types are: application/json, application/x-yaml, application/yaml, text/yaml, text/x-yaml","status":415,"title":"Unsupported Media Type","type":"about:blank"}`,
+		},
+		{
+			"Suggest titles in NDJSON",
+			"GET", "/_suggest?prefix=app", "", "application/x-ndjson", "",
+			200, "application/x-ndjson",
+			"\"App2\"\n\"App1\"\n",
+		},
+		{
+			"Import apps in bulk with NDJSON body",
+			"POST", "/apps/_bulk", "application/x-ndjson", "", strings.Replace(app1v1JSON, "App1", "App3", 1) + "\n\n{\"title\":\"App4\"}\n",
+			200, "application/x-ndjson",
+			`{"line":1,"status":"created","title":"App3","version":"0.0.1"}` + "\n" +
+				`{"line":3,"status":"invalid","title":"App4","error":"The request body has 7 invalid field(s): 'version' is required. 'maintainers' is required. 'company' is required. 'website' is required. 'source' is required. 'license' is required. 'description' is required.",` +
+				`"errors":[{"path":"version","rule":"required","message":"'version' is required."},{"path":"maintainers","rule":"required","message":"'maintainers' is required."},{"path":"company","rule":"required","message":"'company' is required."},` +
+				`{"path":"website","rule":"required","message":"'website' is required."},{"path":"source","rule":"required","message":"'source' is required."},{"path":"license","rule":"required","message":"'license' is required."},{"path":"description","rule":"required","message":"'description' is required."}]}` + "\n",
+		},
+		{
+			"List apps with unsupported accept, response 406",
+			"GET", "/apps", "", "text/html", "",
+			406, "application/problem+json",
+			`{"detail":"None of the accepted media types 'text/html' is supported. Supported media types are: application/json, application/x-yaml, application/yaml, text/yaml, application/x-ndjson","status":406,"title":"Not Acceptable","type":"about:blank"}`,
+		},
+	}
+
+	// The scenarios share the same store, apps created in the scenarios are listed in the latter ones.
+	setupStore()
+	for _, tt := range tests {
+		t.Run(tt.scenarioTitle, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, fmt.Sprintf("%s/v1%s", ts.URL, tt.url), strings.NewReader(tt.data))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("Error occurred during %s %s, detail: %e", tt.method, tt.url, err)
+			}
+			body, _ := ioutil.ReadAll(resp.Body)
+
+			if resp.StatusCode != tt.expectedStatusCode {
+				t.Errorf("Expected status code to be %d but got %d", tt.expectedStatusCode, resp.StatusCode)
+			}
+			if contentType := resp.Header.Get("Content-Type"); contentType != tt.expectedContentType {
+				t.Errorf("Expected content type to be '%s' but got '%s'", tt.expectedContentType, contentType)
+			}
+			if string(body) != tt.expectedResponseBody {
+				t.Errorf("Expected response body to be '%s' but got '%s'", tt.expectedResponseBody, string(body))
+			}
+		})
+	}
+}
+
+func TestExportAndImport(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	ts := httptest.NewServer(setupServer())
+	defer ts.Close()
+
+	setupStore()
+	for _, data := range []string{app1v1, app1v2, app3WithSpaceInTitle} {
+		http.Post(ts.URL+"/v1/apps", "application/x-yaml", strings.NewReader(data))
+	}
+	http.Post(ts.URL+"/v1/searches", "application/json", strings.NewReader(`{"Name":"App1 only","Filter":"title=App1"}`))
+	http.Post(ts.URL+"/v1/apps/App1/versions/0.0.2/_yank", "application/json", nil)
+
+	resp, err := http.Get(ts.URL + "/v1/_export")
+	if err != nil {
+		t.Fatalf("Error occurred during export, detail: %e", err)
+	}
+	exported, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != 200 || resp.Header.Get("Content-Type") != "application/gzip" {
+		t.Fatalf("Expected 200 with archive but got %d '%s'", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	var tests = []struct {
+		scenarioTitle        string
+		setup                []string
+		url                  string
+		contentType          string
+		data                 []byte
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			"Import into an empty store",
+			nil,
+			"/_import", "application/gzip", exported,
+			200, `{"Mode":"merge","Created":3,"Unchanged":0,"Removed":0,"Conflicts":[]}`,
+		},
+		{
+			"Import and merge, report conflicts",
+			[]string{app1v1, strings.Replace(app1v2, "Random Inc.", "Other Inc.", 1), app2v1},
+			"/_import?mode=merge", "application/gzip", exported,
+			200, `{"Mode":"merge","Created":1,"Unchanged":1,"Removed":0,"Conflicts":[{"Title":"App1","Version":"0.0.2","Error":"App 'App1' with version '0.0.2' already exists with different content."}]}`,
+		},
+		{
+			"Import and replace",
+			[]string{app1v1, app2v1},
+			"/_import?mode=replace", "application/gzip", exported,
+			200, `{"Mode":"replace","Created":3,"Unchanged":0,"Removed":2,"Conflicts":[]}`,
+		},
+		{
+			"Import with bad mode, response 400",
+			nil,
+			"/_import?mode=dummy", "application/gzip", exported,
+			400, `{"detail":"Unrecognized import mode 'dummy'","status":400,"title":"Bad Request","type":"about:blank"}`,
+		},
+		{
+			"Import a broken archive, response 400",
+			nil,
+			"/_import", "application/gzip", exported[:len(exported)/2],
+			400, `{"detail":"Failed to read archive manifest: unexpected EOF","status":400,"title":"Bad Request","type":"about:blank"}`,
+		},
+		{
+			"Import with unsupported content type, response 415",
+			nil,
+			"/_import", "text/plain", exported,
+			415, `{"detail":"Content-Type 'text/plain' is not supported. Supported content types are: application/gzip, application/x-gzip, application/octet-stream","status":415,"title":"Unsupported Media Type","type":"about:blank"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		setupStore()
+		t.Run(tt.scenarioTitle, func(t *testing.T) {
+			for _, data := range tt.setup {
+				http.Post(ts.URL+"/v1/apps", "application/x-yaml", strings.NewReader(data))
+			}
+
+			resp, err := http.Post(ts.URL+"/v1"+tt.url, tt.contentType, bytes.NewReader(tt.data))
+			if err != nil {
+				t.Fatalf("Error occurred during import, detail: %e", err)
+			}
+			body, _ := ioutil.ReadAll(resp.Body)
+			if resp.StatusCode != tt.expectedStatusCode {
+				t.Errorf("Expected status code to be %d but got %d", tt.expectedStatusCode, resp.StatusCode)
+			}
+			if string(body) != tt.expectedResponseBody {
+				t.Errorf("Expected response body to be '%s' but got '%s'", tt.expectedResponseBody, string(body))
+			}
+		})
+	}
+
+	// Saved searches and yanked versions are imported along with the apps.
+	setupStore()
+	http.Post(ts.URL+"/v1/_import", "application/gzip", bytes.NewReader(exported))
+	if search := store.GetSearch("App1 only"); search == nil || search.Error != "" {
+		t.Errorf("Expected the saved search to be imported but got '%v'", search)
+	}
+	if latest := store.GetByTitle("App1"); latest == nil || latest.Version.String() != "0.0.1" {
+		t.Errorf("Expected the yanked version not to be the latest one but got '%v'", latest)
+	}
+}
+
 func trimAndMergeToOneLine(text string) string {
 	lines := strings.Split(text, "\n")
 	for i := 0; i < len(lines); i++ {
@@ -554,3 +1128,209 @@ func trimAndMergeToOneLine(text string) string {
 	}
 	return strings.Join(lines, "")
 }
+
+// newTestServer starts a server on an empty store, which authenticates requests by the API keys in a new apiKeys.
+// The server is closed and the authentication settings are restored when the test finishes.
+func newTestServer(t *testing.T) *httptest.Server {
+	gin.SetMode(gin.ReleaseMode)
+	setupStore()
+	ts := httptest.NewServer(setupServer())
+
+	originalAuthenticator, originalKeys, originalKeysFile, originalAnonymousRead := authenticator, apiKeys, apiKeysFile, anonymousRead
+	t.Cleanup(func() {
+		ts.Close()
+		authenticator, apiKeys, apiKeysFile, anonymousRead = originalAuthenticator, originalKeys, originalKeysFile, originalAnonymousRead
+	})
+	apiKeys = auth.NewKeyStore()
+	authenticator = apiKeys
+	return ts
+}
+
+// apiTest is a request sent by runAPITests with the API key, and its expected response.
+type apiTest struct {
+	name         string
+	method       string
+	path         string
+	key          string
+	data         string
+	expectedCode int
+	// expectedBody is a part of the response body, empty means the body is not checked.
+	expectedBody string
+}
+
+// runAPITests sends the requests to the server in order, each of them runs as a subtest.
+// The requests depend on each other, e.g. an app is published by a former one and yanked by a latter one.
+func runAPITests(t *testing.T, ts *httptest.Server, tests []apiTest) {
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, ts.URL+tt.path, strings.NewReader(tt.data))
+			req.Header.Set("Authorization", "Bearer "+tt.key)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("Error occurred during %s %s, detail: %e", tt.method, tt.path, err)
+			}
+			body, _ := ioutil.ReadAll(resp.Body)
+			if resp.StatusCode != tt.expectedCode {
+				t.Errorf("Expected status code %d but got %d: %s", tt.expectedCode, resp.StatusCode, body)
+			}
+			if !strings.Contains(string(body), tt.expectedBody) {
+				t.Errorf("Expected body to contain '%s' but got '%s'", tt.expectedBody, body)
+			}
+		})
+	}
+}
+
+func TestConditionalRequests(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	ts := httptest.NewServer(setupServer())
+	defer ts.Close()
+
+	perform := func(method string, url string, header string, value string, data string) (*http.Response, string) {
+		req, _ := http.NewRequest(method, ts.URL+"/v1"+url, strings.NewReader(data))
+		req.Header.Set("Content-Type", "application/x-yaml")
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Error occurred during %s %s, detail: %e", method, url, err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		return resp, string(body)
+	}
+
+	setupStore()
+	resp, _ := perform("POST", "/apps", "", "", app1v1)
+	created := resp.Header.Get("ETag")
+	if created == "" {
+		t.Fatalf("Expected ETag in the response of creating app")
+	}
+
+	resp, _ = perform("GET", "/apps/App1/versions/0.0.1", "", "", "")
+	if etag := resp.Header.Get("ETag"); etag != created {
+		t.Errorf("Expected ETag '%s' but got '%s'", created, etag)
+	}
+
+	resp, body := perform("GET", "/apps/App1", "If-None-Match", `"other", W/`+created, "")
+	if resp.StatusCode != http.StatusNotModified || body != "" {
+		t.Errorf("Expected 304 without body but got %d '%s'", resp.StatusCode, body)
+	}
+
+	resp, _ = perform("GET", "/apps/App1", "If-None-Match", `"other"`, "")
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200 for a not matching If-None-Match but got %d", resp.StatusCode)
+	}
+
+	// Each representation has its own ETag, so that the JSON one does not make a YAML request respond 304.
+	resp, _ = perform("GET", "/apps/App1", "Accept", "application/x-yaml", "")
+	createdYAML := resp.Header.Get("ETag")
+	if createdYAML == "" || createdYAML == created || resp.Header.Get("Vary") != "Accept" {
+		t.Errorf("Expected ETag other than '%s' varying by Accept but got '%s' '%s'", created, createdYAML, resp.Header.Get("Vary"))
+	}
+	req, _ := http.NewRequest("GET", ts.URL+"/v1/apps/App1", nil)
+	req.Header.Set("Accept", "application/x-yaml")
+	req.Header.Set("If-None-Match", created)
+	if resp, err := http.DefaultClient.Do(req); err != nil || resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200 for the ETag of another representation but got %v %v", resp, err)
+	}
+
+	updated := strings.Replace(app1v1, "Random Inc.", "Other Inc.", 1)
+	// The ETag of any representation is accepted by If-Match, since they all describe the same revision.
+	resp, body = perform("PUT", "/apps/App1/versions/0.0.1", "If-Match", createdYAML, updated)
+	if resp.StatusCode != http.StatusOK || !strings.Contains(body, `"company":"Other Inc."`) {
+		t.Errorf("Expected 200 with updated app but got %d '%s'", resp.StatusCode, body)
+	}
+	latest := resp.Header.Get("ETag")
+	if latest == created {
+		t.Errorf("Expected ETag changed after update but got '%s'", latest)
+	}
+
+	// Update with the stale ETag, which is the case when two maintainers edit the same version.
+	resp, body = perform("PUT", "/apps/App1/versions/0.0.1", "If-Match", created, app1v1)
+	expected := fmt.Sprintf(`{"detail":"App 'App1' with version '0.0.1' has been modified, the current revision is '%s'.","status":412,"title":"App has been modified","type":"/problems/revision-mismatch"}`, strings.Trim(latest, `"`))
+	if resp.StatusCode != http.StatusPreconditionFailed || body != expected {
+		t.Errorf("Expected 412 '%s' but got %d '%s'", expected, resp.StatusCode, body)
+	}
+
+	// A weak ETag never matches If-Match.
+	resp, _ = perform("PUT", "/apps/App1/versions/0.0.1", "If-Match", "W/"+latest, app1v1)
+	if resp.StatusCode != http.StatusPreconditionFailed {
+		t.Errorf("Expected 412 for a weak ETag but got %d", resp.StatusCode)
+	}
+
+	resp, _ = perform("PUT", "/apps/App1/versions/0.0.1", "If-Match", "*", app1v1)
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("ETag") != created {
+		t.Errorf("Expected 200 with ETag '%s' but got %d '%s'", created, resp.StatusCode, resp.Header.Get("ETag"))
+	}
+
+	// Yanking changes the representation of the app, so that neither If-None-Match nor If-Match matches the ETag read before.
+	resp, _ = perform("POST", "/apps/App1/versions/0.0.1/_yank", "", "", "")
+	yanked := resp.Header.Get("ETag")
+	if resp.StatusCode != http.StatusOK || yanked == "" || yanked == created {
+		t.Errorf("Expected 200 with ETag other than '%s' after yank but got %d '%s'", created, resp.StatusCode, yanked)
+	}
+	resp, body = perform("GET", "/apps/App1/versions/0.0.1", "If-None-Match", created, "")
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("ETag") != yanked || !strings.Contains(body, `"yanked":true`) {
+		t.Errorf("Expected 200 with the yanked app and ETag '%s' but got %d '%s' '%s'", yanked, resp.StatusCode, resp.Header.Get("ETag"), body)
+	}
+	resp, _ = perform("GET", "/apps/App1/versions/0.0.1", "If-None-Match", yanked, "")
+	if resp.StatusCode != http.StatusNotModified {
+		t.Errorf("Expected 304 for the ETag after yank but got %d", resp.StatusCode)
+	}
+	resp, body = perform("PUT", "/apps/App1/versions/0.0.1", "If-Match", created, app1v1)
+	if resp.StatusCode != http.StatusPreconditionFailed || resp.Header.Get("ETag") != yanked || !strings.Contains(body, strings.Trim(yanked, `"`)) {
+		t.Errorf("Expected 412 with ETag '%s' but got %d '%s' '%s'", yanked, resp.StatusCode, resp.Header.Get("ETag"), body)
+	}
+	resp, _ = perform("PUT", "/apps/App1/versions/0.0.1", "If-Match", yanked, app1v1)
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("ETag") != yanked {
+		t.Errorf("Expected 200 with ETag '%s' but got %d '%s'", yanked, resp.StatusCode, resp.Header.Get("ETag"))
+	}
+	resp, _ = perform("POST", "/apps/App1/versions/0.0.1/_unyank", "", "", "")
+	if resp.Header.Get("ETag") != created {
+		t.Errorf("Expected ETag '%s' after unyank but got '%s'", created, resp.Header.Get("ETag"))
+	}
+
+	resp, body = perform("PUT", "/apps/App1/versions/0.0.2", "", "", app1v1)
+	expected = `{"detail":"Title 'App1' and version '0.0.1' in the request body do not match the URL.","status":400,"title":"Bad Request","type":"about:blank"}`
+	if resp.StatusCode != http.StatusBadRequest || body != expected {
+		t.Errorf("Expected 400 '%s' but got %d '%s'", expected, resp.StatusCode, body)
+	}
+
+	resp, body = perform("PUT", "/apps/App1/versions/0.0.2", "", "", app1v2)
+	expected = `{"detail":"App with title 'App1' and version '0.0.2' does not exist.","status":404,"title":"App not found","type":"/problems/app-not-found"}`
+	if resp.StatusCode != http.StatusNotFound || body != expected {
+		t.Errorf("Expected 404 '%s' but got %d '%s'", expected, resp.StatusCode, body)
+	}
+}
+
+func TestPersistFailure(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	ts := httptest.NewServer(setupServer())
+	defer ts.Close()
+
+	setupStore()
+	defer func(original string) { dataFile = original }(dataFile)
+	// The directory does not exist, so that the store could not be saved.
+	dataFile = filepath.Join(t.TempDir(), "missing", "store.yaml")
+
+	var tests = []struct {
+		path         string
+		data         string
+		expectedCode int
+		expectedBody string
+	}{
+		{"/apps", app1v1, 500, `"detail":"Failed to persist store: `},
+		{"/searches", savedSearchApp1, 500, `"detail":"Failed to persist store: `},
+		{"/apps/_bulk", app2v1, 200, `{"line":0,"status":"failed","error":"Failed to persist store: `},
+	}
+	for _, tt := range tests {
+		resp, err := http.Post(ts.URL+"/v1"+tt.path, "application/x-yaml", strings.NewReader(tt.data))
+		if err != nil {
+			t.Fatalf("Error occurred during POST %s, detail: %e", tt.path, err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		if resp.StatusCode != tt.expectedCode || !strings.Contains(string(body), tt.expectedBody) {
+			t.Errorf("Expected %d '%s' for POST %s but got %d '%s'", tt.expectedCode, tt.expectedBody, tt.path, resp.StatusCode, body)
+		}
+	}
+}
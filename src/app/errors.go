@@ -0,0 +1,38 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/zzn2/demo/appstore/semver"
+)
+
+// Kinds of errors returned by this package, which could be matched by errors.Is.
+var (
+	// ErrDuplicateVersion means the store already contains an app with the same title and version.
+	ErrDuplicateVersion = errors.New("duplicate version")
+	// ErrNotFound means the app does not exist in the store.
+	ErrNotFound = errors.New("app not found")
+	// ErrRevisionMismatch means the app has been modified since it was read.
+	ErrRevisionMismatch = errors.New("revision mismatch")
+	// ErrMissingVersion means the app lacks of version.
+	ErrMissingVersion = errors.New("missing version")
+	// ErrTitleConflict means the store already contains an app whose title differs only in case or punctuation.
+	ErrTitleConflict = errors.New("title conflict")
+	// ErrNotOwner means the publisher is not permitted to change the app, since it is not an owner or a maintainer.
+	ErrNotOwner = errors.New("not owner")
+)
+
+// MissingVersionError is returned when adding an app without version.
+type MissingVersionError struct {
+	Title string
+}
+
+func (e *MissingVersionError) Error() string {
+	return fmt.Sprintf("App '%s' lacks of version or the version could not be '%s'.)", e.Title, semver.Empty)
+}
+
+// Is makes errors.Is(err, ErrMissingVersion) report true for a MissingVersionError.
+func (e *MissingVersionError) Is(target error) bool {
+	return target == ErrMissingVersion
+}
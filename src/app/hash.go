@@ -0,0 +1,92 @@
+package app
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// FieldDiff is a field whose value differs between two app metadata.
+type FieldDiff struct {
+	// Field is the path of the field, e.g. "Company" or "Maintainers[0].Email".
+	Field    string `json:"field" yaml:"field"`
+	Existing string `json:"existing" yaml:"existing"`
+	Posted   string `json:"posted" yaml:"posted"`
+}
+
+// ContentHash returns the hash of the canonical form of the app metadata.
+// Metadata differing only in whitespace, e.g. trailing spaces or line endings of the description, have the same hash.
+func (m Meta) ContentHash() string {
+	// Marshalling a struct always succeeds and keeps the order of the fields.
+	data, _ := json.Marshal(m.canonical())
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Diff returns the fields of other which differ from m, compared in their canonical forms.
+// It returns an empty slice if m and other have the same ContentHash.
+func (m Meta) Diff(other Meta) []FieldDiff {
+	a, b := m.canonical(), other.canonical()
+	diff := make([]FieldDiff, 0)
+	add := func(field string, existing string, posted string) {
+		if existing != posted {
+			diff = append(diff, FieldDiff{field, existing, posted})
+		}
+	}
+
+	add("Title", a.Title, b.Title)
+	add("Version", a.Version.String(), b.Version.String())
+	for i := 0; i < len(a.Maintainers) || i < len(b.Maintainers); i++ {
+		switch {
+		case i >= len(a.Maintainers):
+			add(fmt.Sprintf("Maintainers[%d]", i), "", b.Maintainers[i].String())
+		case i >= len(b.Maintainers):
+			add(fmt.Sprintf("Maintainers[%d]", i), a.Maintainers[i].String(), "")
+		default:
+			add(fmt.Sprintf("Maintainers[%d].Name", i), a.Maintainers[i].Name, b.Maintainers[i].Name)
+			add(fmt.Sprintf("Maintainers[%d].Email", i), a.Maintainers[i].Email, b.Maintainers[i].Email)
+		}
+	}
+	add("Company", a.Company, b.Company)
+	add("Website", a.Website, b.Website)
+	add("Source", a.Source, b.Source)
+	add("License", a.License, b.License)
+	add("Description", a.Description, b.Description)
+	return diff
+}
+
+// String returns the string representation of the maintainer, e.g. "Alice <alice@hotmail.com>".
+func (m Maintainer) String() string {
+	return fmt.Sprintf("%s <%s>", m.Name, m.Email)
+}
+
+// canonical returns a copy of the app metadata with whitespace normalized in all the text fields.
+func (m Meta) canonical() Meta {
+	c := m
+	c.Title = normalizeText(m.Title)
+	c.Maintainers = make([]Maintainer, len(m.Maintainers))
+	for i, maintainer := range m.Maintainers {
+		c.Maintainers[i] = Maintainer{normalizeText(maintainer.Name), normalizeText(maintainer.Email)}
+	}
+	c.Company = normalizeText(m.Company)
+	c.Website = normalizeText(m.Website)
+	c.Source = normalizeText(m.Source)
+	c.License = normalizeText(m.License)
+	c.Description = normalizeText(m.Description)
+	return c
+}
+
+// normalizeText unifies line endings, collapses runs of spaces and tabs in each line into a single space,
+// and removes the leading and trailing whitespace of each line and of the whole text.
+func normalizeText(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.Join(strings.Fields(line), " ")
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,84 @@
+package app
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeText(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		{"", ""},
+		{"App1", "App1"},
+		{"  Random \t Inc.  ", "Random Inc."},
+		{"### Title\r\nSome  content \r\n\r\n", "### Title\nSome content"},
+		{"line1\rline2\n  indented", "line1\nline2\nindented"},
+	}
+
+	for _, test := range tests {
+		if actual := normalizeText(test.text); actual != test.expected {
+			t.Errorf("Expected %q normalized to %q but got %q.", test.text, test.expected, actual)
+		}
+	}
+}
+
+func TestContentHash(t *testing.T) {
+	same := app1v1
+	same.Title = " App1 "
+	same.Description = "\r\n"
+	if same.ContentHash() != app1v1.ContentHash() {
+		t.Errorf("Expected the same hash for content differing only in whitespace.")
+	}
+
+	different := app1v1
+	different.Company = "Other Inc."
+	if different.ContentHash() == app1v1.ContentHash() {
+		t.Errorf("Expected different hash for different content.")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	existing := app1v1
+	existing.Company = "Random Inc."
+	existing.Maintainers = []Maintainer{{"Alice", "alice@hotmail.com"}, {"Bob", "bob@hotmail.com"}}
+
+	posted := existing
+	posted.Company = " Random  Inc. "
+	posted.License = "MIT"
+	posted.Maintainers = []Maintainer{{"Alice", "alice@gmail.com"}}
+
+	expected := []FieldDiff{
+		{"Maintainers[0].Email", "alice@hotmail.com", "alice@gmail.com"},
+		{"Maintainers[1]", "Bob <bob@hotmail.com>", ""},
+		{"License", "", "MIT"},
+	}
+	if diff := existing.Diff(posted); !reflect.DeepEqual(diff, expected) {
+		t.Errorf("Expected diff %v but got %v.", expected, diff)
+	}
+	if diff := existing.Diff(existing); len(diff) != 0 {
+		t.Errorf("Expected no diff for identical apps but got %v.", diff)
+	}
+}
+
+func TestAddDuplicate(t *testing.T) {
+	var store Store
+	store.Add(app1v1)
+
+	var duplicate *DuplicateError
+	if err := store.Add(app1v1); !errors.As(err, &duplicate) || !duplicate.Identical() {
+		t.Errorf("Expected identical duplicate error but got %v.", err)
+	}
+
+	changed := app1v1
+	changed.Company = "Other Inc."
+	err := store.Add(changed)
+	if !errors.As(err, &duplicate) || duplicate.Identical() || !reflect.DeepEqual(duplicate.Existing, app1v1) {
+		t.Errorf("Expected duplicate error with diff but got %v.", err)
+	}
+	if err.Error() != "App 'App1' with version '0.0.1' already exists." {
+		t.Errorf("Unexpected error message '%s'.", err.Error())
+	}
+}
@@ -0,0 +1,175 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/zzn2/demo/appstore/semver"
+)
+
+// ImportMode defines how imported apps are put into the store.
+type ImportMode string
+
+const (
+	// ImportMerge adds the imported apps which do not exist in the store yet.
+	ImportMerge ImportMode = "merge"
+	// ImportReplace removes all the apps in the store and adds the imported apps.
+	ImportReplace ImportMode = "replace"
+)
+
+// ParseImportMode parses text into ImportMode. Empty text is treated as ImportMerge.
+func ParseImportMode(text string) (ImportMode, error) {
+	switch mode := ImportMode(strings.ToLower(text)); mode {
+	case "":
+		return ImportMerge, nil
+	case ImportMerge, ImportReplace:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("Unrecognized import mode '%s'", text)
+	}
+}
+
+// ImportConflict describes an imported app which could not be put into the store.
+type ImportConflict struct {
+	Title   string
+	Version semver.Version
+	Error   string
+}
+
+// ImportResult summarizes an import.
+type ImportResult struct {
+	Mode ImportMode
+	// Created is the number of imported apps added into the store.
+	Created int
+	// Unchanged is the number of imported apps identical to the ones already in the store.
+	Unchanged int
+	// Removed is the number of apps removed from the store, only used in ImportReplace mode.
+	Removed   int
+	Conflicts []ImportConflict
+}
+
+// Catalog is the content of a store which is moved between environments, e.g. by archives:
+// the apps with their owners and yanked versions, and the saved searches. Snapshots are not included.
+type Catalog struct {
+	Apps     []Meta
+	Searches []SavedSearch
+	// Owners maps the titles of the apps to the emails of their owners.
+	Owners map[string][]string
+	// Yanked maps the titles of the apps to their yanked versions.
+	Yanked map[string][]semver.Version
+}
+
+// Catalog returns the content of the store, see Import.
+func (s *Store) Catalog() (Catalog, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	searches, err := s.savedSearches()
+	if err != nil {
+		return Catalog{}, err
+	}
+	return Catalog{
+		Apps:     s.apps,
+		Searches: searches,
+		Owners:   copyOwners(s.owners),
+		Yanked:   copyYanked(s.yanked),
+	}, nil
+}
+
+// Import puts the content of the catalog into the store according to the mode. It is atomic with respect to other modifications.
+//
+// In ImportMerge mode, an app with the same title and version as an existing one is either unchanged if identical (see Meta.ContentHash),
+// or reported as a conflict otherwise.
+// In ImportReplace mode, the apps in the store are replaced by the imported ones,
+// while duplicated apps in the imported ones are reported as conflicts.
+// In both modes, apps whose titles conflict with other apps (see Store.Add) are reported as conflicts.
+//
+// The owners of the catalog are only taken for the titles which did not exist in the store before,
+// and the yanked versions only for the versions created by the import, so that a catalog could not take over existing apps.
+// Saved searches are replaced in ImportReplace mode, while in ImportMerge mode they are added unless the names exist.
+func (s *Store) Import(catalog Catalog, mode ImportMode) ImportResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := ImportResult{Mode: mode, Conflicts: make([]ImportConflict, 0)}
+	existing := s.apps
+	if mode == ImportReplace {
+		result.Removed = len(s.apps)
+		existing = nil
+	}
+
+	merged := make([]Meta, len(existing), len(existing)+len(catalog.Apps))
+	copy(merged, existing)
+	index := make(map[string]int, len(merged))
+	titles := make(map[string]string, len(merged))
+	for i, app := range merged {
+		index[app.key()] = i
+		titles[app.Slug()] = app.Title
+	}
+	existingTitles := make(map[string]bool, len(titles))
+	for _, title := range titles {
+		existingTitles[title] = true
+	}
+
+	created := make(map[string]bool, len(catalog.Apps))
+	for _, app := range catalog.Apps {
+		i, exists := index[app.key()]
+		if title, ok := titles[app.Slug()]; ok && title != app.Title {
+			err := &TitleConflictError{Title: app.Title, Existing: title}
+			result.Conflicts = append(result.Conflicts, ImportConflict{Title: app.Title, Version: app.Version, Error: err.Error()})
+		} else if !exists {
+			titles[app.Slug()] = app.Title
+			index[app.key()] = len(merged)
+			merged = append(merged, app)
+			created[app.key()] = true
+			result.Created++
+		} else if merged[i].ContentHash() == app.ContentHash() {
+			result.Unchanged++
+		} else {
+			result.Conflicts = append(result.Conflicts, ImportConflict{
+				Title:   app.Title,
+				Version: app.Version,
+				Error:   fmt.Sprintf("App '%s' with version '%s' already exists with different content.", app.Title, app.Version),
+			})
+		}
+	}
+
+	s.apps = merged
+	if mode == ImportReplace {
+		s.owners = nil
+		s.yanked = nil
+		s.searches = restoreSearches(catalog.Searches)
+	} else {
+		s.mergeSearches(catalog.Searches)
+	}
+	for title, emails := range catalog.Owners {
+		if !existingTitles[title] && s.lastOrNil(func(app Meta) bool { return app.Title == title }) != nil {
+			s.setOwners(title, append([]string(nil), emails...))
+		}
+	}
+	for title, versions := range catalog.Yanked {
+		for _, version := range versions {
+			if created[Meta{Title: title, Version: version}.key()] {
+				s.markYanked(title, version, true)
+			}
+		}
+	}
+	s.reindex()
+	return result
+}
+
+// mergeSearches adds the searches whose names do not exist in the store. The caller must hold s.mu.
+func (s *Store) mergeSearches(saved []SavedSearch) {
+	names := make(map[string]bool, len(s.searches))
+	searches := append([]Search(nil), s.searches...)
+	for _, search := range searches {
+		names[search.Name] = true
+	}
+	for _, search := range restoreSearches(saved) {
+		if !names[search.Name] {
+			names[search.Name] = true
+			searches = append(searches, search)
+		}
+	}
+	s.searches = searches
+}
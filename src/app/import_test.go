@@ -0,0 +1,117 @@
+package app
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/zzn2/demo/appstore/filter"
+)
+
+func TestParseImportMode(t *testing.T) {
+	var tests = []struct {
+		text         string
+		expected     ImportMode
+		errorMessage string
+	}{
+		{"", ImportMerge, ""},
+		{"merge", ImportMerge, ""},
+		{"Replace", ImportReplace, ""},
+		{"dummy", "", "Unrecognized import mode 'dummy'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			mode, err := ParseImportMode(tt.text)
+			if mode != tt.expected {
+				t.Errorf("Expected to be '%s' but got '%s'", tt.expected, mode)
+			}
+			if err != nil && err.Error() != tt.errorMessage {
+				t.Errorf("Expect error message '%s' but got '%s'.", tt.errorMessage, err.Error())
+			}
+		})
+	}
+}
+
+func TestImport_Merge(t *testing.T) {
+	var store Store
+	store.Add(app1v1)
+	store.Add(app2v1)
+
+	changed := app2v1
+	changed.Company = "Other Inc."
+	result := store.Import(Catalog{Apps: []Meta{app1v1, changed, app1v2}}, ImportMerge)
+
+	if result.Created != 1 || result.Unchanged != 1 || result.Removed != 0 || len(result.Conflicts) != 1 {
+		t.Errorf("Unexpected result '%+v'", result)
+	}
+	if result.Conflicts[0].Error != "App 'App2' with version '0.0.1' already exists with different content." {
+		t.Errorf("Unexpected conflict '%+v'", result.Conflicts[0])
+	}
+	if len(store.apps) != 3 || store.GetByTitle("App2").Company != "" {
+		t.Errorf("Expected store contains 3 apps with App2 unchanged but got '%v'", store.apps)
+	}
+}
+
+func TestImport_Replace(t *testing.T) {
+	var store Store
+	store.Add(app1v1)
+	store.Add(app2v1)
+
+	result := store.Import(Catalog{Apps: []Meta{app1v2}}, ImportReplace)
+
+	if result.Created != 1 || result.Removed != 2 || len(result.Conflicts) != 0 {
+		t.Errorf("Unexpected result '%+v'", result)
+	}
+	if len(store.apps) != 1 || store.GetByTitle("App2") != nil {
+		t.Errorf("Expected store only contains the imported app but got '%v'", store.apps)
+	}
+	if titles, _ := store.Suggest(SuggestFieldTitle, "App", 10, 0); len(titles) != 1 {
+		t.Errorf("Expected suggestions to be rebuilt but got '%v'", titles)
+	}
+}
+
+func TestImport_StoreContent(t *testing.T) {
+	var source Store
+	source.AddAs(app1v1, Publisher{Email: "alice@random.com"})
+	source.Add(app1v2)
+	source.Add(app2v1)
+	source.YankAs(app1v2.Title, app1v2.Version, Publisher{})
+	search, _ := NewSearch("all", filter.RuleSet{}, nil, nil)
+	source.AddSearch(search)
+	catalog, err := source.Catalog()
+	if err != nil {
+		t.Fatalf("Should not have error but error '%s' occurred.", err.Error())
+	}
+
+	// App1 exists in the target already, so that its owners and existing versions are kept.
+	var target Store
+	target.AddAs(app1v1, Publisher{Email: "bob@random.com"})
+	target.Import(catalog, ImportMerge)
+
+	if owners := target.Owners(app1v1.Title); !reflect.DeepEqual(owners, []string{"bob@random.com"}) {
+		t.Errorf("Expected owners of App1 to be kept but got '%v'", owners)
+	}
+	if !target.IsYanked(app1v2.Title, app1v2.Version) || target.GetByTitle(app1v1.Title).Version != app1v1.Version {
+		t.Errorf("Expected the imported version of App1 to be yanked")
+	}
+	if target.GetSearch("all") == nil {
+		t.Errorf("Expected the saved search to be imported")
+	}
+
+	// Replacing the apps replaces their owners and yanked versions as well.
+	target.Import(Catalog{Apps: []Meta{app1v1, app1v2}}, ImportReplace)
+	if owners := target.Owners(app1v1.Title); len(owners) != 0 {
+		t.Errorf("Expected owners to be cleared but got '%v'", owners)
+	}
+	if target.IsYanked(app1v2.Title, app1v2.Version) || target.GetSearch("all") != nil {
+		t.Errorf("Expected yanked versions and saved searches to be replaced")
+	}
+
+	target.Import(catalog, ImportReplace)
+	if owners := target.Owners(app1v1.Title); !reflect.DeepEqual(owners, []string{"alice@random.com"}) {
+		t.Errorf("Expected owners of App1 to be imported but got '%v'", owners)
+	}
+	if !target.IsYanked(app1v2.Title, app1v2.Version) {
+		t.Errorf("Expected the imported version of App1 to be yanked")
+	}
+}
@@ -7,22 +7,29 @@ import (
 )
 
 type Maintainer struct {
-	Name  string `binding:"required"`
-	Email string `binding:"required,email"`
+	Name  string `json:"name" yaml:"name" binding:"required"`
+	Email string `json:"email" yaml:"email" binding:"required,email"`
 }
 
+// Meta is the metadata of an app version.
+// The fields are named the same in JSON and YAML, i.e. the lower case field names, which are also used by the paths of invalid fields.
 type Meta struct {
-	Title       string         `binding:"required"`
-	Version     semver.Version `binding:"required"`
-	Maintainers []Maintainer   `binding:"required,dive"`
-	Company     string         `binding:"required"`
-	Website     string         `binding:"required,url"`
-	Source      string         `binding:"required"`
-	License     string         `binding:"required"`
-	Description string         `binding:"required"`
+	Title       string         `json:"title" yaml:"title" binding:"required"`
+	Version     semver.Version `json:"version" yaml:"version" binding:"required"`
+	Maintainers []Maintainer   `json:"maintainers" yaml:"maintainers" binding:"required,dive"`
+	Company     string         `json:"company" yaml:"company" binding:"required"`
+	Website     string         `json:"website" yaml:"website" binding:"required,httpurl"`
+	Source      string         `json:"source" yaml:"source" binding:"required,vcsurl"`
+	License     string         `json:"license" yaml:"license" binding:"required,spdx"`
+	Description string         `json:"description" yaml:"description" binding:"required"`
 }
 
 // String returns the string representation of this object.
 func (m Meta) String() string {
 	return fmt.Sprintf("App: %s@%s", m.Title, m.Version)
 }
+
+// key returns the identity of the app in the store, which is unique by title and version.
+func (m Meta) key() string {
+	return fmt.Sprintf("%s@%s", m.Title, m.Version)
+}
@@ -0,0 +1,132 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Publisher is who publishes or edits apps, which is checked against the owners and the maintainers of the apps.
+// The zero value is an unidentified publisher which is not checked, e.g. when authentication is disabled.
+type Publisher struct {
+	// Email identifies the publisher. The publisher of a new title becomes its owner.
+	Email string
+	// Privileged publishers, e.g. administrators, could publish and edit any app and manage the owners of any app.
+	Privileged bool
+}
+
+func (p Publisher) checked() bool {
+	return p.Email != "" && !p.Privileged
+}
+
+// NotOwnerError is returned when the publisher is not permitted to change an app.
+type NotOwnerError struct {
+	Title string
+	Email string
+	// MaintainerPermitted tells whether maintainers are permitted to do the operation as well as owners.
+	MaintainerPermitted bool
+}
+
+func (e *NotOwnerError) Error() string {
+	if e.MaintainerPermitted {
+		return fmt.Sprintf("'%s' is neither an owner nor a maintainer of the app '%s'.", e.Email, e.Title)
+	}
+	return fmt.Sprintf("'%s' is not an owner of the app '%s'.", e.Email, e.Title)
+}
+
+// Is makes errors.Is(err, ErrNotOwner) report true for a NotOwnerError.
+func (e *NotOwnerError) Is(target error) bool {
+	return target == ErrNotOwner
+}
+
+// Owners returns the emails of the owners of the app with the title.
+// It returns an empty slice if the app has no owners, e.g. it was published before ownership was recorded.
+func (s *Store) Owners(title string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append(make([]string, 0, len(s.owners[title])), s.owners[title]...)
+}
+
+// AddOwner adds the email to the owners of the app with the title, and returns the owners.
+// Only the owners (or privileged publishers) could add owners.
+// It returns *NotFoundError if the app does not exist, or *NotOwnerError if the publisher is not permitted.
+func (s *Store) AddOwner(title string, email string, publisher Publisher) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.checkOwner(title, publisher); err != nil {
+		return nil, err
+	}
+
+	if !containsEmail(s.owners[title], email) {
+		s.setOwners(title, append(append([]string(nil), s.owners[title]...), email))
+	}
+	return append([]string(nil), s.owners[title]...), nil
+}
+
+// TransferOwnership makes the email the only owner of the app with the title, and returns the owners.
+// Only the owners (or privileged publishers) could transfer the ownership.
+// It returns *NotFoundError if the app does not exist, or *NotOwnerError if the publisher is not permitted.
+func (s *Store) TransferOwnership(title string, email string, publisher Publisher) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.checkOwner(title, publisher); err != nil {
+		return nil, err
+	}
+
+	s.setOwners(title, []string{email})
+	return []string{email}, nil
+}
+
+// The helpers below access the owners without locking, the caller must hold s.mu.
+
+// checkOwner checks whether the publisher could manage the owners of the existing app.
+func (s *Store) checkOwner(title string, publisher Publisher) error {
+	if s.lastOrNil(func(app Meta) bool { return app.Title == title }) == nil {
+		return &NotFoundError{Title: title}
+	}
+	if publisher.checked() && !containsEmail(s.owners[title], publisher.Email) {
+		return &NotOwnerError{Title: title, Email: publisher.Email}
+	}
+	return nil
+}
+
+// checkPublisher checks whether the publisher could publish or edit versions of the app.
+// New titles could be published by anyone, while versions of existing titles could only be published
+// by the owners or the maintainers of the latest version.
+func (s *Store) checkPublisher(title string, publisher Publisher) error {
+	if !publisher.checked() {
+		return nil
+	}
+	latest := s.lastOrNil(func(app Meta) bool { return app.Title == title })
+	if latest == nil || containsEmail(s.owners[title], publisher.Email) {
+		return nil
+	}
+	for _, maintainer := range latest.Maintainers {
+		if strings.EqualFold(maintainer.Email, publisher.Email) {
+			return nil
+		}
+	}
+	return &NotOwnerError{Title: title, Email: publisher.Email, MaintainerPermitted: true}
+}
+
+// claim makes the publisher the owner of a new title.
+func (s *Store) claim(title string, publisher Publisher) {
+	if publisher.Email != "" && len(s.owners[title]) == 0 {
+		s.setOwners(title, []string{publisher.Email})
+	}
+}
+
+func (s *Store) setOwners(title string, emails []string) {
+	if s.owners == nil {
+		s.owners = make(map[string][]string)
+	}
+	s.owners[title] = emails
+}
+
+func containsEmail(emails []string, email string) bool {
+	for _, e := range emails {
+		if strings.EqualFold(e, email) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/zzn2/demo/appstore/semver"
+)
+
+func TestAddAs_Ownership(t *testing.T) {
+	alice := Publisher{Email: "alice@random.com"}
+	bob := Publisher{Email: "bob@random.com"}
+	carol := Publisher{Email: "carol@random.com"}
+	admin := Publisher{Email: "admin@random.com", Privileged: true}
+
+	var store Store
+	if err := store.AddAs(Meta{Title: "App1", Version: v_0_0_1, Maintainers: []Maintainer{{Name: "Bob", Email: "Bob@Random.com"}}}, alice); err != nil {
+		t.Fatalf("Should not have error but error '%s' occurred.", err.Error())
+	}
+	if owners := store.Owners("App1"); !reflect.DeepEqual(owners, []string{"alice@random.com"}) {
+		t.Errorf("Expected the first publisher to be the owner but got %v", owners)
+	}
+
+	var tests = []struct {
+		name      string
+		app       Meta
+		publisher Publisher
+		permitted bool
+	}{
+		{"Owner", Meta{Title: "App1", Version: v_0_0_2}, alice, true},
+		// The maintainers of the latest version (0.0.2 has none) are permitted.
+		{"Former maintainer", Meta{Title: "App1", Version: v_0_0_3}, bob, false},
+		{"Other", Meta{Title: "App1", Version: v_0_0_3}, carol, false},
+		{"Privileged", Meta{Title: "App1", Version: v_0_0_3, Maintainers: []Maintainer{{Name: "Bob", Email: "bob@random.com"}}}, admin, true},
+		{"Maintainer", Meta{Title: "App1", Version: semver.Version{Patch: 4}}, bob, true},
+		{"Unidentified", Meta{Title: "App1", Version: semver.Version{Patch: 5}}, Publisher{}, true},
+		{"New title", Meta{Title: "App2", Version: v_0_0_1}, carol, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := store.AddAs(tt.app, tt.publisher)
+			if tt.permitted && err != nil {
+				t.Errorf("Should not have error but error '%s' occurred.", err.Error())
+			}
+			if !tt.permitted && !errors.Is(err, ErrNotOwner) {
+				t.Errorf("Expected not owner error but got '%v'", err)
+			}
+		})
+	}
+
+	err := store.UpdateAs(Meta{Title: "App2", Version: v_0_0_1}, nil, alice)
+	if err == nil || err.Error() != "'alice@random.com' is neither an owner nor a maintainer of the app 'App2'." {
+		t.Errorf("Expected not owner error but got '%v'", err)
+	}
+	if err := store.UpdateAs(Meta{Title: "App2", Version: v_0_0_1, Description: "desc"}, nil, carol); err != nil {
+		t.Errorf("Should not have error but error '%s' occurred.", err.Error())
+	}
+
+	err = store.AddAllAs([]Meta{{Title: "App3", Version: v_0_0_1}, {Title: "App2", Version: v_0_0_2}}, alice)
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) || len(batchErr.Errors) != 1 || !errors.Is(batchErr.Errors[1], ErrNotOwner) {
+		t.Errorf("Expected the second app in the batch to be rejected but got '%v'", err)
+	}
+	if err := store.AddAllAs([]Meta{{Title: "App3", Version: v_0_0_1}, {Title: "App3", Version: v_0_0_2}}, bob); err != nil {
+		t.Fatalf("Should not have error but error '%s' occurred.", err.Error())
+	}
+	if owners := store.Owners("App3"); !reflect.DeepEqual(owners, []string{"bob@random.com"}) {
+		t.Errorf("Expected the publisher of the batch to be the owner but got %v", owners)
+	}
+}
+
+func TestOwnerManagement(t *testing.T) {
+	alice := Publisher{Email: "alice@random.com"}
+	bob := Publisher{Email: "bob@random.com"}
+
+	var store Store
+	store.AddAs(Meta{Title: "App1", Version: v_0_0_1, Maintainers: []Maintainer{{Name: "Bob", Email: "bob@random.com"}}}, alice)
+
+	// Maintainers could publish but not manage the owners.
+	_, err := store.AddOwner("App1", "bob@random.com", bob)
+	if err == nil || err.Error() != "'bob@random.com' is not an owner of the app 'App1'." || !errors.Is(err, ErrNotOwner) {
+		t.Errorf("Expected not owner error but got '%v'", err)
+	}
+	if _, err := store.AddOwner("App2", "bob@random.com", alice); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected not found error but got '%v'", err)
+	}
+
+	owners, err := store.AddOwner("App1", "bob@random.com", alice)
+	if err != nil || !reflect.DeepEqual(owners, []string{"alice@random.com", "bob@random.com"}) {
+		t.Errorf("Expected bob to be added to the owners but got %v %v", owners, err)
+	}
+	if owners, _ := store.AddOwner("App1", "BOB@random.com", alice); len(owners) != 2 {
+		t.Errorf("Expected existing owners not to be added again but got %v", owners)
+	}
+
+	owners, err = store.TransferOwnership("App1", "carol@random.com", bob)
+	if err != nil || !reflect.DeepEqual(owners, []string{"carol@random.com"}) {
+		t.Errorf("Expected the ownership to be transferred to carol but got %v %v", owners, err)
+	}
+	if _, err := store.TransferOwnership("App1", "alice@random.com", alice); !errors.Is(err, ErrNotOwner) {
+		t.Errorf("Expected the former owner not to be permitted but got '%v'", err)
+	}
+
+	// Owners are persisted along with the apps.
+	var buf bytes.Buffer
+	if err := store.Save(&buf); err != nil {
+		t.Fatalf("Should not have error but error '%s' occurred.", err.Error())
+	}
+	var loaded Store
+	if err := loaded.Load(&buf); err != nil {
+		t.Fatalf("Should not have error but error '%s' occurred.", err.Error())
+	}
+	if owners := loaded.Owners("App1"); !reflect.DeepEqual(owners, []string{"carol@random.com"}) {
+		t.Errorf("Expected the owners to be loaded but got %v", owners)
+	}
+}
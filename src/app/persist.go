@@ -0,0 +1,175 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/zzn2/demo/appstore/semver"
+)
+
+// storeFile is the form of Store persisted on disk.
+type storeFile struct {
+	Apps     []Meta        `json:"apps"`
+	Searches []SavedSearch `json:"searches"`
+	// Owners maps the titles of the apps to the emails of their owners.
+	Owners map[string][]string `json:"owners,omitempty"`
+	// Yanked maps the titles of the apps to their yanked versions.
+	Yanked map[string][]semver.Version `json:"yanked,omitempty"`
+}
+
+// Save writes all the apps, saved searches, owners and yanked versions of the store into w in JSON format.
+func (s *Store) Save(w io.Writer) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	searches, err := s.savedSearches()
+	if err != nil {
+		return err
+	}
+	data := storeFile{
+		Apps:     s.apps,
+		Searches: searches,
+		Owners:   s.owners,
+		Yanked:   s.yanked,
+	}
+	return json.NewEncoder(w).Encode(data)
+}
+
+// Load replaces the content of the store by the data read from r, which is generated by Save.
+// Saved searches are validated against the current Meta again. See fromSaved for details.
+func (s *Store) Load(r io.Reader) error {
+	var data storeFile
+	if err := json.NewDecoder(r).Decode(&data); err != nil {
+		return fmt.Errorf("Failed to load store: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.apps = data.Apps
+	s.searches = restoreSearches(data.Searches)
+	s.owners = data.Owners
+	s.yanked = data.Yanked
+	s.reindex()
+	return nil
+}
+
+// SaveFile saves the store into the file at the given path.
+// The file is replaced atomically so that a crash during saving will not corrupt the previous data.
+func (s *Store) SaveFile(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("Failed to save store: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := s.Save(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("Failed to save store: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	stamp, err := statFile(path)
+	if err != nil {
+		return fmt.Errorf("Failed to save store: %w", err)
+	}
+	s.setStamp(stamp)
+	return nil
+}
+
+// LoadFile loads the store from the file at the given path.
+// A non-existing file is treated as an empty store.
+func (s *Store) LoadFile(path string) error {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		s.setStamp(fileStamp{})
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("Failed to load store: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("Failed to load store: %w", err)
+	}
+	if err := s.Load(f); err != nil {
+		return err
+	}
+	s.setStamp(fileStamp{info.ModTime(), info.Size()})
+	return nil
+}
+
+// ReloadFileIfChanged loads the store from the file at the given path again when the file has changed
+// since it was last loaded or saved by LoadFile or SaveFile, e.g. when it has been replaced by the import command.
+// Files are compared by their modification times and sizes. It tells whether the store has been reloaded.
+func (s *Store) ReloadFileIfChanged(path string) (bool, error) {
+	stamp, err := statFile(path)
+	if err != nil {
+		return false, fmt.Errorf("Failed to reload store: %w", err)
+	}
+	s.mu.RLock()
+	changed := !stamp.equal(s.stamp)
+	s.mu.RUnlock()
+	if !changed {
+		return false, nil
+	}
+	return true, s.LoadFile(path)
+}
+
+func (s *Store) setStamp(stamp fileStamp) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stamp = stamp
+}
+
+// fileStamp identifies the version of a file. The zero value stands for a non-existing file.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func (a fileStamp) equal(b fileStamp) bool {
+	return a.modTime.Equal(b.modTime) && a.size == b.size
+}
+
+// statFile returns the stamp of the file at the given path, or the zero value if it does not exist.
+func statFile(path string) (fileStamp, error) {
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return fileStamp{}, nil
+	} else if err != nil {
+		return fileStamp{}, err
+	}
+	return fileStamp{info.ModTime(), info.Size()}, nil
+}
+
+// savedSearches converts the saved searches into their persisted form. The caller must hold s.mu.
+func (s *Store) savedSearches() ([]SavedSearch, error) {
+	searches := make([]SavedSearch, 0, len(s.searches))
+	for _, search := range s.searches {
+		saved, err := search.toSaved()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to save search '%s': %w", search.Name, err)
+		}
+		searches = append(searches, saved)
+	}
+	return searches, nil
+}
+
+// restoreSearches restores the searches from their persisted form, see fromSaved.
+func restoreSearches(saved []SavedSearch) []Search {
+	searches := make([]Search, 0, len(saved))
+	for _, search := range saved {
+		searches = append(searches, fromSaved(search))
+	}
+	return searches
+}
@@ -0,0 +1,108 @@
+package app
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zzn2/demo/appstore/filter"
+)
+
+func TestSaveAndLoad(t *testing.T) {
+	var store Store
+	store.Add(app)
+	store.Add(app1v1)
+
+	ruleSet, _ := filter.ParseRuleSetQuery("title[like]=App&version[gt]=0.0.1", app)
+	search, _ := NewSearch("recent", ruleSet, []string{"-version"}, []string{"title"})
+	store.AddSearch(search)
+
+	path := filepath.Join(t.TempDir(), "store.json")
+	if err := store.SaveFile(path); err != nil {
+		t.Fatalf("Should not have error but error '%s' occurred.", err.Error())
+	}
+
+	var loaded Store
+	if err := loaded.LoadFile(path); err != nil {
+		t.Fatalf("Should not have error but error '%s' occurred.", err.Error())
+	}
+
+	result := loaded.GetByTitleAndVersion(app.Title, app.Version)
+	if result == nil || result.Description != app.Description || len(result.Maintainers) != 2 {
+		t.Errorf("Expected to be '%v' but got '%v'", app, result)
+	}
+
+	loadedSearch := loaded.GetSearch("recent")
+	if loadedSearch == nil {
+		t.Fatalf("Expected to find search 'recent' but got nil")
+	}
+	if loadedSearch.Error != "" {
+		t.Errorf("Expected search to be valid but got error '%s'", loadedSearch.Error)
+	}
+	if loadedSearch.Filter.QueryString() != ruleSet.QueryString() {
+		t.Errorf("Expected filter to be '%s' but got '%s'", ruleSet.QueryString(), loadedSearch.Filter.QueryString())
+	}
+}
+
+func TestLoad_RevalidatesSearches(t *testing.T) {
+	data := `{"apps":[],"searches":[{"name":"stale","filter":{"rules":[{"field":"removed","op":"eq","value":"x"}]}}]}`
+
+	var store Store
+	if err := store.Load(strings.NewReader(data)); err != nil {
+		t.Fatalf("Should not have error but error '%s' occurred.", err.Error())
+	}
+
+	search := store.GetSearch("stale")
+	expectedErrMsg := "Failed to create rule: Field with name 'removed' does not exist."
+	if search == nil || search.Error != expectedErrMsg {
+		t.Fatalf("Expected search to have error '%s' but got '%v'", expectedErrMsg, search)
+	}
+
+	// The original filter is kept when saving the store again.
+	var buf bytes.Buffer
+	store.Save(&buf)
+	if !strings.Contains(buf.String(), `"field":"removed"`) {
+		t.Errorf("Expected the invalid filter to be kept but got '%s'", buf.String())
+	}
+}
+
+func TestLoadFile_NotExist(t *testing.T) {
+	var store Store
+	if err := store.LoadFile(filepath.Join(t.TempDir(), "none.json")); err != nil {
+		t.Errorf("Should not have error but error '%s' occurred.", err.Error())
+	}
+}
+
+func TestReloadFileIfChanged(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.json")
+	var server Store
+	if err := server.LoadFile(path); err != nil {
+		t.Fatalf("Should not have error but error '%s' occurred.", err.Error())
+	}
+	server.Add(app1v1)
+	if err := server.SaveFile(path); err != nil {
+		t.Fatalf("Should not have error but error '%s' occurred.", err.Error())
+	}
+	if reloaded, err := server.ReloadFileIfChanged(path); reloaded || err != nil {
+		t.Errorf("Expected the file saved by the store not to be reloaded but got %v with error '%v'", reloaded, err)
+	}
+
+	// Another process, e.g. the import command, changes the file.
+	var other Store
+	other.LoadFile(path)
+	other.Add(app2v1)
+	if err := other.SaveFile(path); err != nil {
+		t.Fatalf("Should not have error but error '%s' occurred.", err.Error())
+	}
+
+	if reloaded, err := server.ReloadFileIfChanged(path); !reloaded || err != nil {
+		t.Errorf("Expected the changed file to be reloaded but got %v with error '%v'", reloaded, err)
+	}
+	if server.GetByTitle(app2v1.Title) == nil {
+		t.Errorf("Expected the app added by the other store after reloading")
+	}
+	if reloaded, _ := server.ReloadFileIfChanged(path); reloaded {
+		t.Errorf("Expected the file not to be reloaded again")
+	}
+}
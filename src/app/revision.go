@@ -0,0 +1,116 @@
+package app
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/zzn2/demo/appstore/semver"
+)
+
+// Revision returns the revision of the app metadata, which is a hash of its content.
+// Any change of the content results in a different revision, so it could be used as an ETag.
+func (m Meta) Revision() string {
+	// Marshalling a struct always succeeds and keeps the order of the fields.
+	data, _ := json.Marshal(m)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:16])
+}
+
+// RevisionOf returns the revision of the app in the store, which also changes when the app is yanked or unyanked,
+// since it is part of the state of the app as well as its content.
+func (s *Store) RevisionOf(meta Meta) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.revisionOf(meta)
+}
+
+// revisionOf is RevisionOf without locking, the caller must hold s.mu.
+func (s *Store) revisionOf(meta Meta) string {
+	if s.isYanked(meta.Title, meta.Version) {
+		return meta.Revision() + "-yanked"
+	}
+	return meta.Revision()
+}
+
+// NotFoundError is returned when the app does not exist in the store.
+type NotFoundError struct {
+	Title string
+	// Version is empty when no version of the app exists.
+	Version semver.Version
+}
+
+func (e *NotFoundError) Error() string {
+	if e.Version == semver.Empty {
+		return fmt.Sprintf("App with title '%s' does not exist.", e.Title)
+	}
+	return fmt.Sprintf("App with title '%s' and version '%s' does not exist.", e.Title, e.Version)
+}
+
+// Is makes errors.Is(err, ErrNotFound) report true for a NotFoundError.
+func (e *NotFoundError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
+// RevisionMismatchError is returned when the app to update does not satisfy the precondition,
+// i.e. it has been changed since it was read.
+type RevisionMismatchError struct {
+	Title    string
+	Version  semver.Version
+	Revision string
+}
+
+func (e *RevisionMismatchError) Error() string {
+	return fmt.Sprintf("App '%s' with version '%s' has been modified, the current revision is '%s'.", e.Title, e.Version, e.Revision)
+}
+
+// Is makes errors.Is(err, ErrRevisionMismatch) report true for a RevisionMismatchError.
+func (e *RevisionMismatchError) Is(target error) bool {
+	return target == ErrRevisionMismatch
+}
+
+// Update replaces the app with the same title and version in the store.
+// The precondition is checked against the current app and its revision (see RevisionOf) atomically before replacing it,
+// nil means no precondition.
+// It returns *NotFoundError if the app does not exist, or *RevisionMismatchError if the precondition is not satisfied.
+func (s *Store) Update(app Meta, precondition func(current Meta, revision string) bool) error {
+	return s.UpdateAs(app, precondition, Publisher{})
+}
+
+// UpdateAs replaces the app with the same title and version in the store on behalf of the publisher, see Update.
+// Only the owners or the maintainers of the app could update it, otherwise it returns *NotOwnerError.
+func (s *Store) UpdateAs(app Meta, precondition func(current Meta, revision string) bool, publisher Publisher) error {
+	if app.Version == semver.Empty {
+		return &MissingVersionError{app.Title}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := len(s.apps) - 1
+	for ; i >= 0; i-- {
+		if s.apps[i].Title == app.Title && s.apps[i].Version == app.Version {
+			break
+		}
+	}
+	if i < 0 {
+		return &NotFoundError{app.Title, app.Version}
+	}
+
+	if err := s.checkPublisher(app.Title, publisher); err != nil {
+		return err
+	}
+	current := s.apps[i]
+	if revision := s.revisionOf(current); precondition != nil && !precondition(current, revision) {
+		return &RevisionMismatchError{current.Title, current.Version, revision}
+	}
+
+	// Replace the whole slice instead of modifying it in place, see Store for details.
+	apps := make([]Meta, len(s.apps))
+	copy(apps, s.apps)
+	apps[i] = app
+	s.apps = apps
+	s.reindex()
+	return nil
+}
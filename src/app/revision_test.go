@@ -0,0 +1,91 @@
+package app
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/zzn2/demo/appstore/suggest"
+)
+
+func TestRevision(t *testing.T) {
+	if app1v1.Revision() != app1v1.Revision() {
+		t.Errorf("Expected revision to be stable.")
+	}
+
+	changed := app1v1
+	changed.Description = "Changed"
+	if changed.Revision() == app1v1.Revision() {
+		t.Errorf("Expected revision changed with the content but got '%s'.", changed.Revision())
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	var store Store
+	store.Add(app1v1)
+	store.Add(app2v1)
+	store.Snapshot("")
+
+	changed := app1v1
+	changed.Maintainers = []Maintainer{{Name: "Alice", Email: "alice@hotmail.com"}}
+
+	err := store.Update(changed, func(current Meta, revision string) bool {
+		return revision == "stale"
+	})
+	var mismatch *RevisionMismatchError
+	if !errors.As(err, &mismatch) || mismatch.Revision != app1v1.Revision() {
+		t.Errorf("Expected revision mismatch error with current revision but got %v.", err)
+	}
+
+	if err := store.Update(changed, func(current Meta, revision string) bool {
+		return revision == app1v1.Revision()
+	}); err != nil {
+		t.Fatalf("Should not have error but error '%s' occurred.", err.Error())
+	}
+	if got := store.GetByTitleAndVersion("App1", v_0_0_1); !reflect.DeepEqual(*got, changed) {
+		t.Errorf("Expected app %v after update but got %v.", changed, got)
+	}
+	if names, _ := store.Suggest(SuggestFieldMaintainer, "Ali", 5, suggest.Popularity); !reflect.DeepEqual(names, []string{"Alice"}) {
+		t.Errorf("Expected the updated maintainer indexed but got %v.", names)
+	}
+
+	// The snapshot taken before the update keeps the original app.
+	if !reflect.DeepEqual(store.snapshots[0].apps[0], app1v1) {
+		t.Errorf("Expected snapshot not affected by update but got %v.", store.snapshots[0].apps[0])
+	}
+
+	var notFound *NotFoundError
+	if err := store.Update(app1v2, nil); !errors.As(err, &notFound) {
+		t.Errorf("Expected not found error but got %v.", err)
+	}
+}
+
+func TestRevisionOf(t *testing.T) {
+	var store Store
+	store.Add(app1v1)
+
+	revision := store.RevisionOf(app1v1)
+	if revision != app1v1.Revision() {
+		t.Errorf("Expected revision '%s' but got '%s'.", app1v1.Revision(), revision)
+	}
+
+	store.YankAs(app1v1.Title, app1v1.Version, Publisher{})
+	yanked := store.RevisionOf(app1v1)
+	if yanked == revision {
+		t.Errorf("Expected revision changed by yanking but got '%s'.", yanked)
+	}
+
+	// A precondition on the revision read before yanking is not satisfied.
+	err := store.Update(app1v1, func(current Meta, currentRevision string) bool {
+		return currentRevision == revision
+	})
+	var mismatch *RevisionMismatchError
+	if !errors.As(err, &mismatch) || mismatch.Revision != yanked {
+		t.Errorf("Expected revision mismatch error with revision '%s' but got %v.", yanked, err)
+	}
+
+	store.UnyankAs(app1v1.Title, app1v1.Version, Publisher{})
+	if got := store.RevisionOf(app1v1); got != revision {
+		t.Errorf("Expected revision '%s' after unyanking but got '%s'.", revision, got)
+	}
+}
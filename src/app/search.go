@@ -0,0 +1,114 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/zzn2/demo/appstore/filter"
+)
+
+// Search is a named query which could be saved in the store and executed later.
+type Search struct {
+	Name   string
+	Filter filter.RuleSet
+	Sort   []string
+	Fields []string
+
+	// Error describes why the search is no longer valid, e.g. a filtered field was removed from Meta.
+	// It is empty for valid searches.
+	Error string `json:",omitempty" yaml:",omitempty"`
+
+	// rawFilter keeps the stored JSON form of an invalid filter so that it will not get lost when saving the store.
+	rawFilter json.RawMessage
+}
+
+// NewSearch creates a new Search and validates the filter, sort keys and fields against Meta.
+func NewSearch(name string, ruleSet filter.RuleSet, sort []string, fields []string) (Search, error) {
+	if name == "" {
+		return Search{}, errors.New("Search name must not be empty.")
+	}
+
+	search := Search{
+		Name:   name,
+		Filter: ruleSet,
+		Sort:   sort,
+		Fields: fields,
+	}
+	if err := search.validate(); err != nil {
+		return Search{}, err
+	}
+
+	return search, nil
+}
+
+// SortKeys returns the parsed sort keys of this search.
+func (s Search) SortKeys() ([]filter.SortKey, error) {
+	var meta Meta
+	return filter.ParseSortKeys(s.Sort, meta)
+}
+
+// String returns the string representation of this object.
+func (s Search) String() string {
+	return fmt.Sprintf("Search: %s", s.Name)
+}
+
+// validate checks the sort keys and fields of this search against Meta.
+func (s Search) validate() error {
+	var meta Meta
+	if _, err := s.SortKeys(); err != nil {
+		return err
+	}
+	return filter.ValidateFields(s.Fields, meta)
+}
+
+// SavedSearch is the form of Search persisted along with the store, and moved between stores by Catalog.
+type SavedSearch struct {
+	Name   string          `json:"name"`
+	Filter json.RawMessage `json:"filter"`
+	Sort   []string        `json:"sort,omitempty"`
+	Fields []string        `json:"fields,omitempty"`
+}
+
+// toSaved converts the search into its persisted form.
+func (s Search) toSaved() (SavedSearch, error) {
+	raw := s.rawFilter
+	if raw == nil {
+		var err error
+		if raw, err = json.Marshal(s.Filter); err != nil {
+			return SavedSearch{}, err
+		}
+	}
+
+	return SavedSearch{
+		Name:   s.Name,
+		Filter: raw,
+		Sort:   s.Sort,
+		Fields: s.Fields,
+	}, nil
+}
+
+// fromSaved restores a search from its persisted form.
+// The filter, sort keys and fields are validated against the current Meta again since Meta may have evolved since the search was saved.
+// An invalid search is still restored, but with Error set and the original filter kept.
+func fromSaved(saved SavedSearch) Search {
+	var meta Meta
+	search := Search{
+		Name:   saved.Name,
+		Sort:   saved.Sort,
+		Fields: saved.Fields,
+	}
+
+	ruleSet, err := filter.ParseRuleSetJSON(saved.Filter, meta)
+	if err == nil {
+		err = search.validate()
+	}
+	if err != nil {
+		search.Error = err.Error()
+		search.rawFilter = saved.Filter
+		return search
+	}
+
+	search.Filter = ruleSet
+	return search
+}
@@ -0,0 +1,81 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/zzn2/demo/appstore/filter"
+)
+
+func TestNewSearch(t *testing.T) {
+	var tests = []struct {
+		name           string
+		sort           []string
+		fields         []string
+		expectedErrMsg string
+	}{
+		{"valid", []string{"-version"}, []string{"title", "version"}, ""},
+		{"", nil, nil, "Search name must not be empty."},
+		{"bad sort", []string{"dummy"}, nil, "Failed to create sort key: Field with name 'dummy' does not exist."},
+		{"bad fields", nil, []string{"dummy"}, "Failed to select fields: Field with name 'dummy' does not exist."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewSearch(tt.name, filter.RuleSet{}, tt.sort, tt.fields)
+			if tt.expectedErrMsg == "" && err != nil {
+				t.Errorf("Should not have error but error '%s' occurred.", err.Error())
+			}
+			if tt.expectedErrMsg != "" && (err == nil || err.Error() != tt.expectedErrMsg) {
+				t.Errorf("Expected to have error '%s' but got '%v'", tt.expectedErrMsg, err)
+			}
+		})
+	}
+}
+
+func TestAddSearch(t *testing.T) {
+	var store Store
+
+	search, _ := NewSearch("my search", filter.RuleSet{}, nil, nil)
+	if err := store.AddSearch(search); err != nil {
+		t.Errorf("Should not have error but error '%s' occurred.", err.Error())
+	}
+
+	err := store.AddSearch(search)
+	if err == nil || err.Error() != "Search 'my search' already exists." {
+		t.Errorf("Expected to have duplicate error but got '%v'", err)
+	}
+
+	if len(store.ListSearches()) != 1 {
+		t.Errorf("Expected store contains 1 search but actually contained %d searches.", len(store.ListSearches()))
+	}
+	if store.GetSearch("my search") == nil {
+		t.Errorf("Expected to find search 'my search' but got nil")
+	}
+	if store.GetSearch("other") != nil {
+		t.Errorf("Expected to be nil but got a search")
+	}
+}
+
+func TestRunSearch(t *testing.T) {
+	var store Store
+	store.Add(app1v1)
+	store.Add(app2v1)
+	store.Add(app1v2)
+
+	ruleSet, _ := filter.ParseRuleSetQuery("title=App1", app1v1)
+	search, _ := NewSearch("app1", ruleSet, []string{"-version"}, nil)
+
+	result, err := store.RunSearch(search)
+	if err != nil {
+		t.Fatalf("Should not have error but error '%s' occurred.", err.Error())
+	}
+	if len(result) != 2 || !equals(result[0], app1v2) || !equals(result[1], app1v1) {
+		t.Errorf("Expected to be ['%s', '%s'] but got '%s'", app1v2, app1v1, result)
+	}
+
+	search.Error = "field removed"
+	_, err = store.RunSearch(search)
+	if err == nil || err.Error() != "Search 'app1' is no longer valid: field removed" {
+		t.Errorf("Expected to have invalid search error but got '%v'", err)
+	}
+}
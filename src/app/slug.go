@@ -0,0 +1,79 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Slugify derives the URL friendly identifier of an app from its title:
+// letters are lower cased, digits are kept, and any other characters in between are replaced by a single "-",
+// e.g. "App3 with space in title" becomes "app3-with-space-in-title".
+// It returns empty string if the title has no letters or digits.
+func Slugify(title string) string {
+	var b strings.Builder
+	pendingDash := false
+	for _, r := range title {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			pendingDash = b.Len() > 0
+			continue
+		}
+		if pendingDash {
+			b.WriteByte('-')
+			pendingDash = false
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// Slug returns the identifier of the app derived from its title, see Slugify.
+// The store makes sure that apps with different titles have different slugs.
+func (m Meta) Slug() string {
+	return Slugify(m.Title)
+}
+
+// TitleConflictError is returned when adding an app whose title differs from an existing one only in case or punctuation,
+// i.e. both titles have the same slug.
+type TitleConflictError struct {
+	Title    string
+	Existing string
+}
+
+func (e *TitleConflictError) Error() string {
+	return fmt.Sprintf("App title '%s' conflicts with the existing app '%s'. Titles must be unique regardless of case and punctuation.", e.Title, e.Existing)
+}
+
+// Is makes errors.Is(err, ErrTitleConflict) report true for a TitleConflictError.
+func (e *TitleConflictError) Is(target error) bool {
+	return target == ErrTitleConflict
+}
+
+// GetBySlug gets the last saved version of the app with the slug, skipping the yanked versions like GetByTitle.
+// It returns nil if the app does not exist.
+func (s *Store) GetBySlug(slug string) *Meta {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	title, ok := s.slugs[slug]
+	if !ok {
+		return nil
+	}
+	return s.latestOrNil(title)
+}
+
+// ResolveTitle returns the title of the app which has the slug.
+// It returns empty string and false if the app does not exist.
+func (s *Store) ResolveTitle(slug string) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	title, ok := s.slugs[slug]
+	return title, ok
+}
+
+// checkTitle makes sure the title does not conflict with an app of a different title, the caller must hold s.mu.
+func (s *Store) checkTitle(title string) error {
+	if existing, ok := s.slugs[Slugify(title)]; ok && existing != title {
+		return &TitleConflictError{Title: title, Existing: existing}
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package app
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSlugify(t *testing.T) {
+	var tests = []struct {
+		title    string
+		expected string
+	}{
+		{"App1", "app1"},
+		{"app1", "app1"},
+		{"App3 with space in title", "app3-with-space-in-title"},
+		{"  My -- App! 2.0 ", "my-app-2-0"},
+		{"Überapp", "überapp"},
+		{"!!!", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			if actual := Slugify(tt.title); actual != tt.expected {
+				t.Errorf("Expected slug of '%s' to be '%s' but got '%s'", tt.title, tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestAdd_TitleConflict(t *testing.T) {
+	var store Store
+	if err := store.Add(app1v1); err != nil {
+		t.Fatalf("Should not have error but error '%s' occurred.", err.Error())
+	}
+	if err := store.Add(app1v2); err != nil {
+		t.Errorf("Expected other versions of the same title to be added but got '%s'", err.Error())
+	}
+
+	err := store.Add(Meta{Title: "app1", Version: v_0_0_3})
+	var conflict *TitleConflictError
+	if !errors.As(err, &conflict) || conflict.Existing != "App1" || !errors.Is(err, ErrTitleConflict) {
+		t.Errorf("Expected title conflict with 'App1' but got '%v'", err)
+	}
+
+	err = store.AddAll([]Meta{{Title: "App 2", Version: v_0_0_1}, {Title: "app-2", Version: v_0_0_1}})
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) || len(batchErr.Errors) != 1 || !errors.Is(batchErr.Errors[1], ErrTitleConflict) {
+		t.Errorf("Expected the second app in the batch to conflict but got '%v'", err)
+	}
+}
+
+func TestGetBySlug(t *testing.T) {
+	var store Store
+	store.Add(app1v1)
+	store.Add(app1v2)
+	store.Add(Meta{Title: "App3 with space in title", Version: v_0_0_1})
+
+	if app := store.GetBySlug("app1"); app == nil || app.Version != v_0_0_2 {
+		t.Errorf("Expected the latest version of App1 but got %v", app)
+	}
+	if title, ok := store.ResolveTitle("app3-with-space-in-title"); !ok || title != "App3 with space in title" {
+		t.Errorf("Expected slug to be resolved to 'App3 with space in title' but got '%s'", title)
+	}
+	if app := store.GetBySlug("App1"); app != nil {
+		t.Errorf("Expected slugs to be exact but got %v", app)
+	}
+
+	// Slugs should follow the apps when the whole slice is replaced.
+	store.Import(Catalog{Apps: []Meta{app2v1}}, ImportReplace)
+	if _, ok := store.ResolveTitle("app1"); ok {
+		t.Errorf("Expected slug 'app1' to be removed after replacing the apps")
+	}
+	if store.GetBySlug("app2") == nil {
+		t.Errorf("Expected slug 'app2' to be resolved after replacing the apps")
+	}
+
+	result := store.Import(Catalog{Apps: []Meta{{Title: "APP2", Version: v_0_0_2}}}, ImportMerge)
+	if len(result.Conflicts) != 1 || result.Created != 0 {
+		t.Errorf("Expected the imported app to conflict with 'App2' but got %+v", result)
+	}
+}
@@ -0,0 +1,148 @@
+package app
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/zzn2/demo/appstore/semver"
+)
+
+// DefaultSnapshotRetention is the retention policy used when Store.SnapshotRetention is not set.
+var DefaultSnapshotRetention = RetentionPolicy{MaxCount: 10}
+
+// RetentionPolicy defines which snapshots are kept. Zero values mean no limit.
+type RetentionPolicy struct {
+	// MaxCount is the max number of snapshots kept, the oldest ones are removed first.
+	MaxCount int
+	// MaxAge is how long a snapshot is kept.
+	MaxAge time.Duration
+}
+
+// SnapshotInfo describes a snapshot of the store.
+type SnapshotInfo struct {
+	ID        string
+	CreatedAt time.Time
+	Label     string
+	// Apps is the number of apps in the snapshot.
+	Apps int
+}
+
+// snapshot is a point-in-time copy of the apps in the store, with their owners and yanked versions.
+// Snapshots are kept in the order they are taken.
+type snapshot struct {
+	info SnapshotInfo
+	// apps shares the underlying array with the store, which is safe since the store never modifies the stored apps in place.
+	// The capacity is limited to the length so that appending to it always copies.
+	apps []Meta
+	// owners and yanked are copies of the maps in the store, which share the slices since they are never modified in place either.
+	owners map[string][]string
+	yanked map[string][]semver.Version
+}
+
+// Snapshot takes a snapshot of the apps in the store, including their owners and yanked versions. Saved searches are not included.
+// It is cheap since the apps are not copied (copy-on-write), see Store for details.
+// Old snapshots are removed according to the SnapshotRetention policy.
+func (s *Store) Snapshot(label string) SnapshotInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.snapshotSeq++
+	snap := snapshot{
+		info: SnapshotInfo{
+			ID:        strconv.Itoa(s.snapshotSeq),
+			CreatedAt: s.now(),
+			Label:     label,
+			Apps:      len(s.apps),
+		},
+		apps:   s.apps[:len(s.apps):len(s.apps)],
+		owners: copyOwners(s.owners),
+		yanked: copyYanked(s.yanked),
+	}
+	s.snapshots = append(s.snapshots, snap)
+	s.pruneSnapshots()
+	return snap.info
+}
+
+// ListSnapshots returns the snapshots kept in the store, newest first.
+func (s *Store) ListSnapshots() []SnapshotInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pruneSnapshots()
+	result := make([]SnapshotInfo, 0, len(s.snapshots))
+	for i := len(s.snapshots) - 1; i >= 0; i-- {
+		result = append(result, s.snapshots[i].info)
+	}
+	return result
+}
+
+// RestoreSnapshot rolls the apps in the store, with their owners and yanked versions, back to the snapshot with the given ID.
+// It is atomic with respect to other modifications like Add, i.e. an app is either added before the restore and discarded by it,
+// or added after the restore.
+// The snapshot is kept after restoring, so that it could be restored again.
+func (s *Store) RestoreSnapshot(id string) (SnapshotInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pruneSnapshots()
+	for _, snap := range s.snapshots {
+		if snap.info.ID == id {
+			s.apps = snap.apps
+			// Copy the maps again, so that later changes to the store do not leak into the snapshot.
+			s.owners = copyOwners(snap.owners)
+			s.yanked = copyYanked(snap.yanked)
+			s.reindex()
+			return snap.info, nil
+		}
+	}
+	return SnapshotInfo{}, fmt.Errorf("Snapshot '%s' does not exist.", id)
+}
+
+// pruneSnapshots removes the snapshots which should not be kept by the retention policy.
+// The caller must hold s.mu.
+func (s *Store) pruneSnapshots() {
+	policy := DefaultSnapshotRetention
+	if s.SnapshotRetention != nil {
+		policy = *s.SnapshotRetention
+	}
+
+	kept := s.snapshots[:0]
+	now := s.now()
+	for _, snap := range s.snapshots {
+		if policy.MaxAge > 0 && now.Sub(snap.info.CreatedAt) > policy.MaxAge {
+			continue
+		}
+		kept = append(kept, snap)
+	}
+	if policy.MaxCount > 0 && len(kept) > policy.MaxCount {
+		kept = kept[len(kept)-policy.MaxCount:]
+	}
+	s.snapshots = kept
+}
+
+// copyOwners copies the map of owners, the slices are shared since they are never modified in place.
+func copyOwners(owners map[string][]string) map[string][]string {
+	result := make(map[string][]string, len(owners))
+	for title, emails := range owners {
+		result[title] = emails
+	}
+	return result
+}
+
+// copyYanked copies the map of yanked versions, the slices are shared since they are never modified in place.
+func copyYanked(yanked map[string][]semver.Version) map[string][]semver.Version {
+	result := make(map[string][]semver.Version, len(yanked))
+	for title, versions := range yanked {
+		result[title] = versions
+	}
+	return result
+}
+
+// now returns the current time, which could be replaced in tests.
+func (s *Store) now() time.Time {
+	if s.clock != nil {
+		return s.clock()
+	}
+	return time.Now()
+}
@@ -0,0 +1,207 @@
+package app
+
+import (
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zzn2/demo/appstore/semver"
+	"github.com/zzn2/demo/appstore/suggest"
+)
+
+func TestSnapshotAndRestore(t *testing.T) {
+	var store Store
+	store.Add(app1v1)
+
+	info := store.Snapshot("first")
+	if info.ID != "1" || info.Label != "first" || info.Apps != 1 {
+		t.Errorf("Unexpected snapshot info %+v.", info)
+	}
+
+	store.Add(app1v2)
+	store.Add(app2v1)
+
+	restored, err := store.RestoreSnapshot(info.ID)
+	if err != nil {
+		t.Fatalf("Should not have error but error '%s' occurred.", err.Error())
+	}
+	if restored != info {
+		t.Errorf("Expected restored snapshot %+v but got %+v.", info, restored)
+	}
+	if !reflect.DeepEqual(store.apps, []Meta{app1v1}) {
+		t.Errorf("Expected apps %v after restore but got %v.", []Meta{app1v1}, store.apps)
+	}
+	if titles, _ := store.Suggest(SuggestFieldTitle, "App2", 10, suggest.Popularity); len(titles) != 0 {
+		t.Errorf("Expected App2 removed from the suggestion index by restore but got %v.", titles)
+	}
+
+	// The snapshot is kept and is not affected by the apps added after restoring.
+	store.Add(app2v1)
+	if _, err := store.RestoreSnapshot(info.ID); err != nil {
+		t.Fatalf("Should not have error but error '%s' occurred.", err.Error())
+	}
+	if !reflect.DeepEqual(store.apps, []Meta{app1v1}) {
+		t.Errorf("Expected apps %v after restoring again but got %v.", []Meta{app1v1}, store.apps)
+	}
+}
+
+func TestSnapshotAndRestore_OwnersAndYanked(t *testing.T) {
+	alice := Publisher{Email: "alice@random.com"}
+	bob := Publisher{Email: "bob@random.com"}
+
+	var store Store
+	store.AddAs(Meta{Title: "App1", Version: v_0_0_1}, alice)
+	info := store.Snapshot("")
+
+	store.AddAs(Meta{Title: "App1", Version: v_0_0_2}, alice)
+	store.YankAs("App1", v_0_0_2, alice)
+	store.TransferOwnership("App1", bob.Email, alice)
+	store.AddAs(Meta{Title: "App2", Version: v_0_0_1}, bob)
+
+	if _, err := store.RestoreSnapshot(info.ID); err != nil {
+		t.Fatalf("Should not have error but error '%s' occurred.", err.Error())
+	}
+	if owners := store.Owners("App1"); !reflect.DeepEqual(owners, []string{alice.Email}) {
+		t.Errorf("Expected owners %v of App1 after restore but got %v.", []string{alice.Email}, owners)
+	}
+	if owners := store.Owners("App2"); len(owners) != 0 {
+		t.Errorf("Expected no owner of App2 after restore but got %v.", owners)
+	}
+	if store.IsYanked("App1", v_0_0_2) {
+		t.Errorf("Expected App1 %s not yanked after restore.", v_0_0_2)
+	}
+
+	// Publishing the same version again after restoring is neither yanked nor affecting the snapshot.
+	store.AddAs(Meta{Title: "App1", Version: v_0_0_2}, alice)
+	if store.IsYanked("App1", v_0_0_2) {
+		t.Errorf("Expected App1 %s republished after restore not yanked.", v_0_0_2)
+	}
+	store.YankAs("App1", v_0_0_1, alice)
+	store.AddOwner("App1", bob.Email, alice)
+	if _, err := store.RestoreSnapshot(info.ID); err != nil {
+		t.Fatalf("Should not have error but error '%s' occurred.", err.Error())
+	}
+	if store.IsYanked("App1", v_0_0_1) {
+		t.Errorf("Expected App1 %s not yanked after restoring again.", v_0_0_1)
+	}
+	if owners := store.Owners("App1"); !reflect.DeepEqual(owners, []string{alice.Email}) {
+		t.Errorf("Expected owners %v of App1 after restoring again but got %v.", []string{alice.Email}, owners)
+	}
+}
+
+func TestRestoreNonExistingSnapshot(t *testing.T) {
+	var store Store
+	_, err := store.RestoreSnapshot("42")
+	if err == nil || err.Error() != "Snapshot '42' does not exist." {
+		t.Errorf("Expected error for non-existing snapshot but got %v.", err)
+	}
+}
+
+func TestSnapshotIsNotAffectedByLaterChanges(t *testing.T) {
+	var store Store
+	// Leave spare capacity in the underlying array, so that an append without copy would write into the snapshot.
+	store.apps = make([]Meta, 0, 10)
+	store.Add(app1v1)
+	store.Snapshot("")
+
+	store.Add(app1v2)
+	store.Import(Catalog{Apps: []Meta{app2v1}}, ImportReplace)
+
+	if !reflect.DeepEqual(store.snapshots[0].apps, []Meta{app1v1}) {
+		t.Errorf("Expected snapshot apps %v but got %v.", []Meta{app1v1}, store.snapshots[0].apps)
+	}
+}
+
+func TestListSnapshots(t *testing.T) {
+	var store Store
+	store.Snapshot("a")
+	store.Snapshot("b")
+	store.Snapshot("c")
+
+	var labels []string
+	for _, info := range store.ListSnapshots() {
+		labels = append(labels, info.Label)
+	}
+	expected := []string{"c", "b", "a"}
+	if !reflect.DeepEqual(labels, expected) {
+		t.Errorf("Expected snapshots %v (newest first) but got %v.", expected, labels)
+	}
+}
+
+func TestSnapshotRetention(t *testing.T) {
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+
+	tests := []struct {
+		name     string
+		policy   *RetentionPolicy
+		expected []string
+	}{
+		{"default policy keeps 10 snapshots", nil, []string{"12", "11", "10", "9", "8", "7", "6", "5", "4", "3"}},
+		{"max count", &RetentionPolicy{MaxCount: 2}, []string{"12", "11"}},
+		{"max age", &RetentionPolicy{MaxAge: 210 * time.Minute}, []string{"12", "11", "10"}},
+		{"max count and max age", &RetentionPolicy{MaxCount: 2, MaxAge: 150 * time.Minute}, []string{"12", "11"}},
+		{"no limit", &RetentionPolicy{}, []string{"12", "11", "10", "9", "8", "7", "6", "5", "4", "3", "2", "1"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := Store{SnapshotRetention: test.policy, clock: clock}
+			now = time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+			for i := 0; i < 12; i++ {
+				store.Snapshot("")
+				now = now.Add(time.Hour)
+			}
+
+			// The last snapshot was taken an hour ago.
+			var ids []string
+			for _, info := range store.ListSnapshots() {
+				ids = append(ids, info.ID)
+			}
+			if !reflect.DeepEqual(ids, test.expected) {
+				t.Errorf("Expected snapshots %v but got %v.", test.expected, ids)
+			}
+		})
+	}
+}
+
+func TestRestoreSnapshotWithConcurrentAdd(t *testing.T) {
+	var store Store
+	store.Add(app1v1)
+	info := store.Snapshot("")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			store.Add(Meta{Title: "App" + strconv.Itoa(i), Version: semver.Version{Major: 1}})
+		}(i)
+	}
+	if _, err := store.RestoreSnapshot(info.ID); err != nil {
+		t.Errorf("Should not have error but error '%s' occurred.", err.Error())
+	}
+	wg.Wait()
+
+	// Every app added concurrently is either discarded by the restore or kept, but the index always matches the apps.
+	for _, app := range store.apps {
+		titles, _ := store.Suggest(SuggestFieldTitle, app.Title, suggest.MaxResults, suggest.Popularity)
+		if !contains(titles, app.Title) {
+			t.Errorf("Expected app %s in the suggestion index but got %v.", app.Title, titles)
+		}
+	}
+	if !reflect.DeepEqual(store.apps[0], app1v1) {
+		t.Errorf("Expected the first app %v but got %v.", app1v1, store.apps[0])
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
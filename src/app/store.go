@@ -3,45 +3,198 @@ package app
 
 import (
 	"fmt"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/zzn2/demo/appstore/filter"
+	"github.com/zzn2/demo/appstore/fuzzy"
 	"github.com/zzn2/demo/appstore/semver"
+	"github.com/zzn2/demo/appstore/suggest"
 )
 
 // Store stores metadata of apps.
 // They can be searched by various filters.
+//
+// The stored apps are never modified in place: apps are only appended, or the whole slice is replaced.
+// This makes snapshots cheap since they share the underlying array with the store (copy-on-write).
+//
+// A Store is safe for concurrent use. Reads could run in parallel, while modifications are exclusive.
+// A Store must not be copied after first use.
 type Store struct {
-	apps []Meta
+	// mu guards all the fields below.
+	mu sync.RWMutex
+
+	apps     []Meta
+	searches []Search
+
+	// titles and maintainers index the values for type-ahead suggestions.
+	// They are kept in sync with apps.
+	titles      suggest.Index
+	maintainers suggest.Index
+
+	// slugs maps the slugs of the apps to their titles. It is kept in sync with apps.
+	slugs map[string]string
+
+	// owners maps the titles of the apps to the emails of their owners, see Publisher.
+	owners map[string][]string
+
+	// yanked maps the titles of the apps to their yanked versions, see YankAs.
+	yanked map[string][]semver.Version
+
+	// stamp identifies the version of the file last loaded or saved, see ReloadFileIfChanged.
+	stamp fileStamp
+
+	// SnapshotRetention defines which snapshots are kept. DefaultSnapshotRetention is used when nil.
+	SnapshotRetention *RetentionPolicy
+	snapshots         []snapshot
+	snapshotSeq       int
+	clock             func() time.Time
+}
+
+// Fields which could be suggested by Store.Suggest.
+const (
+	SuggestFieldTitle      = "title"
+	SuggestFieldMaintainer = "maintainer"
+)
+
+// DuplicateError is returned by Add when the store already contains an app with the same title and version.
+type DuplicateError struct {
+	Existing Meta
+	// Diff is the difference from the existing app to the added one. It is empty when they are identical.
+	Diff []FieldDiff
 }
 
-var modifyLock sync.Mutex
+func (e *DuplicateError) Error() string {
+	return fmt.Sprintf("App '%s' with version '%s' already exists.", e.Existing.Title, e.Existing.Version)
+}
+
+// Is makes errors.Is(err, ErrDuplicateVersion) report true for a DuplicateError.
+func (e *DuplicateError) Is(target error) bool {
+	return target == ErrDuplicateVersion
+}
+
+// Identical tells whether the added app has the same content as the existing one, see Meta.ContentHash.
+func (e *DuplicateError) Identical() bool {
+	return len(e.Diff) == 0
+}
 
 // Add a new app metadata into the store.
-// It returns error if the store already contains an app with the same title and version.
+// It returns *DuplicateError if the store already contains an app with the same title and version,
+// or *TitleConflictError if the store contains an app with the same slug but a different title.
 func (s *Store) Add(app Meta) error {
+	return s.AddAs(app, Publisher{})
+}
+
+// AddAs adds a new app metadata published by the publisher into the store, see Add.
+// The publisher becomes the owner of a new title, while versions of an existing title could only be published
+// by its owners or maintainers, otherwise it returns *NotOwnerError.
+func (s *Store) AddAs(app Meta, publisher Publisher) error {
 	if app.Version == semver.Empty {
-		return fmt.Errorf("App '%s' lacks of version or the version could not be '%s'.)", app.Title, app.Version)
+		return &MissingVersionError{app.Title}
 	}
 
-	if s.GetByTitleAndVersion(app.Title, app.Version) != nil {
-		return fmt.Errorf("App '%s' with version '%s' already exists.", app.Title, app.Version)
+	// Check and append under the same lock so that concurrent adds of the same app could not both succeed.
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.checkTitle(app.Title); err != nil {
+		return err
+	}
+	if err := s.checkPublisher(app.Title, publisher); err != nil {
+		return err
+	}
+	if existing := s.lastOrNil(matchTitleAndVersion(app.Title, app.Version)); existing != nil {
+		return &DuplicateError{Existing: *existing, Diff: existing.Diff(app)}
 	}
 
-	modifyLock.Lock()
-	defer modifyLock.Unlock()
-	// add lock to append operation to avoid potential racing cases.
+	_, exists := s.slugs[app.Slug()]
 	s.apps = append(s.apps, app)
+	s.index(app)
+	if !exists {
+		s.claim(app.Title, publisher)
+	}
+	return nil
+}
+
+// BatchError reports the apps rejected by AddAll.
+type BatchError struct {
+	// Errors maps the index of a rejected app in the batch to the reason.
+	Errors map[int]error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("%d app(s) in the batch were rejected.", len(e.Errors))
+}
+
+// AddAll adds a batch of app metadata into the store transactionally:
+// either all the apps are added, or none of them when any of them is rejected.
+// An app is rejected if it lacks of version, the store already contains an app with the same title and version,
+// or an earlier app in the same batch has the same title and version.
+// Apps whose titles conflict with the store or an earlier app in the same batch (see Add) are rejected as well.
+// When rejected, it returns *BatchError describing every rejected app.
+func (s *Store) AddAll(apps []Meta) error {
+	return s.AddAllAs(apps, Publisher{})
+}
+
+// AddAllAs adds a batch of app metadata published by the publisher into the store transactionally, see AddAll and AddAs.
+func (s *Store) AddAllAs(apps []Meta, publisher Publisher) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	batchErr := &BatchError{Errors: make(map[int]error)}
+	seen := make(map[string]Meta)
+	seenTitles := make(map[string]string)
+	for i, app := range apps {
+		key := app.key()
+		existing, inBatch := seen[key]
+		seenTitle, slugInBatch := seenTitles[app.Slug()]
+		if app.Version == semver.Empty {
+			batchErr.Errors[i] = &MissingVersionError{app.Title}
+		} else if err := s.checkTitle(app.Title); err != nil {
+			batchErr.Errors[i] = err
+		} else if err := s.checkPublisher(app.Title, publisher); err != nil {
+			batchErr.Errors[i] = err
+		} else if slugInBatch && seenTitle != app.Title {
+			batchErr.Errors[i] = &TitleConflictError{Title: app.Title, Existing: seenTitle}
+		} else if inBatch {
+			batchErr.Errors[i] = &DuplicateError{Existing: existing, Diff: existing.Diff(app)}
+		} else if existing := s.lastOrNil(matchTitleAndVersion(app.Title, app.Version)); existing != nil {
+			batchErr.Errors[i] = &DuplicateError{Existing: *existing, Diff: existing.Diff(app)}
+		}
+		if !inBatch {
+			seen[key] = app
+		}
+		if !slugInBatch {
+			seenTitles[app.Slug()] = app.Title
+		}
+	}
+	if len(batchErr.Errors) > 0 {
+		return batchErr
+	}
+
+	newTitles := make([]string, 0)
+	for title := range seenTitles {
+		if _, exists := s.slugs[title]; !exists {
+			newTitles = append(newTitles, seenTitles[title])
+		}
+	}
+	s.apps = append(s.apps, apps...)
+	for _, app := range apps {
+		s.index(app)
+	}
+	for _, title := range newTitles {
+		s.claim(title, publisher)
+	}
 	return nil
 }
 
 // GetByTitle gets an app metadata using title.
 // It returns the matching metadata if exists, otherwise returns nil.
-// If multiple version exists for the same title, it returns the last saved version.
+// If multiple version exists for the same title, it returns the last saved version which is not yanked.
 func (s *Store) GetByTitle(title string) *Meta {
-	return s.lastOrNil(func(app Meta) bool {
-		return app.Title == title
-	})
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.latestOrNil(title)
 }
 
 // GetByTitleAndVersion gets an app metadata using title and version.
@@ -49,9 +202,9 @@ func (s *Store) GetByTitle(title string) *Meta {
 func (s *Store) GetByTitleAndVersion(title string, version semver.Version) *Meta {
 	// If multiple found, return the last one, which is likely to be the latest version.
 	// TODO: Should add version comparing logic here and only return the latest version.
-	return s.lastOrNil(func(app Meta) bool {
-		return app.Title == title && app.Version == version
-	})
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.lastOrNil(matchTitleAndVersion(title, version))
 }
 
 // List returns the list of stored apps.
@@ -59,6 +212,9 @@ func (s *Store) GetByTitleAndVersion(title string, version semver.Version) *Meta
 // RuleSet could also be empty (i.e. contains no rules). In this case, all the apps will be listed in the result.
 // If no matching apps found, return an empty slice.
 func (s *Store) List(ruleSet filter.RuleSet) ([]Meta, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
 	result := make([]Meta, 0)
 	for _, app := range s.apps {
 		matched, err := ruleSet.Match(app)
@@ -74,6 +230,128 @@ func (s *Store) List(ruleSet filter.RuleSet) ([]Meta, error) {
 	return result, nil
 }
 
+// SuggestTitles returns at most n distinct titles in the store which are close to the given title.
+// It is used to answer "did you mean" questions when an app could not be found.
+func (s *Store) SuggestTitles(title string, n int) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	titles := make([]string, 0, len(s.apps))
+	for _, app := range s.apps {
+		titles = append(titles, app.Title)
+	}
+
+	return fuzzy.Suggest(title, titles, n)
+}
+
+// Suggest returns at most n distinct values of the field starting with the prefix, ordered by the rank.
+// The field could be either SuggestFieldTitle or SuggestFieldMaintainer (the name of maintainers).
+func (s *Store) Suggest(field string, prefix string, n int, rank suggest.Rank) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	switch strings.ToLower(field) {
+	case SuggestFieldTitle:
+		return s.titles.Lookup(prefix, n, rank), nil
+	case SuggestFieldMaintainer:
+		return s.maintainers.Lookup(prefix, n, rank), nil
+	default:
+		return nil, fmt.Errorf("Field '%s' does not support suggestions.", field)
+	}
+}
+
+// AddSearch saves a new named search into the store.
+// It returns error if the store already contains a search with the same name.
+func (s *Store) AddSearch(search Search) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, existing := range s.searches {
+		if existing.Name == search.Name {
+			return fmt.Errorf("Search '%s' already exists.", search.Name)
+		}
+	}
+
+	s.searches = append(s.searches, search)
+	return nil
+}
+
+// GetSearch gets a saved search by name.
+// It returns nil if the search does not exist.
+func (s *Store) GetSearch(name string) *Search {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, search := range s.searches {
+		if search.Name == name {
+			return &search
+		}
+	}
+
+	return nil
+}
+
+// ListSearches returns all the saved searches.
+// If no searches saved, return an empty slice.
+func (s *Store) ListSearches() []Search {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	result := make([]Search, len(s.searches))
+	copy(result, s.searches)
+	return result
+}
+
+// RunSearch executes the given search against the apps in the store.
+// The matching apps are ordered by the sort keys of the search.
+// It returns error if the search is no longer valid.
+func (s *Store) RunSearch(search Search) ([]Meta, error) {
+	if search.Error != "" {
+		return nil, fmt.Errorf("Search '%s' is no longer valid: %s", search.Name, search.Error)
+	}
+
+	keys, err := search.SortKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.List(search.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	filter.SortSlice(result, keys)
+	return result, nil
+}
+
+// index adds the app into the suggestion indexes and the slugs.
+func (s *Store) index(app Meta) {
+	if s.slugs == nil {
+		s.slugs = make(map[string]string)
+	}
+	s.slugs[app.Slug()] = app.Title
+	s.titles.Add(app.Title)
+	for _, maintainer := range app.Maintainers {
+		s.maintainers.Add(maintainer.Name)
+	}
+}
+
+// reindex rebuilds the suggestion indexes and the slugs from all the apps in the store.
+func (s *Store) reindex() {
+	s.slugs = nil
+	s.titles.Reset()
+	s.maintainers.Reset()
+	for _, app := range s.apps {
+		s.index(app)
+	}
+}
+
+// The helpers below read the apps without locking, the caller must hold s.mu.
+
+// matchTitleAndVersion returns a rule matching the app with the given title and version.
+func matchTitleAndVersion(title string, version semver.Version) func(Meta) bool {
+	return func(app Meta) bool {
+		return app.Title == title && app.Version == version
+	}
+}
+
 // filter returns the apps that matches the given rule in the store.
 // It returns an empty slice if no matching apps found.
 func (s *Store) filter(match func(Meta) bool) []Meta {
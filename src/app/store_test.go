@@ -1,10 +1,14 @@
 package app
 
 import (
+	"reflect"
+	"sync"
+	"sync/atomic"
 	"testing"
 
 	"github.com/zzn2/demo/appstore/filter"
 	"github.com/zzn2/demo/appstore/semver"
+	"github.com/zzn2/demo/appstore/suggest"
 )
 
 var (
@@ -167,3 +171,143 @@ func TestList(t *testing.T) {
 		t.Errorf("Expected to be 0 items but got %d", len(result))
 	}
 }
+
+func TestSuggestTitles(t *testing.T) {
+	var store Store
+
+	store.Add(app1v1)
+	store.Add(app1v2)
+	store.Add(app2v1)
+
+	result := store.SuggestTitles("Ap1", 5)
+	if len(result) != 1 || result[0] != "App1" {
+		t.Errorf("Expected to be [App1] but got %v", result)
+	}
+
+	result = store.SuggestTitles("Zzz", 5)
+	if len(result) != 0 {
+		t.Errorf("Expected to be empty but got %v", result)
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	var store Store
+
+	store.Add(app)
+	store.Add(app1v1)
+	store.Add(app1v2)
+	store.Add(app2v1)
+
+	result, err := store.Suggest(SuggestFieldTitle, "app", 10, suggest.Popularity)
+	if err != nil {
+		t.Fatalf("Should not have error but error '%s' occurred.", err.Error())
+	}
+	expected := []string{"App1", "App2"}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected to be %v but got %v", expected, result)
+	}
+
+	result, _ = store.Suggest(SuggestFieldMaintainer, "b", 10, suggest.Popularity)
+	if !reflect.DeepEqual(result, []string{"Bob"}) {
+		t.Errorf("Expected to be [Bob] but got %v", result)
+	}
+
+	_, err = store.Suggest("company", "R", 10, suggest.Popularity)
+	if err == nil || err.Error() != "Field 'company' does not support suggestions." {
+		t.Errorf("Expected to have error but got '%v'", err)
+	}
+}
+
+func TestAddAll(t *testing.T) {
+	var store Store
+	store.Add(app1v1)
+
+	err := store.AddAll([]Meta{app1v2, app1v1, app2v1, app2v1, {Title: "App3"}})
+	batchErr, ok := err.(*BatchError)
+	if !ok {
+		t.Fatalf("Expected to be *BatchError but got '%v'", err)
+	}
+
+	expected := map[int]string{
+		1: "App 'App1' with version '0.0.1' already exists.",
+		3: "App 'App2' with version '0.0.1' already exists.",
+		4: "App 'App3' lacks of version or the version could not be '0.0.0'.)",
+	}
+	if len(batchErr.Errors) != len(expected) {
+		t.Errorf("Expected %d errors but got %d", len(expected), len(batchErr.Errors))
+	}
+	for i, message := range expected {
+		if batchErr.Errors[i] == nil || batchErr.Errors[i].Error() != message {
+			t.Errorf("Expected error of #%d to be '%s' but got '%v'", i, message, batchErr.Errors[i])
+		}
+	}
+	if len(store.apps) != 1 {
+		t.Errorf("Expected nothing added but the store contained %d apps.", len(store.apps))
+	}
+
+	if err := store.AddAll([]Meta{app1v2, app2v1}); err != nil {
+		t.Errorf("Should not have error but error '%s' occurred.", err.Error())
+	}
+	if len(store.apps) != 3 {
+		t.Errorf("Expected store contains 3 apps but actually contained %d apps.", len(store.apps))
+	}
+}
+
+// TestConcurrentAddAndList is a stress test to be run with -race.
+// Every app is added by several goroutines at the same time, exactly one of them should succeed.
+func TestConcurrentAddAndList(t *testing.T) {
+	const (
+		apps    = 50
+		writers = 8
+		readers = 8
+	)
+
+	var store Store
+	var added int32
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+
+	for r := 0; r < readers; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				if _, err := store.List(filter.RuleSet{}); err != nil {
+					t.Errorf("Should not have error but error '%s' occurred.", err.Error())
+				}
+				store.GetByTitle("App1")
+				store.Suggest(SuggestFieldTitle, "App", 5, suggest.Popularity)
+			}
+		}()
+	}
+
+	var writersWg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		writersWg.Add(1)
+		go func() {
+			defer writersWg.Done()
+			for i := 0; i < apps; i++ {
+				app := Meta{Title: "App1", Version: semver.Version{Patch: uint64(i + 1)}}
+				if err := store.Add(app); err == nil {
+					atomic.AddInt32(&added, 1)
+				}
+			}
+		}()
+	}
+	writersWg.Wait()
+	close(stop)
+	wg.Wait()
+
+	if added != apps {
+		t.Errorf("Expected %d apps added but %d were added.", apps, added)
+	}
+	list, _ := store.List(filter.RuleSet{})
+	if len(list) != apps {
+		t.Errorf("Expected store contains %d apps but actually contained %d apps.", apps, len(list))
+	}
+}
@@ -0,0 +1,91 @@
+package app
+
+import (
+	"github.com/zzn2/demo/appstore/semver"
+)
+
+// YankAs marks the version of the app as yanked on behalf of the publisher, e.g. when it has been published by mistake.
+// A yanked version is still retrievable by its version, but is no longer resolved as the latest version of the app.
+// Only the owners or the maintainers of the app could yank it.
+// It returns *NotFoundError if the version does not exist, or *NotOwnerError if the publisher is not permitted.
+func (s *Store) YankAs(title string, version semver.Version, publisher Publisher) error {
+	return s.setYanked(title, version, true, publisher)
+}
+
+// UnyankAs reverts YankAs, with the same permissions.
+func (s *Store) UnyankAs(title string, version semver.Version, publisher Publisher) error {
+	return s.setYanked(title, version, false, publisher)
+}
+
+// IsYanked tells whether the version of the app has been yanked.
+func (s *Store) IsYanked(title string, version semver.Version) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.isYanked(title, version)
+}
+
+// The helpers below access the yanked versions without locking, the caller must hold s.mu.
+
+func (s *Store) setYanked(title string, version semver.Version, yanked bool, publisher Publisher) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.lastOrNil(matchTitleAndVersion(title, version)) == nil {
+		return &NotFoundError{title, version}
+	}
+	if err := s.checkPublisher(title, publisher); err != nil {
+		return err
+	}
+	s.markYanked(title, version, yanked)
+	return nil
+}
+
+func (s *Store) markYanked(title string, version semver.Version, yanked bool) {
+	if yanked == s.isYanked(title, version) {
+		return
+	}
+
+	// Replace the slice instead of modifying it in place, so that it could be read by Save without copying.
+	versions := make([]semver.Version, 0, len(s.yanked[title])+1)
+	for _, v := range s.yanked[title] {
+		if v != version {
+			versions = append(versions, v)
+		}
+	}
+	if yanked {
+		versions = append(versions, version)
+	}
+
+	if s.yanked == nil {
+		s.yanked = make(map[string][]semver.Version)
+	}
+	if len(versions) == 0 {
+		delete(s.yanked, title)
+	} else {
+		s.yanked[title] = versions
+	}
+}
+
+func (s *Store) isYanked(title string, version semver.Version) bool {
+	for _, v := range s.yanked[title] {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
+// latestOrNil gets the last saved version of the app which is not yanked.
+// When all the versions have been yanked, it gets the last saved one, so that the app is still retrievable.
+// It returns nil if the app does not exist.
+func (s *Store) latestOrNil(title string) *Meta {
+	latest := s.lastOrNil(func(app Meta) bool {
+		return app.Title == title && !s.isYanked(app.Title, app.Version)
+	})
+	if latest != nil {
+		return latest
+	}
+	return s.lastOrNil(func(app Meta) bool {
+		return app.Title == title
+	})
+}
@@ -0,0 +1,82 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestYankAs(t *testing.T) {
+	alice := Publisher{Email: "alice@random.com"}
+	bob := Publisher{Email: "bob@random.com"}
+	carol := Publisher{Email: "carol@random.com"}
+
+	var store Store
+	store.AddAs(Meta{Title: "App1", Version: v_0_0_1}, alice)
+	store.AddAs(Meta{Title: "App1", Version: v_0_0_2, Maintainers: []Maintainer{{Name: "Bob", Email: "bob@random.com"}}}, alice)
+
+	var tests = []struct {
+		name           string
+		publisher      Publisher
+		expectedErrMsg string
+	}{
+		{"Other", carol, "'carol@random.com' is neither an owner nor a maintainer of the app 'App1'."},
+		{"Maintainer", bob, ""},
+		{"Owner yanks again", alice, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := store.YankAs("App1", v_0_0_2, tt.publisher)
+			if tt.expectedErrMsg == "" && err != nil {
+				t.Errorf("Should not have error but error '%s' occurred.", err.Error())
+			}
+			if tt.expectedErrMsg != "" && (!errors.Is(err, ErrNotOwner) || err.Error() != tt.expectedErrMsg) {
+				t.Errorf("Expected error '%s' but got '%v'", tt.expectedErrMsg, err)
+			}
+		})
+	}
+
+	if err := store.YankAs("App1", v_0_0_3, alice); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected not found error but got '%v'", err)
+	}
+
+	// The yanked version is still retrievable by its version, but not resolved as the latest one.
+	if !store.IsYanked("App1", v_0_0_2) || store.IsYanked("App1", v_0_0_1) {
+		t.Errorf("Expected only 0.0.2 to be yanked")
+	}
+	if latest := store.GetByTitle("App1"); latest == nil || latest.Version != v_0_0_1 {
+		t.Errorf("Expected the latest version to be 0.0.1 but got %v", latest)
+	}
+	if latest := store.GetBySlug("app1"); latest == nil || latest.Version != v_0_0_1 {
+		t.Errorf("Expected the latest version to be 0.0.1 but got %v", latest)
+	}
+	if app := store.GetByTitleAndVersion("App1", v_0_0_2); app == nil {
+		t.Errorf("Expected the yanked version to be retrievable")
+	}
+
+	// Yanked versions are persisted.
+	var buf bytes.Buffer
+	if err := store.Save(&buf); err != nil {
+		t.Fatalf("Should not have error but error '%s' occurred.", err.Error())
+	}
+	var loaded Store
+	if err := loaded.Load(&buf); err != nil {
+		t.Fatalf("Should not have error but error '%s' occurred.", err.Error())
+	}
+	if !loaded.IsYanked("App1", v_0_0_2) {
+		t.Errorf("Expected the yanked version to be loaded")
+	}
+
+	// When all the versions are yanked, the last saved one is still resolved.
+	store.YankAs("App1", v_0_0_1, alice)
+	if latest := store.GetByTitle("App1"); latest == nil || latest.Version != v_0_0_2 {
+		t.Errorf("Expected the last saved version 0.0.2 but got %v", latest)
+	}
+
+	if err := store.UnyankAs("App1", v_0_0_2, bob); err != nil {
+		t.Errorf("Should not have error but error '%s' occurred.", err.Error())
+	}
+	if store.IsYanked("App1", v_0_0_2) || !store.IsYanked("App1", v_0_0_1) {
+		t.Errorf("Expected only 0.0.1 to be yanked after unyanking 0.0.2")
+	}
+}
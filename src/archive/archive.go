@@ -0,0 +1,238 @@
+// Package archive provides a portable archive format of app metadata,
+// used to move catalogs between environments and to keep offline backups.
+//
+// An archive is a gzipped tar file which contains:
+//
+//    manifest.json                 -> format version, creation time and checksums of all the entries
+//    apps/<title>/<version>.yaml   -> metadata of an app, one file per version
+//    searches.json                 -> saved searches
+//    owners.json                   -> emails of the owners by app title
+//    yanked.json                   -> yanked versions by app title
+//
+// The manifest is always the first entry, so that the entries could be verified while reading the archive.
+// Archives of format version 1 only contain the apps.
+package archive
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"path"
+	"time"
+
+	"github.com/zzn2/demo/appstore/app"
+	"gopkg.in/yaml.v2"
+)
+
+// FormatVersion is the version of the archive format written by Write.
+// Read rejects archives with newer format versions.
+const FormatVersion = 2
+
+// ManifestName is the name of the manifest entry in the archive.
+const ManifestName = "manifest.json"
+
+// The names of the entries of the store content other than the apps.
+const (
+	SearchesName = "searches.json"
+	OwnersName   = "owners.json"
+	YankedName   = "yanked.json"
+)
+
+// Manifest describes the content of an archive.
+type Manifest struct {
+	FormatVersion int       `json:"formatVersion"`
+	CreatedAt     time.Time `json:"createdAt"`
+	Apps          []Entry   `json:"apps"`
+	// Extras lists the entries other than the apps, i.e. searches.json, owners.json and yanked.json.
+	Extras []Extra `json:"extras,omitempty"`
+}
+
+// Entry describes an app file in the archive.
+type Entry struct {
+	Path    string `json:"path"`
+	Title   string `json:"title"`
+	Version string `json:"version"`
+	SHA256  string `json:"sha256"`
+}
+
+// Extra describes an entry of the store content other than the apps.
+type Extra struct {
+	Path   string `json:"path"`
+	SHA256 string `json:"sha256"`
+}
+
+// Write writes the content of the store into w as an archive.
+func Write(w io.Writer, catalog app.Catalog, createdAt time.Time) error {
+	manifest := Manifest{
+		FormatVersion: FormatVersion,
+		CreatedAt:     createdAt.UTC(),
+		Apps:          make([]Entry, 0, len(catalog.Apps)),
+	}
+	files := make([][]byte, 0, len(catalog.Apps))
+	for _, meta := range catalog.Apps {
+		data, err := yaml.Marshal(meta)
+		if err != nil {
+			return fmt.Errorf("Failed to write archive: %w", err)
+		}
+		files = append(files, data)
+		manifest.Apps = append(manifest.Apps, Entry{
+			Path:    entryPath(meta),
+			Title:   meta.Title,
+			Version: meta.Version.String(),
+			SHA256:  checksum(data),
+		})
+	}
+
+	extras := []struct {
+		name  string
+		value interface{}
+	}{
+		{SearchesName, catalog.Searches},
+		{OwnersName, catalog.Owners},
+		{YankedName, catalog.Yanked},
+	}
+	for _, extra := range extras {
+		data, err := json.Marshal(extra.value)
+		if err != nil {
+			return fmt.Errorf("Failed to write archive: %w", err)
+		}
+		files = append(files, data)
+		manifest.Extras = append(manifest.Extras, Extra{Path: extra.name, SHA256: checksum(data)})
+	}
+
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("Failed to write archive: %w", err)
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	if err := writeEntry(tw, ManifestName, manifestData, manifest.CreatedAt); err != nil {
+		return err
+	}
+	for i, entry := range manifest.Apps {
+		if err := writeEntry(tw, entry.Path, files[i], manifest.CreatedAt); err != nil {
+			return err
+		}
+	}
+	for i, extra := range manifest.Extras {
+		if err := writeEntry(tw, extra.Path, files[len(manifest.Apps)+i], manifest.CreatedAt); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("Failed to write archive: %w", err)
+	}
+	return gz.Close()
+}
+
+// Read reads the content of the store from an archive generated by Write.
+// It verifies the format version and that every entry listed in the manifest exists and matches its checksum.
+func Read(r io.Reader) (app.Catalog, Manifest, error) {
+	var catalog app.Catalog
+	var manifest Manifest
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return app.Catalog{}, manifest, fmt.Errorf("Failed to read archive: %w", err)
+	}
+	tr := tar.NewReader(gz)
+
+	header, err := tr.Next()
+	if err != nil || header.Name != ManifestName {
+		return app.Catalog{}, manifest, fmt.Errorf("Failed to read archive: The first entry must be '%s'", ManifestName)
+	}
+	if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+		return app.Catalog{}, manifest, fmt.Errorf("Failed to read archive manifest: %w", err)
+	}
+	if manifest.FormatVersion < 1 || manifest.FormatVersion > FormatVersion {
+		return app.Catalog{}, manifest, fmt.Errorf("Failed to read archive: Unsupported format version %d", manifest.FormatVersion)
+	}
+
+	checksums := make(map[string]string, len(manifest.Apps)+len(manifest.Extras))
+	for _, entry := range manifest.Apps {
+		checksums[entry.Path] = entry.SHA256
+	}
+	for _, extra := range manifest.Extras {
+		checksums[extra.Path] = extra.SHA256
+	}
+
+	catalog.Apps = make([]app.Meta, 0, len(manifest.Apps))
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return app.Catalog{}, manifest, fmt.Errorf("Failed to read archive: %w", err)
+		}
+
+		sum, ok := checksums[header.Name]
+		if !ok {
+			return app.Catalog{}, manifest, fmt.Errorf("Failed to read archive: Entry '%s' is not listed in the manifest", header.Name)
+		}
+		delete(checksums, header.Name)
+
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return app.Catalog{}, manifest, fmt.Errorf("Failed to read archive entry '%s': %w", header.Name, err)
+		}
+		if checksum(data) != sum {
+			return app.Catalog{}, manifest, fmt.Errorf("Failed to read archive entry '%s': Checksum mismatch", header.Name)
+		}
+
+		switch header.Name {
+		case SearchesName:
+			err = json.Unmarshal(data, &catalog.Searches)
+		case OwnersName:
+			err = json.Unmarshal(data, &catalog.Owners)
+		case YankedName:
+			err = json.Unmarshal(data, &catalog.Yanked)
+		default:
+			var meta app.Meta
+			if err = yaml.Unmarshal(data, &meta); err == nil {
+				catalog.Apps = append(catalog.Apps, meta)
+			}
+		}
+		if err != nil {
+			return app.Catalog{}, manifest, fmt.Errorf("Failed to read archive entry '%s': %w", header.Name, err)
+		}
+	}
+
+	for name := range checksums {
+		return app.Catalog{}, manifest, fmt.Errorf("Failed to read archive: Entry '%s' listed in the manifest is missing", name)
+	}
+	return catalog, manifest, nil
+}
+
+// entryPath returns the path of the app in the archive.
+// The title is escaped since it may contain characters like "/".
+func entryPath(meta app.Meta) string {
+	return path.Join("apps", url.PathEscape(meta.Title), meta.Version.String()+".yaml")
+}
+
+func writeEntry(tw *tar.Writer, name string, data []byte, modTime time.Time) error {
+	header := &tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: modTime,
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("Failed to write archive entry '%s': %w", name, err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		return fmt.Errorf("Failed to write archive entry '%s': %w", name, err)
+	}
+	return nil
+}
+
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,172 @@
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zzn2/demo/appstore/app"
+	"github.com/zzn2/demo/appstore/semver"
+)
+
+var apps = []app.Meta{
+	{
+		Title:       "App1",
+		Version:     semver.Version{Major: 0, Minor: 0, Patch: 1},
+		Maintainers: []app.Maintainer{{Name: "Alice", Email: "alice@hotmail.com"}},
+		Company:     "Random Inc.",
+		Website:     "https://website.com",
+		Source:      "https://github.com/random/repo",
+		License:     "Apache-2.0",
+		Description: "### Interesting Title\nSome application content, and description\n",
+	},
+	{
+		Title:       "App/2 with space",
+		Version:     semver.Version{Major: 1, Minor: 2, Patch: 3},
+		Maintainers: []app.Maintainer{{Name: "Bob", Email: "bob@gmail.com"}},
+		Company:     "Random Inc.",
+		Website:     "https://website.com",
+		Source:      "https://github.com/random/repo",
+		License:     "MIT",
+		Description: "Another app",
+	},
+}
+
+var catalog = app.Catalog{
+	Apps:     apps,
+	Searches: []app.SavedSearch{{Name: "mit", Filter: []byte(`{"license":{"eq":"MIT"}}`), Sort: []string{"-version"}}},
+	Owners:   map[string][]string{"App1": {"alice@hotmail.com"}},
+	Yanked:   map[string][]semver.Version{"App/2 with space": {{Major: 1, Minor: 2, Patch: 3}}},
+}
+
+var createdAt = time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)
+
+func TestWriteAndRead(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, catalog, createdAt); err != nil {
+		t.Fatalf("Should not have error but error '%s' occurred.", err.Error())
+	}
+
+	result, manifest, err := Read(&buf)
+	if err != nil {
+		t.Fatalf("Should not have error but error '%s' occurred.", err.Error())
+	}
+	if !reflect.DeepEqual(result, catalog) {
+		t.Errorf("Expected to be '%v' but got '%v'", catalog, result)
+	}
+
+	if manifest.FormatVersion != FormatVersion || !manifest.CreatedAt.Equal(createdAt) {
+		t.Errorf("Unexpected manifest '%v'", manifest)
+	}
+	expectedPaths := []string{"apps/App1/0.0.1.yaml", "apps/App%2F2%20with%20space/1.2.3.yaml"}
+	for i, entry := range manifest.Apps {
+		if entry.Path != expectedPaths[i] {
+			t.Errorf("Expected path to be '%s' but got '%s'", expectedPaths[i], entry.Path)
+		}
+		if len(entry.SHA256) != 64 {
+			t.Errorf("Expected SHA256 checksum but got '%s'", entry.SHA256)
+		}
+	}
+	if len(manifest.Extras) != 3 || manifest.Extras[0].Path != SearchesName || len(manifest.Extras[0].SHA256) != 64 {
+		t.Errorf("Expected searches, owners and yanked versions in the manifest but got '%v'", manifest.Extras)
+	}
+}
+
+func TestRead_FormatVersion1(t *testing.T) {
+	app1 := "title: App1\nversion: 0.0.1\n"
+	entries := []tarEntry{
+		{ManifestName, `{"formatVersion":1,"apps":[{"path":"apps/App1/0.0.1.yaml","sha256":"` + checksum([]byte(app1)) + `"}]}`},
+		{"apps/App1/0.0.1.yaml", app1},
+	}
+
+	result, _, err := Read(bytes.NewReader(makeArchive(t, entries)))
+	if err != nil {
+		t.Fatalf("Should not have error but error '%s' occurred.", err.Error())
+	}
+	if len(result.Apps) != 1 || result.Apps[0].Title != "App1" || result.Searches != nil || result.Owners != nil || result.Yanked != nil {
+		t.Errorf("Expected only the app to be read but got '%v'", result)
+	}
+}
+
+func TestRead_Errors(t *testing.T) {
+	manifest := func(content string) tarEntry {
+		return tarEntry{ManifestName, content}
+	}
+	app1 := "title: App1\nversion: 0.0.1\n"
+	// A checksum which does not match app1.
+	app1Sum := "1cd7ee9a6c7a0d6f0d79e5f4a5b7bdf6a0a1e7ec9b3a8a2b2e3b7c0f1b5c0d5e"
+
+	var tests = []struct {
+		name           string
+		entries        []tarEntry
+		expectedErrMsg string
+	}{
+		{
+			"no manifest",
+			[]tarEntry{{"apps/App1/0.0.1.yaml", app1}},
+			"Failed to read archive: The first entry must be 'manifest.json'",
+		},
+		{
+			"newer format",
+			[]tarEntry{manifest(`{"formatVersion":3,"apps":[]}`)},
+			"Failed to read archive: Unsupported format version 3",
+		},
+		{
+			"unlisted entry",
+			[]tarEntry{manifest(`{"formatVersion":1,"apps":[]}`), {"apps/App1/0.0.1.yaml", app1}},
+			"Failed to read archive: Entry 'apps/App1/0.0.1.yaml' is not listed in the manifest",
+		},
+		{
+			"checksum mismatch",
+			[]tarEntry{manifest(`{"formatVersion":1,"apps":[{"path":"apps/App1/0.0.1.yaml","sha256":"` + app1Sum + `"}]}`), {"apps/App1/0.0.1.yaml", app1}},
+			"Failed to read archive entry 'apps/App1/0.0.1.yaml': Checksum mismatch",
+		},
+		{
+			"bad owners",
+			[]tarEntry{manifest(`{"formatVersion":2,"apps":[],"extras":[{"path":"owners.json","sha256":"` + checksum([]byte("[]")) + `"}]}`), {OwnersName, "[]"}},
+			"Failed to read archive entry 'owners.json': json: cannot unmarshal array into Go value of type map[string][]string",
+		},
+		{
+			"missing entry",
+			[]tarEntry{manifest(`{"formatVersion":1,"apps":[{"path":"apps/App1/0.0.1.yaml"}]}`)},
+			"Failed to read archive: Entry 'apps/App1/0.0.1.yaml' listed in the manifest is missing",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := Read(bytes.NewReader(makeArchive(t, tt.entries)))
+			if err == nil || err.Error() != tt.expectedErrMsg {
+				t.Errorf("Expected to have error '%s' but got '%v'", tt.expectedErrMsg, err)
+			}
+		})
+	}
+
+	_, _, err := Read(strings.NewReader("not gzip"))
+	if err == nil || !strings.HasPrefix(err.Error(), "Failed to read archive: ") {
+		t.Errorf("Expected to have error but got '%v'", err)
+	}
+}
+
+type tarEntry struct {
+	name    string
+	content string
+}
+
+func makeArchive(t *testing.T, entries []tarEntry) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, entry := range entries {
+		if err := writeEntry(tw, entry.name, []byte(entry.content), createdAt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tw.Close()
+	gz.Close()
+	return buf.Bytes()
+}
@@ -0,0 +1,146 @@
+// Package auth authenticates the clients of the API and tells what they are permitted to do.
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Scope is a permission granted to a client.
+type Scope string
+
+const (
+	// ScopeAppsRead permits reading apps, searches and suggestions.
+	ScopeAppsRead Scope = "apps:read"
+	// ScopeAppsWrite permits creating and updating apps and searches.
+	ScopeAppsWrite Scope = "apps:write"
+	// ScopeAdmin permits everything, including managing API keys, snapshots, exports and imports.
+	ScopeAdmin Scope = "admin"
+)
+
+// Scopes are all the known scopes.
+var Scopes = []Scope{ScopeAppsRead, ScopeAppsWrite, ScopeAdmin}
+
+// Kinds of errors returned by this package, which could be matched by errors.Is.
+var (
+	// ErrInvalidCredentials means the credentials are missing, malformed, unknown or expired.
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	// ErrInsufficientScope means the client is authenticated but not permitted to do the operation.
+	ErrInsufficientScope = errors.New("insufficient scope")
+	// ErrUnsupportedToken means the token is not in the form accepted by an authenticator, e.g. an API key presented to a JWT authenticator.
+	// Errors matching it also match ErrInvalidCredentials.
+	ErrUnsupportedToken = errors.New("unsupported token")
+)
+
+// Principal is an authenticated client.
+type Principal struct {
+	// ID uniquely identifies the client among all the authenticators, e.g. "key:<id of the API key>" or "jwt:<subject>".
+	ID string
+	// Subject identifies the client, e.g. the name of an API key.
+	Subject string
+	// Email is the email of the person or the team behind the client, if known, which identifies it as an owner or a maintainer of apps.
+	Email  string
+	Scopes []Scope
+}
+
+// Has tells whether the principal is granted the scope. ScopeAdmin implies all the other scopes.
+func (p Principal) Has(scope Scope) bool {
+	for _, granted := range p.Scopes {
+		if granted == scope || granted == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// Authenticator authenticates clients by the bearer tokens they present.
+type Authenticator interface {
+	// Authenticate returns the principal of the token.
+	// It returns an error matching ErrInvalidCredentials if the token is not accepted.
+	Authenticate(token string) (Principal, error)
+}
+
+// Chain authenticates clients by the first authenticator supporting the form of the token,
+// so that API keys and JWTs could be accepted at the same time.
+type Chain []Authenticator
+
+// Authenticate returns the result of the first authenticator whose error does not match ErrUnsupportedToken.
+// If no authenticator supports the token, the error of the first one is returned.
+func (chain Chain) Authenticate(token string) (Principal, error) {
+	var first error
+	for _, authenticator := range chain {
+		principal, err := authenticator.Authenticate(token)
+		if !errors.Is(err, ErrUnsupportedToken) {
+			return principal, err
+		}
+		if first == nil {
+			first = err
+		}
+	}
+	if first == nil {
+		first = ErrInvalidCredentials
+	}
+	return Principal{}, first
+}
+
+// InsufficientScopeError is returned when a principal lacks of the scope required by an operation.
+type InsufficientScopeError struct {
+	Subject string
+	Scope   Scope
+}
+
+func (e *InsufficientScopeError) Error() string {
+	return fmt.Sprintf("'%s' is not granted the scope '%s'.", e.Subject, e.Scope)
+}
+
+// Is makes errors.Is(err, ErrInsufficientScope) report true for an InsufficientScopeError.
+func (e *InsufficientScopeError) Is(target error) bool {
+	return target == ErrInsufficientScope
+}
+
+// Authorize returns *InsufficientScopeError if the principal is not granted the scope.
+func (p Principal) Authorize(scope Scope) error {
+	if !p.Has(scope) {
+		return &InsufficientScopeError{Subject: p.Subject, Scope: scope}
+	}
+	return nil
+}
+
+// ParseScopes parses a comma separated list of scopes, e.g. "apps:read,apps:write".
+func ParseScopes(text string) ([]Scope, error) {
+	scopes := make([]Scope, 0)
+	for _, item := range strings.Split(text, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		scope, err := ParseScope(item)
+		if err != nil {
+			return nil, err
+		}
+		scopes = append(scopes, scope)
+	}
+	if len(scopes) == 0 {
+		return nil, errors.New("At least one scope is required.")
+	}
+	return scopes, nil
+}
+
+// ParseScope parses the text into a known scope.
+func ParseScope(text string) (Scope, error) {
+	for _, scope := range Scopes {
+		if string(scope) == text {
+			return scope, nil
+		}
+	}
+	return "", fmt.Errorf("Unknown scope '%s'. Available scopes are: %s", text, joinScopes(Scopes))
+}
+
+func joinScopes(scopes []Scope) string {
+	texts := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		texts = append(texts, string(scope))
+	}
+	return strings.Join(texts, ", ")
+}
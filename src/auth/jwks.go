@@ -0,0 +1,172 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// jwksFetchTimeout is the timeout of fetching a JWKS from a URL.
+const jwksFetchTimeout = 10 * time.Second
+
+// jwk is a verification key in a JSON Web Key Set, see RFC 7517.
+// Exactly one of rsaKey, ecKey and secret is set according to the key type.
+type jwk struct {
+	id     string
+	alg    string
+	rsaKey *rsa.PublicKey
+	ecKey  *ecdsa.PublicKey
+	secret []byte
+}
+
+// supports tells whether the key could verify signatures of the algorithm.
+func (k jwk) supports(alg string) bool {
+	if k.alg != "" && k.alg != alg {
+		return false
+	}
+	switch alg {
+	case "RS256":
+		return k.rsaKey != nil
+	case "ES256":
+		return k.ecKey != nil && k.ecKey.Curve == elliptic.P256()
+	case "HS256":
+		return k.secret != nil
+	}
+	return false
+}
+
+// jwkFile is a key in the JSON form of a JWKS.
+type jwkFile struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	// RSA keys.
+	N string `json:"n"`
+	E string `json:"e"`
+	// EC keys.
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	// Symmetric keys.
+	K string `json:"k"`
+}
+
+// loadJWKS reads a JWKS from a local file, or fetches it when location is an http(s) URL.
+func loadJWKS(location string) ([]jwk, error) {
+	var data []byte
+	var err error
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		data, err = fetchJWKS(location)
+	} else {
+		data, err = ioutil.ReadFile(location)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Failed to load JWKS: %w", err)
+	}
+	return parseJWKS(data)
+}
+
+func fetchJWKS(url string) ([]byte, error) {
+	client := http.Client{Timeout: jwksFetchTimeout}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s responded %s", url, resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// parseJWKS parses the keys for verifying signatures in a JWKS.
+// Keys for encryption and keys of unsupported types are ignored.
+func parseJWKS(data []byte) ([]jwk, error) {
+	var set struct {
+		Keys []jwkFile `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("Failed to parse JWKS: %w", err)
+	}
+
+	keys := make([]jwk, 0, len(set.Keys))
+	for i, file := range set.Keys {
+		if file.Use != "" && file.Use != "sig" {
+			continue
+		}
+		key, err := file.parse()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse key %d of JWKS: %w", i, err)
+		}
+		if key != nil {
+			keys = append(keys, *key)
+		}
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("JWKS has no keys for verifying signatures.")
+	}
+	return keys, nil
+}
+
+// parse returns nil if the key type is not supported.
+func (f jwkFile) parse() (*jwk, error) {
+	key := jwk{id: f.Kid, alg: f.Alg}
+	switch f.Kty {
+	case "RSA":
+		n, err := decodeBigInt(f.N, "n")
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(f.E, "e")
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, errors.New("Exponent 'e' is too large.")
+		}
+		key.rsaKey = &rsa.PublicKey{N: n, E: int(e.Int64())}
+	case "EC":
+		if f.Crv != "P-256" {
+			return nil, fmt.Errorf("Curve '%s' is not supported.", f.Crv)
+		}
+		x, err := decodeBigInt(f.X, "x")
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(f.Y, "y")
+		if err != nil {
+			return nil, err
+		}
+		if !elliptic.P256().IsOnCurve(x, y) {
+			return nil, errors.New("Point is not on the curve P-256.")
+		}
+		key.ecKey = &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+	case "oct":
+		secret, err := base64.RawURLEncoding.DecodeString(f.K)
+		if err != nil || len(secret) == 0 {
+			return nil, errors.New("Bad format of 'k'.")
+		}
+		key.secret = secret
+	default:
+		return nil, nil
+	}
+	return &key, nil
+}
+
+func decodeBigInt(text string, name string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(text)
+	if err != nil || len(b) == 0 {
+		return nil, fmt.Errorf("Bad format of '%s'.", name)
+	}
+	return new(big.Int).SetBytes(b), nil
+}
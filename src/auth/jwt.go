@@ -0,0 +1,346 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// jwksRefreshInterval is the min interval of fetching the JWKS again when a token is signed by an unknown key,
+// which happens after the identity provider rotates its keys.
+const jwksRefreshInterval = time.Minute
+
+// JWTConfig configures how JWTs issued by an identity provider are verified. It is loaded from a YAML (or JSON) file like:
+//
+//    jwks: https://sso.example.com/.well-known/jwks.json
+//    issuer: https://sso.example.com
+//    audience: appstore
+//    clockSkew: 1m
+//    scopeClaim: groups
+//    scopes:
+//      appstore-readers: [apps:read]
+//      appstore-publishers: [apps:read, apps:write]
+//      appstore-admins: [admin]
+type JWTConfig struct {
+	// JWKS is the path or the http(s) URL of the JSON Web Key Set to verify the signatures.
+	JWKS     string `yaml:"jwks"`
+	Issuer   string `yaml:"issuer"`
+	Audience string `yaml:"audience"`
+	// ClockSkew is tolerated when checking the expiry and the not-before time.
+	ClockSkew time.Duration `yaml:"clockSkew"`
+	// SubjectClaim is the claim identifying the principal, "sub" by default.
+	SubjectClaim string `yaml:"subjectClaim"`
+	// EmailClaim is the claim of the email of the principal, "email" by default.
+	// The email is ignored when the "email_verified" claim is false.
+	EmailClaim string `yaml:"emailClaim"`
+	// ScopeClaim is the claim whose values are mapped to scopes, "scope" by default.
+	// Its value is either a space separated string or an array of strings.
+	ScopeClaim string `yaml:"scopeClaim"`
+	// Scopes maps the values of ScopeClaim to scopes. When empty, the values are taken as scopes themselves.
+	// Values without scopes are ignored.
+	Scopes map[string][]Scope `yaml:"scopes"`
+}
+
+// LoadJWTConfig loads the JWTConfig from the file at the given path.
+func LoadJWTConfig(path string) (JWTConfig, error) {
+	var config JWTConfig
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return config, fmt.Errorf("Failed to read JWT config file: %w", err)
+	}
+	if err := yaml.UnmarshalStrict(data, &config); err != nil {
+		return config, fmt.Errorf("Failed to parse JWT config: %w", err)
+	}
+	return config, nil
+}
+
+// TokenError is returned when a JWT is malformed, not properly signed, or its claims are not accepted.
+type TokenError struct {
+	Reason string
+	// unsupported is set when the token is not a JWT at all.
+	unsupported bool
+}
+
+func (e *TokenError) Error() string {
+	return fmt.Sprintf("Invalid token: %s", e.Reason)
+}
+
+// Is makes errors.Is(err, ErrInvalidCredentials) report true for a TokenError,
+// as well as errors.Is(err, ErrUnsupportedToken) when the token is not a JWT.
+func (e *TokenError) Is(target error) bool {
+	return target == ErrInvalidCredentials || (e.unsupported && target == ErrUnsupportedToken)
+}
+
+// JWTAuthenticator authenticates clients by JWTs signed with RS256, ES256 or HS256. It is safe for concurrent use.
+type JWTAuthenticator struct {
+	config JWTConfig
+	// now returns the current time, which could be replaced in tests.
+	now func() time.Time
+
+	mu        sync.RWMutex
+	keys      []jwk
+	fetchedAt time.Time
+}
+
+// NewJWTAuthenticator creates a JWTAuthenticator and loads the JWKS of the config.
+// The JWKS, the issuer and the audience are required.
+func NewJWTAuthenticator(config JWTConfig) (*JWTAuthenticator, error) {
+	switch {
+	case config.JWKS == "":
+		return nil, errors.New("JWKS of the JWT config is required.")
+	case config.Issuer == "":
+		return nil, errors.New("Issuer of the JWT config is required.")
+	case config.Audience == "":
+		return nil, errors.New("Audience of the JWT config is required.")
+	case config.ClockSkew < 0:
+		return nil, fmt.Errorf("Clock skew '%s' of the JWT config must not be negative.", config.ClockSkew)
+	}
+	for value, scopes := range config.Scopes {
+		for _, scope := range scopes {
+			if _, err := ParseScope(string(scope)); err != nil {
+				return nil, fmt.Errorf("Bad scopes of '%s' in the JWT config: %w", value, err)
+			}
+		}
+	}
+	if config.SubjectClaim == "" {
+		config.SubjectClaim = "sub"
+	}
+	if config.EmailClaim == "" {
+		config.EmailClaim = "email"
+	}
+	if config.ScopeClaim == "" {
+		config.ScopeClaim = "scope"
+	}
+
+	keys, err := loadJWKS(config.JWKS)
+	if err != nil {
+		return nil, err
+	}
+	return &JWTAuthenticator{config: config, now: time.Now, keys: keys, fetchedAt: time.Now()}, nil
+}
+
+// jwtHeader is the JOSE header of a JWT.
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// Authenticate verifies the signature and the claims of the JWT, and returns its principal.
+// It returns *TokenError if the token is not accepted.
+func (a *JWTAuthenticator) Authenticate(token string) (Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Principal{}, &TokenError{Reason: "Malformed token.", unsupported: true}
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return Principal{}, &TokenError{Reason: "Malformed header."}
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Principal{}, &TokenError{Reason: "Malformed signature."}
+	}
+	if header.Alg != "RS256" && header.Alg != "ES256" && header.Alg != "HS256" {
+		return Principal{}, &TokenError{Reason: fmt.Sprintf("Algorithm '%s' is not supported. Supported algorithms are: RS256, ES256, HS256", header.Alg)}
+	}
+
+	keys := a.candidateKeys(header)
+	if len(keys) == 0 {
+		return Principal{}, &TokenError{Reason: fmt.Sprintf("No key '%s' for the algorithm %s.", header.Kid, header.Alg)}
+	}
+	signed := []byte(parts[0] + "." + parts[1])
+	verified := false
+	for _, key := range keys {
+		if verify(header.Alg, key, signed, signature) {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return Principal{}, &TokenError{Reason: "Bad signature."}
+	}
+
+	var claims map[string]interface{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return Principal{}, &TokenError{Reason: "Malformed claims."}
+	}
+	return a.principal(claims)
+}
+
+// candidateKeys returns the keys which could have signed the token.
+// When the JWKS is fetched from a URL and the key id is unknown, the JWKS is fetched again in case the keys were rotated.
+// Meanwhile, the other requests are verified by the current keys.
+func (a *JWTAuthenticator) candidateKeys(header jwtHeader) []jwk {
+	a.mu.RLock()
+	keys := matchKeys(a.keys, header)
+	fetchedAt := a.fetchedAt
+	a.mu.RUnlock()
+
+	remote := strings.HasPrefix(a.config.JWKS, "http://") || strings.HasPrefix(a.config.JWKS, "https://")
+	if len(keys) > 0 || !remote || header.Kid == "" || a.now().Sub(fetchedAt) < jwksRefreshInterval {
+		return keys
+	}
+
+	a.mu.Lock()
+	if a.now().Sub(a.fetchedAt) < jwksRefreshInterval {
+		// Refreshed by another request meanwhile.
+		keys = matchKeys(a.keys, header)
+		a.mu.Unlock()
+		return keys
+	}
+	// Claim the refresh, so that the other requests do not fetch the JWKS at the same time.
+	a.fetchedAt = a.now()
+	a.mu.Unlock()
+
+	// The JWKS is fetched without holding the lock, so that a slow identity provider does not block the other requests.
+	fetched, err := loadJWKS(a.config.JWKS)
+	if err != nil {
+		return keys
+	}
+	a.mu.Lock()
+	a.keys = fetched
+	a.mu.Unlock()
+	return matchKeys(fetched, header)
+}
+
+func matchKeys(keys []jwk, header jwtHeader) []jwk {
+	matched := make([]jwk, 0)
+	for _, key := range keys {
+		if (header.Kid == "" || key.id == header.Kid) && key.supports(header.Alg) {
+			matched = append(matched, key)
+		}
+	}
+	return matched
+}
+
+func verify(alg string, key jwk, signed []byte, signature []byte) bool {
+	switch alg {
+	case "RS256":
+		digest := sha256.Sum256(signed)
+		return rsa.VerifyPKCS1v15(key.rsaKey, crypto.SHA256, digest[:], signature) == nil
+	case "ES256":
+		if len(signature) != 64 {
+			return false
+		}
+		digest := sha256.Sum256(signed)
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		return ecdsa.Verify(key.ecKey, digest[:], r, s)
+	case "HS256":
+		mac := hmac.New(sha256.New, key.secret)
+		mac.Write(signed)
+		return hmac.Equal(mac.Sum(nil), signature)
+	}
+	return false
+}
+
+// principal checks the registered claims and maps the claims to the principal.
+func (a *JWTAuthenticator) principal(claims map[string]interface{}) (Principal, error) {
+	now := a.now()
+	skew := a.config.ClockSkew
+
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return Principal{}, &TokenError{Reason: "Expiry is required."}
+	}
+	if now.After(time.Unix(int64(exp), 0).Add(skew)) {
+		return Principal{}, &TokenError{Reason: "Token has expired."}
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(skew).Before(time.Unix(int64(nbf), 0)) {
+		return Principal{}, &TokenError{Reason: "Token is not valid yet."}
+	}
+	if iss, _ := claims["iss"].(string); iss != a.config.Issuer {
+		return Principal{}, &TokenError{Reason: fmt.Sprintf("Issuer '%s' is not trusted.", iss)}
+	}
+	audiences := stringValues(claims["aud"])
+	if aud, ok := claims["aud"].(string); ok {
+		audiences = []string{aud}
+	}
+	if !containsString(audiences, a.config.Audience) {
+		return Principal{}, &TokenError{Reason: fmt.Sprintf("Token is not issued for the audience '%s'.", a.config.Audience)}
+	}
+
+	subject, _ := claims[a.config.SubjectClaim].(string)
+	if subject == "" {
+		return Principal{}, &TokenError{Reason: fmt.Sprintf("Claim '%s' is required.", a.config.SubjectClaim)}
+	}
+	email, _ := claims[a.config.EmailClaim].(string)
+	if verified, ok := claims["email_verified"].(bool); ok && !verified {
+		email = ""
+	}
+	return Principal{ID: "jwt:" + subject, Subject: subject, Email: email, Scopes: a.scopes(claims[a.config.ScopeClaim])}, nil
+}
+
+// scopes maps the values of the scope claim to scopes.
+func (a *JWTAuthenticator) scopes(claim interface{}) []Scope {
+	scopes := make([]Scope, 0)
+	add := func(scope Scope) {
+		for _, existing := range scopes {
+			if existing == scope {
+				return
+			}
+		}
+		scopes = append(scopes, scope)
+	}
+
+	for _, value := range stringValues(claim) {
+		if len(a.config.Scopes) == 0 {
+			if scope, err := ParseScope(value); err == nil {
+				add(scope)
+			}
+			continue
+		}
+		for _, scope := range a.config.Scopes[value] {
+			add(scope)
+		}
+	}
+	return scopes
+}
+
+// stringValues returns the values of a claim which is either a space separated string or an array of strings.
+func stringValues(claim interface{}) []string {
+	switch v := claim.(type) {
+	case string:
+		return strings.Fields(v)
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
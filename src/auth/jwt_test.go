@@ -0,0 +1,347 @@
+package auth
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testKeys is a locally generated key set, so that tokens could be issued without an identity provider.
+type testKeys struct {
+	rsaKey *rsa.PrivateKey
+	ecKey  *ecdsa.PrivateKey
+	secret []byte
+}
+
+func newTestKeys(t *testing.T) testKeys {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %s", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate EC key: %s", err)
+	}
+	return testKeys{rsaKey, ecKey, []byte("a-secret-of-at-least-32-bytes!!!")}
+}
+
+// jwks returns the JWKS of the keys, with the key ids "rsa", "ec" and "hmac".
+func (k testKeys) jwks() []byte {
+	encode := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+	pad := func(i *big.Int) []byte {
+		b := make([]byte, 32)
+		return i.FillBytes(b)
+	}
+	set := map[string]interface{}{"keys": []map[string]string{
+		{"kty": "RSA", "kid": "rsa", "use": "sig", "alg": "RS256", "n": encode(k.rsaKey.N.Bytes()), "e": encode(big.NewInt(int64(k.rsaKey.E)).Bytes())},
+		{"kty": "EC", "kid": "ec", "crv": "P-256", "x": encode(pad(k.ecKey.X)), "y": encode(pad(k.ecKey.Y))},
+		{"kty": "oct", "kid": "hmac", "k": encode(k.secret)},
+		{"kty": "RSA", "kid": "enc", "use": "enc", "n": "AQAB", "e": "AQAB"},
+		{"kty": "OKP", "kid": "okp", "crv": "Ed25519", "x": "AA"},
+	}}
+	data, _ := json.Marshal(set)
+	return data
+}
+
+// sign issues a token with the claims signed by the key of the algorithm.
+func (k testKeys) sign(alg string, kid string, claims map[string]interface{}) string {
+	encode := func(v interface{}) string {
+		data, _ := json.Marshal(v)
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	signed := encode(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"}) + "." + encode(claims)
+	digest := sha256.Sum256([]byte(signed))
+
+	var signature []byte
+	switch alg {
+	case "RS256":
+		signature, _ = rsa.SignPKCS1v15(rand.Reader, k.rsaKey, crypto.SHA256, digest[:])
+	case "ES256":
+		r, s, _ := ecdsa.Sign(rand.Reader, k.ecKey, digest[:])
+		signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	case "HS256":
+		mac := hmac.New(sha256.New, k.secret)
+		mac.Write([]byte(signed))
+		signature = mac.Sum(nil)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+var testNow = time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func testClaims(overrides map[string]interface{}) map[string]interface{} {
+	claims := map[string]interface{}{
+		"iss":   "https://sso.example.com",
+		"aud":   "appstore",
+		"sub":   "alice",
+		"exp":   testNow.Add(time.Hour).Unix(),
+		"scope": "apps:read apps:write",
+	}
+	for name, value := range overrides {
+		if value == nil {
+			delete(claims, name)
+		} else {
+			claims[name] = value
+		}
+	}
+	return claims
+}
+
+func newTestAuthenticator(t *testing.T, keys testKeys, config JWTConfig) *JWTAuthenticator {
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := ioutil.WriteFile(path, keys.jwks(), 0600); err != nil {
+		t.Fatal(err)
+	}
+	config.JWKS = path
+	config.Issuer = "https://sso.example.com"
+	config.Audience = "appstore"
+	a, err := NewJWTAuthenticator(config)
+	if err != nil {
+		t.Fatalf("Should not have error but error '%s' occurred.", err.Error())
+	}
+	a.now = func() time.Time { return testNow }
+	return a
+}
+
+func TestJWTAuthenticator(t *testing.T) {
+	keys := newTestKeys(t)
+	a := newTestAuthenticator(t, keys, JWTConfig{ClockSkew: time.Minute})
+	other := newTestKeys(t)
+
+	var tests = []struct {
+		name           string
+		token          string
+		expectedScopes []Scope
+		expectedErrMsg string
+	}{
+		{"RS256", keys.sign("RS256", "rsa", testClaims(nil)), []Scope{ScopeAppsRead, ScopeAppsWrite}, ""},
+		{"ES256", keys.sign("ES256", "ec", testClaims(nil)), []Scope{ScopeAppsRead, ScopeAppsWrite}, ""},
+		{"HS256", keys.sign("HS256", "hmac", testClaims(nil)), []Scope{ScopeAppsRead, ScopeAppsWrite}, ""},
+		{"Without key id", keys.sign("ES256", "", testClaims(nil)), []Scope{ScopeAppsRead, ScopeAppsWrite}, ""},
+		{"Audience in array", keys.sign("RS256", "rsa", testClaims(map[string]interface{}{"aud": []string{"other", "appstore"}})), []Scope{ScopeAppsRead, ScopeAppsWrite}, ""},
+		{"Scopes in array", keys.sign("RS256", "rsa", testClaims(map[string]interface{}{"scope": []string{"admin", "openid"}})), []Scope{ScopeAdmin}, ""},
+		{"Expired within clock skew", keys.sign("RS256", "rsa", testClaims(map[string]interface{}{"exp": testNow.Add(-30 * time.Second).Unix()})), []Scope{ScopeAppsRead, ScopeAppsWrite}, ""},
+		{"Expired", keys.sign("RS256", "rsa", testClaims(map[string]interface{}{"exp": testNow.Add(-2 * time.Minute).Unix()})), nil, "Invalid token: Token has expired."},
+		{"Without expiry", keys.sign("RS256", "rsa", testClaims(map[string]interface{}{"exp": nil})), nil, "Invalid token: Expiry is required."},
+		{"Not valid yet", keys.sign("RS256", "rsa", testClaims(map[string]interface{}{"nbf": testNow.Add(2 * time.Minute).Unix()})), nil, "Invalid token: Token is not valid yet."},
+		{"Untrusted issuer", keys.sign("RS256", "rsa", testClaims(map[string]interface{}{"iss": "https://evil.com"})), nil, "Invalid token: Issuer 'https://evil.com' is not trusted."},
+		{"Other audience", keys.sign("RS256", "rsa", testClaims(map[string]interface{}{"aud": "other"})), nil, "Invalid token: Token is not issued for the audience 'appstore'."},
+		{"Without subject", keys.sign("RS256", "rsa", testClaims(map[string]interface{}{"sub": nil})), nil, "Invalid token: Claim 'sub' is required."},
+		{"Signed by other key", other.sign("RS256", "rsa", testClaims(nil)), nil, "Invalid token: Bad signature."},
+		{"Tampered claims", strings.Replace(keys.sign("HS256", "hmac", testClaims(nil)), ".", ".e30", 1), nil, "Invalid token: Bad signature."},
+		{"Unknown key id", keys.sign("RS256", "dummy", testClaims(nil)), nil, "Invalid token: No key 'dummy' for the algorithm RS256."},
+		{"Algorithm of other key", keys.sign("HS256", "rsa", testClaims(nil)), nil, "Invalid token: No key 'rsa' for the algorithm HS256."},
+		{"Algorithm none", strings.Join(strings.Split(keys.sign("HS256", "hmac", testClaims(nil)), ".")[:2], ".") + ".", nil, "Invalid token: Bad signature."},
+		{"Unsupported algorithm", "eyJhbGciOiJub25lIn0.e30.", nil, "Invalid token: Algorithm 'none' is not supported. Supported algorithms are: RS256, ES256, HS256"},
+		{"Malformed", "dummy", nil, "Invalid token: Malformed token."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			principal, err := a.Authenticate(tt.token)
+			if tt.expectedErrMsg != "" {
+				if err == nil || err.Error() != tt.expectedErrMsg {
+					t.Errorf("Expected error '%s' but got '%v'", tt.expectedErrMsg, err)
+				}
+				if !errors.Is(err, ErrInvalidCredentials) {
+					t.Errorf("Expected error to match ErrInvalidCredentials")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Should not have error but error '%s' occurred.", err.Error())
+			}
+			if principal.Subject != "alice" || !reflect.DeepEqual(principal.Scopes, tt.expectedScopes) {
+				t.Errorf("Expected alice with scopes %v but got %+v", tt.expectedScopes, principal)
+			}
+		})
+	}
+}
+
+func TestJWTAuthenticator_ClaimMapping(t *testing.T) {
+	keys := newTestKeys(t)
+	a := newTestAuthenticator(t, keys, JWTConfig{
+		SubjectClaim: "email",
+		ScopeClaim:   "groups",
+		Scopes: map[string][]Scope{
+			"appstore-publishers": {ScopeAppsRead, ScopeAppsWrite},
+			"appstore-readers":    {ScopeAppsRead},
+		},
+	})
+
+	token := keys.sign("RS256", "rsa", testClaims(map[string]interface{}{"email": "alice@random.com", "groups": []string{"appstore-readers", "staff", "appstore-publishers"}}))
+	principal, err := a.Authenticate(token)
+	if err != nil {
+		t.Fatalf("Should not have error but error '%s' occurred.", err.Error())
+	}
+	expected := Principal{ID: "jwt:alice@random.com", Subject: "alice@random.com", Email: "alice@random.com", Scopes: []Scope{ScopeAppsRead, ScopeAppsWrite}}
+	if !reflect.DeepEqual(principal, expected) {
+		t.Errorf("Expected %+v but got %+v", expected, principal)
+	}
+
+	// Unverified emails could not identify owners or maintainers.
+	token = keys.sign("RS256", "rsa", testClaims(map[string]interface{}{"email": "bob@random.com", "email_verified": false}))
+	principal, err = a.Authenticate(token)
+	if err != nil || principal.Subject != "bob@random.com" || principal.Email != "" {
+		t.Errorf("Expected bob without email but got %+v %v", principal, err)
+	}
+}
+
+func TestJWTAuthenticator_RemoteJWKS(t *testing.T) {
+	keys := newTestKeys(t)
+	jwks := keys.jwks()
+	fetched := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetched++
+		w.Write(jwks)
+	}))
+	defer ts.Close()
+
+	a, err := NewJWTAuthenticator(JWTConfig{JWKS: ts.URL, Issuer: "https://sso.example.com", Audience: "appstore"})
+	if err != nil {
+		t.Fatalf("Should not have error but error '%s' occurred.", err.Error())
+	}
+	now := testNow
+	a.now = func() time.Time { return now }
+	a.fetchedAt = now
+
+	// The identity provider rotates its keys with new key ids.
+	rotated := newTestKeys(t)
+	jwks = bytes.Replace(rotated.jwks(), []byte(`"kid":"ec"`), []byte(`"kid":"ec-2"`), 1)
+	token := rotated.sign("ES256", "ec-2", testClaims(nil))
+	if _, err := a.Authenticate(token); err == nil {
+		t.Errorf("Expected the JWKS not to be fetched again within the refresh interval")
+	}
+
+	now = now.Add(jwksRefreshInterval)
+	if _, err := a.Authenticate(token); err != nil {
+		t.Errorf("Should not have error but error '%s' occurred.", err.Error())
+	}
+	if fetched != 2 {
+		t.Errorf("Expected the JWKS to be fetched 2 times but got %d", fetched)
+	}
+}
+
+func TestJWTAuthenticator_RemoteJWKS_NotBlocking(t *testing.T) {
+	keys := newTestKeys(t)
+	jwks := keys.jwks()
+	fetching := make(chan struct{}, 1)
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("slow") != "" {
+			fetching <- struct{}{}
+			<-release
+		}
+		w.Write(jwks)
+	}))
+	defer ts.Close()
+
+	a, err := NewJWTAuthenticator(JWTConfig{JWKS: ts.URL, Issuer: "https://sso.example.com", Audience: "appstore"})
+	if err != nil {
+		t.Fatalf("Should not have error but error '%s' occurred.", err.Error())
+	}
+	a.now = func() time.Time { return testNow }
+	a.fetchedAt = testNow.Add(-jwksRefreshInterval)
+	a.config.JWKS = ts.URL + "?slow=true"
+
+	// A token with an unknown key id makes the JWKS fetched again, which is slow.
+	unknown := make(chan error, 1)
+	go func() {
+		_, err := a.Authenticate(keys.sign("ES256", "unknown", testClaims(nil)))
+		unknown <- err
+	}()
+	<-fetching
+
+	// Tokens signed by the known keys are still verified meanwhile.
+	verified := make(chan error, 1)
+	go func() {
+		_, err := a.Authenticate(keys.sign("ES256", "ec", testClaims(nil)))
+		verified <- err
+	}()
+	select {
+	case err := <-verified:
+		if err != nil {
+			t.Errorf("Should not have error but error '%s' occurred.", err.Error())
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("Expected the token to be verified while the JWKS is being fetched")
+	}
+
+	close(release)
+	if err := <-unknown; err == nil {
+		t.Errorf("Expected the token with the unknown key id to be rejected")
+	}
+}
+
+func TestNewJWTAuthenticator_Errors(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, data string) string {
+		path := filepath.Join(dir, name)
+		ioutil.WriteFile(path, []byte(data), 0600)
+		return path
+	}
+	valid := write("valid.json", `{"keys":[{"kty":"oct","k":"c2VjcmV0"}]}`)
+
+	var tests = []struct {
+		name           string
+		config         JWTConfig
+		expectedErrMsg string
+	}{
+		{"Without JWKS", JWTConfig{Issuer: "iss", Audience: "aud"}, "JWKS of the JWT config is required."},
+		{"Without issuer", JWTConfig{JWKS: valid, Audience: "aud"}, "Issuer of the JWT config is required."},
+		{"Without audience", JWTConfig{JWKS: valid, Issuer: "iss"}, "Audience of the JWT config is required."},
+		{"Unknown scope", JWTConfig{JWKS: valid, Issuer: "iss", Audience: "aud", Scopes: map[string][]Scope{"readers": {"apps:list"}}},
+			"Bad scopes of 'readers' in the JWT config: Unknown scope 'apps:list'. Available scopes are: apps:read, apps:write, admin"},
+		{"No signing keys", JWTConfig{JWKS: write("enc.json", `{"keys":[{"kty":"oct","use":"enc","k":"c2VjcmV0"}]}`), Issuer: "iss", Audience: "aud"},
+			"JWKS has no keys for verifying signatures."},
+		{"Unsupported curve", JWTConfig{JWKS: write("curve.json", `{"keys":[{"kty":"EC","crv":"P-384","x":"AA","y":"AA"}]}`), Issuer: "iss", Audience: "aud"},
+			"Failed to parse key 0 of JWKS: Curve 'P-384' is not supported."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewJWTAuthenticator(tt.config)
+			if err == nil || err.Error() != tt.expectedErrMsg {
+				t.Errorf("Expected error '%s' but got '%v'", tt.expectedErrMsg, err)
+			}
+		})
+	}
+}
+
+func TestChain(t *testing.T) {
+	keys := newTestKeys(t)
+	jwt := newTestAuthenticator(t, keys, JWTConfig{})
+	ks := NewKeyStore()
+	apiKey, _, _ := ks.Create("ci", "", []Scope{ScopeAppsRead})
+	chain := Chain{ks, jwt}
+
+	if principal, err := chain.Authenticate(apiKey); err != nil || principal.Subject != "ci" {
+		t.Errorf("Expected the API key to be authenticated but got %+v %v", principal, err)
+	}
+	if principal, err := chain.Authenticate(keys.sign("HS256", "hmac", testClaims(nil))); err != nil || principal.Subject != "alice" {
+		t.Errorf("Expected the JWT to be authenticated but got %+v %v", principal, err)
+	}
+	if _, err := chain.Authenticate(keys.sign("HS256", "hmac", testClaims(map[string]interface{}{"aud": "other"}))); err == nil || err.Error() != "Invalid token: Token is not issued for the audience 'appstore'." {
+		t.Errorf("Expected the error of the JWT authenticator but got '%v'", err)
+	}
+	if _, err := chain.Authenticate("dummy"); err == nil || err.Error() != "Invalid API key: Malformed key." {
+		t.Errorf("Expected the error of the first authenticator but got '%v'", err)
+	}
+}
@@ -0,0 +1,280 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// apiKeyPrefix is the prefix of API keys, which makes them easy to recognize, e.g. by secret scanners.
+const apiKeyPrefix = "ask"
+
+// APIKey is the information of an API key. The key itself is only known by the client,
+// the store only keeps its SHA-256 hash. Since keys are long random texts, a fast hash is enough.
+type APIKey struct {
+	ID        string    `json:"id" yaml:"id"`
+	Name      string    `json:"name" yaml:"name"`
+	Email     string    `json:"email,omitempty" yaml:"email,omitempty"`
+	Scopes    []Scope   `json:"scopes" yaml:"scopes"`
+	CreatedAt time.Time `json:"createdAt" yaml:"createdAt"`
+}
+
+// storedKey is the form of an API key persisted on disk.
+type storedKey struct {
+	APIKey
+	Hash string `json:"hash"`
+}
+
+// InvalidKeyError is returned when an API key is malformed, unknown or revoked.
+type InvalidKeyError struct {
+	Reason string
+	// unsupported is set when the token is not an API key at all.
+	unsupported bool
+}
+
+func (e *InvalidKeyError) Error() string {
+	return fmt.Sprintf("Invalid API key: %s", e.Reason)
+}
+
+// Is makes errors.Is(err, ErrInvalidCredentials) report true for an InvalidKeyError,
+// as well as errors.Is(err, ErrUnsupportedToken) when the token is not an API key.
+func (e *InvalidKeyError) Is(target error) bool {
+	return target == ErrInvalidCredentials || (e.unsupported && target == ErrUnsupportedToken)
+}
+
+// KeyStore manages API keys and authenticates clients by them. It is safe for concurrent use.
+//
+// An API key looks like "ask_<id>_<secret>": the id is used to look up the key, while the whole key is hashed.
+type KeyStore struct {
+	mu   sync.RWMutex
+	keys map[string]storedKey
+	// stamp identifies the version of the file last loaded or saved, see ReloadFileIfChanged.
+	stamp fileStamp
+}
+
+// NewKeyStore creates an empty KeyStore.
+func NewKeyStore() *KeyStore {
+	return &KeyStore{keys: make(map[string]storedKey)}
+}
+
+// Create generates a new API key with the scopes. The email is optional, see Principal.
+// It returns the key, which could not be retrieved again, and its information.
+func (ks *KeyStore) Create(name string, email string, scopes []Scope) (string, APIKey, error) {
+	if strings.TrimSpace(name) == "" {
+		return "", APIKey{}, errors.New("Name of the API key is required.")
+	}
+	if len(scopes) == 0 {
+		return "", APIKey{}, errors.New("At least one scope is required.")
+	}
+
+	id, err := randomHex(8)
+	if err != nil {
+		return "", APIKey{}, err
+	}
+	secret, err := randomHex(32)
+	if err != nil {
+		return "", APIKey{}, err
+	}
+
+	key := fmt.Sprintf("%s_%s_%s", apiKeyPrefix, id, secret)
+	info := APIKey{ID: id, Name: name, Email: email, Scopes: scopes, CreatedAt: time.Now().UTC()}
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.keys[id] = storedKey{info, hashKey(key)}
+	return key, info, nil
+}
+
+// List returns the information of all the API keys, ordered by creation time.
+func (ks *KeyStore) List() []APIKey {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	result := make([]APIKey, 0, len(ks.keys))
+	for _, key := range ks.keys {
+		result = append(result, key.APIKey)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.Before(result[j].CreatedAt)
+	})
+	return result
+}
+
+// Revoke deletes the API key with the id, so that it could not be used any more.
+func (ks *KeyStore) Revoke(id string) error {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	if _, ok := ks.keys[id]; !ok {
+		return fmt.Errorf("API key '%s' does not exist.", id)
+	}
+	delete(ks.keys, id)
+	return nil
+}
+
+// Authenticate returns the principal of the API key, whose subject is the name of the key.
+// It returns *InvalidKeyError if the key is malformed, unknown or revoked.
+func (ks *KeyStore) Authenticate(token string) (Principal, error) {
+	parts := strings.Split(token, "_")
+	if len(parts) != 3 || parts[0] != apiKeyPrefix {
+		return Principal{}, &InvalidKeyError{Reason: "Malformed key.", unsupported: true}
+	}
+
+	ks.mu.RLock()
+	stored, ok := ks.keys[parts[1]]
+	ks.mu.RUnlock()
+	if !ok || subtle.ConstantTimeCompare([]byte(stored.Hash), []byte(hashKey(token))) != 1 {
+		return Principal{}, &InvalidKeyError{Reason: "Unknown or revoked key."}
+	}
+	return Principal{ID: "key:" + stored.ID, Subject: stored.Name, Email: stored.Email, Scopes: stored.Scopes}, nil
+}
+
+// Save writes the API keys into w in JSON format. Only the hashes of the keys are written.
+func (ks *KeyStore) Save(w io.Writer) error {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	keys := make([]storedKey, 0, len(ks.keys))
+	for _, key := range ks.keys {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+	})
+	return json.NewEncoder(w).Encode(struct {
+		Keys []storedKey `json:"keys"`
+	}{keys})
+}
+
+// Load replaces the API keys by the ones read from r, which is generated by Save.
+func (ks *KeyStore) Load(r io.Reader) error {
+	var data struct {
+		Keys []storedKey `json:"keys"`
+	}
+	if err := json.NewDecoder(r).Decode(&data); err != nil {
+		return fmt.Errorf("Failed to load API keys: %w", err)
+	}
+
+	keys := make(map[string]storedKey, len(data.Keys))
+	for _, key := range data.Keys {
+		keys[key.ID] = key
+	}
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.keys = keys
+	return nil
+}
+
+// SaveFile saves the API keys into the file at the given path, which is only readable by the owner.
+// The file is replaced atomically so that a crash during saving will not corrupt the previous keys.
+func (ks *KeyStore) SaveFile(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("Failed to save API keys: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := ks.Save(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("Failed to save API keys: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	stamp, err := statFile(path)
+	if err != nil {
+		return fmt.Errorf("Failed to save API keys: %w", err)
+	}
+	ks.setStamp(stamp)
+	return nil
+}
+
+// LoadFile loads the API keys from the file at the given path.
+// A non-existing file is treated as no keys.
+func (ks *KeyStore) LoadFile(path string) error {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		ks.setStamp(fileStamp{})
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("Failed to load API keys: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("Failed to load API keys: %w", err)
+	}
+	if err := ks.Load(f); err != nil {
+		return err
+	}
+	ks.setStamp(fileStamp{info.ModTime(), info.Size()})
+	return nil
+}
+
+// ReloadFileIfChanged loads the API keys from the file at the given path again when the file has changed
+// since it was last loaded or saved by LoadFile or SaveFile, e.g. when a key has been revoked by the keys command.
+// Files are compared by their modification times and sizes. It tells whether the keys have been reloaded.
+func (ks *KeyStore) ReloadFileIfChanged(path string) (bool, error) {
+	stamp, err := statFile(path)
+	if err != nil {
+		return false, fmt.Errorf("Failed to reload API keys: %w", err)
+	}
+	ks.mu.RLock()
+	changed := !stamp.equal(ks.stamp)
+	ks.mu.RUnlock()
+	if !changed {
+		return false, nil
+	}
+	return true, ks.LoadFile(path)
+}
+
+func (ks *KeyStore) setStamp(stamp fileStamp) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.stamp = stamp
+}
+
+// fileStamp identifies the version of a file. The zero value stands for a non-existing file.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func (a fileStamp) equal(b fileStamp) bool {
+	return a.modTime.Equal(b.modTime) && a.size == b.size
+}
+
+// statFile returns the stamp of the file at the given path, or the zero value if it does not exist.
+func statFile(path string) (fileStamp, error) {
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return fileStamp{}, nil
+	} else if err != nil {
+		return fileStamp{}, err
+	}
+	return fileStamp{info.ModTime(), info.Size()}, nil
+}
+
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("Failed to generate API key: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
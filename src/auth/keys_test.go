@@ -0,0 +1,132 @@
+package auth
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestKeyStore(t *testing.T) {
+	ks := NewKeyStore()
+	key, info, err := ks.Create("ci", "", []Scope{ScopeAppsRead, ScopeAppsWrite})
+	if err != nil {
+		t.Fatalf("Should not have error but error '%s' occurred.", err.Error())
+	}
+	if !strings.HasPrefix(key, "ask_"+info.ID+"_") {
+		t.Errorf("Unexpected format of key '%s'", key)
+	}
+
+	principal, err := ks.Authenticate(key)
+	if err != nil || principal.ID != "key:"+info.ID || principal.Subject != "ci" || !reflect.DeepEqual(principal.Scopes, info.Scopes) {
+		t.Errorf("Expected the key to be authenticated as 'ci' but got %+v %v", principal, err)
+	}
+
+	var tests = []struct {
+		name  string
+		token string
+	}{
+		{"Malformed", "dummy"},
+		{"Unknown id", "ask_0000000000000000_" + strings.Repeat("0", 64)},
+		{"Wrong secret", key[:len(key)-1] + "x"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ks.Authenticate(tt.token); !errors.Is(err, ErrInvalidCredentials) {
+				t.Errorf("Expected invalid credentials but got '%v'", err)
+			}
+		})
+	}
+
+	// Only the hashes are persisted.
+	var buf bytes.Buffer
+	if err := ks.Save(&buf); err != nil {
+		t.Fatalf("Should not have error but error '%s' occurred.", err.Error())
+	}
+	if strings.Contains(buf.String(), key) || !strings.Contains(buf.String(), hashKey(key)) {
+		t.Errorf("Expected only the hash of the key to be saved but got '%s'", buf.String())
+	}
+
+	path := filepath.Join(t.TempDir(), "keys.json")
+	if err := ks.SaveFile(path); err != nil {
+		t.Fatalf("Should not have error but error '%s' occurred.", err.Error())
+	}
+	loaded := NewKeyStore()
+	if err := loaded.LoadFile(path); err != nil {
+		t.Fatalf("Should not have error but error '%s' occurred.", err.Error())
+	}
+	if _, err := loaded.Authenticate(key); err != nil {
+		t.Errorf("Expected the key to be authenticated after loading but got '%s'", err.Error())
+	}
+
+	if err := loaded.Revoke(info.ID); err != nil {
+		t.Fatalf("Should not have error but error '%s' occurred.", err.Error())
+	}
+	if _, err := loaded.Authenticate(key); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Expected revoked key to be rejected but got '%v'", err)
+	}
+	if err := loaded.Revoke(info.ID); err == nil || err.Error() != "API key '"+info.ID+"' does not exist." {
+		t.Errorf("Expected error revoking a missing key but got '%v'", err)
+	}
+
+	// The revocation saved by another store, e.g. the keys command, takes effect once the file is reloaded.
+	if reloaded, err := ks.ReloadFileIfChanged(path); reloaded || err != nil {
+		t.Errorf("Expected the file saved by the store not to be reloaded but got %v with error '%v'", reloaded, err)
+	}
+	if err := loaded.SaveFile(path); err != nil {
+		t.Fatalf("Should not have error but error '%s' occurred.", err.Error())
+	}
+	if reloaded, err := ks.ReloadFileIfChanged(path); !reloaded || err != nil {
+		t.Errorf("Expected the changed file to be reloaded but got %v with error '%v'", reloaded, err)
+	}
+	if _, err := ks.Authenticate(key); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Expected the key revoked in the file to be rejected but got '%v'", err)
+	}
+}
+
+func TestPrincipalHas(t *testing.T) {
+	reader := Principal{Subject: "reader", Scopes: []Scope{ScopeAppsRead}}
+	admin := Principal{Subject: "admin", Scopes: []Scope{ScopeAdmin}}
+
+	if !reader.Has(ScopeAppsRead) || reader.Has(ScopeAppsWrite) || reader.Has(ScopeAdmin) {
+		t.Errorf("Expected reader to only have '%s'", ScopeAppsRead)
+	}
+	if !admin.Has(ScopeAppsRead) || !admin.Has(ScopeAppsWrite) || !admin.Has(ScopeAdmin) {
+		t.Errorf("Expected admin to have all the scopes")
+	}
+
+	err := reader.Authorize(ScopeAppsWrite)
+	if !errors.Is(err, ErrInsufficientScope) || err.Error() != "'reader' is not granted the scope 'apps:write'." {
+		t.Errorf("Expected insufficient scope but got '%v'", err)
+	}
+}
+
+func TestParseScopes(t *testing.T) {
+	var tests = []struct {
+		text           string
+		expected       []Scope
+		expectedErrMsg string
+	}{
+		{"apps:read", []Scope{ScopeAppsRead}, ""},
+		{"apps:read, apps:write", []Scope{ScopeAppsRead, ScopeAppsWrite}, ""},
+		{"", nil, "At least one scope is required."},
+		{"apps:delete", nil, "Unknown scope 'apps:delete'. Available scopes are: apps:read, apps:write, admin"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			scopes, err := ParseScopes(tt.text)
+			if tt.expectedErrMsg != "" {
+				if err == nil || err.Error() != tt.expectedErrMsg {
+					t.Errorf("Expected to have error '%s' but got '%v'", tt.expectedErrMsg, err)
+				}
+				return
+			}
+			if err != nil || !reflect.DeepEqual(scopes, tt.expected) {
+				t.Errorf("Expected %v but got %v %v", tt.expected, scopes, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zzn2/demo/appstore/auth"
+)
+
+// authenticator authenticates the clients of the APIs. Authentication is disabled when nil.
+var authenticator auth.Authenticator
+
+// apiKeys manages the API keys, which are persisted in apiKeysFile.
+// It is nil when API key authentication is disabled.
+var apiKeys *auth.KeyStore
+
+// apiKeysFile is the path of the file where the hashes of the API keys are persisted.
+var apiKeysFile string
+
+// anonymousRead permits requests without credentials to read apps when authentication is enabled.
+var anonymousRead bool
+
+// principalKey is the key in gin.Context to keep the authenticated principal.
+const principalKey = "principal"
+
+// authRealm is the realm reported in the WWW-Authenticate header.
+const authRealm = "appstore"
+
+// missingCredentialsError is returned when an operation requires credentials but none are presented.
+type missingCredentialsError struct {
+	scope auth.Scope
+}
+
+func (e missingCredentialsError) Error() string {
+	return fmt.Sprintf("Credentials with the scope '%s' are required. Present an API key or a JWT in the Authorization header as a bearer token.", e.scope)
+}
+
+// Is makes errors.Is(err, auth.ErrInvalidCredentials) report true for a missingCredentialsError.
+func (e missingCredentialsError) Is(target error) bool {
+	return target == auth.ErrInvalidCredentials
+}
+
+// authenticate is a middleware which authenticates the credentials in the request, if any, by authenticator.
+// The credentials are either a bearer token (an API key or a JWT) in the Authorization header, or an API key in the X-API-Key header.
+// It responds 401 Unauthorized when the credentials are not accepted.
+// Requests without credentials are passed on, so that authorize decides whether anonymous access is permitted.
+func authenticate(c *gin.Context) {
+	if authenticator == nil {
+		c.Next()
+		return
+	}
+
+	token := credentials(c)
+	if token == "" {
+		c.Next()
+		return
+	}
+
+	principal, err := authenticator.Authenticate(token)
+	if err != nil {
+		respondUnauthorized(c, err)
+		c.Abort()
+		return
+	}
+	c.Set(principalKey, principal)
+	c.Next()
+}
+
+// authorize returns a middleware which requires the authenticated principal to be granted the scope.
+// It responds 401 Unauthorized when no credentials are presented, unless the scope is apps:read and anonymousRead is set,
+// or 403 Forbidden when the principal is not granted the scope.
+func authorize(scope auth.Scope) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if authenticator == nil {
+			c.Next()
+			return
+		}
+
+		value, ok := c.Get(principalKey)
+		if !ok {
+			if anonymousRead && scope == auth.ScopeAppsRead {
+				c.Next()
+				return
+			}
+			respondUnauthorized(c, missingCredentialsError{scope})
+			c.Abort()
+			return
+		}
+
+		if err := value.(auth.Principal).Authorize(scope); err != nil {
+			c.Header("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s", error="insufficient_scope", scope="%s"`, authRealm, scope))
+			respond(c, http.StatusForbidden, responseBodyForError(err))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// credentials returns the token in the Authorization header with the Bearer scheme, or in the X-API-Key header.
+func credentials(c *gin.Context) string {
+	header := c.GetHeader("Authorization")
+	if len(header) > len("Bearer ") && strings.EqualFold(header[:len("Bearer ")], "Bearer ") {
+		return strings.TrimSpace(header[len("Bearer "):])
+	}
+	return strings.TrimSpace(c.GetHeader("X-API-Key"))
+}
+
+func respondUnauthorized(c *gin.Context, err error) {
+	c.Header("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s"`, authRealm))
+	respond(c, http.StatusUnauthorized, responseBodyForError(err))
+}
+
+// apiKeyRequest is the request body to create an API key.
+type apiKeyRequest struct {
+	Name string `binding:"required"`
+	// Email identifies the client as an owner or a maintainer of apps.
+	Email  string   `binding:"omitempty,email"`
+	Scopes []string `binding:"required"`
+}
+
+// createdAPIKey is the response of a created API key. The key is only responded once.
+type createdAPIKey struct {
+	auth.APIKey `yaml:",inline"`
+	Key         string `json:"key" yaml:"key"`
+}
+
+func newAPIKey(c *gin.Context) {
+	if !requireAPIKeys(c) {
+		return
+	}
+
+	var req apiKeyRequest
+	if err := bindBody(c, &req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	scopes, err := auth.ParseScopes(strings.Join(req.Scopes, ","))
+	if err != nil {
+		respond(c, http.StatusBadRequest, responseBodyForError(err))
+		return
+	}
+
+	key, info, err := apiKeys.Create(req.Name, req.Email, scopes)
+	if err != nil {
+		respond(c, http.StatusBadRequest, responseBodyForError(err))
+		return
+	}
+	persistAPIKeys()
+	respond(c, http.StatusCreated, createdAPIKey{info, key})
+}
+
+func listAPIKeys(c *gin.Context) {
+	if !requireAPIKeys(c) {
+		return
+	}
+	respond(c, http.StatusOK, apiKeys.List())
+}
+
+func revokeAPIKey(c *gin.Context) {
+	if !requireAPIKeys(c) {
+		return
+	}
+	if err := apiKeys.Revoke(c.Param("id")); err != nil {
+		respond(c, http.StatusNotFound, responseBodyForError(err))
+		return
+	}
+	persistAPIKeys()
+	c.Status(http.StatusNoContent)
+}
+
+// requireAPIKeys responds 404 when API key authentication is disabled.
+func requireAPIKeys(c *gin.Context) bool {
+	if apiKeys == nil {
+		respond(c, http.StatusNotFound, responseBodyForErrorMessage("API keys are not enabled. Set APPSTORE_API_KEYS_FILE to enable them."))
+		return false
+	}
+	return true
+}
+
+// persistAPIKeys saves the API keys into apiKeysFile, if set.
+func persistAPIKeys() {
+	if apiKeysFile == "" {
+		return
+	}
+	if err := apiKeys.SaveFile(apiKeysFile); err != nil {
+		log.Printf("Failed to persist API keys: %s", err)
+	}
+}
+
+// setupAuthFromEnv enables authentication by the environment variables:
+//
+//    APPSTORE_API_KEYS_FILE   -> accept API keys, which are loaded from and saved into the file
+//    APPSTORE_JWT_CONFIG      -> accept JWTs verified by the config file, see auth.JWTConfig
+//    APPSTORE_ANONYMOUS_READ  -> "true" to permit reading apps without credentials
+//
+// Authentication is disabled when neither API keys nor JWTs are accepted.
+func setupAuthFromEnv() error {
+	authenticators := make(auth.Chain, 0)
+
+	apiKeysFile = os.Getenv("APPSTORE_API_KEYS_FILE")
+	if apiKeysFile != "" {
+		apiKeys = auth.NewKeyStore()
+		if err := apiKeys.LoadFile(apiKeysFile); err != nil {
+			return err
+		}
+		authenticators = append(authenticators, apiKeys)
+	}
+
+	if path := os.Getenv("APPSTORE_JWT_CONFIG"); path != "" {
+		config, err := auth.LoadJWTConfig(path)
+		if err != nil {
+			return err
+		}
+		jwt, err := auth.NewJWTAuthenticator(config)
+		if err != nil {
+			return err
+		}
+		authenticators = append(authenticators, jwt)
+	}
+
+	switch len(authenticators) {
+	case 0:
+		return nil
+	case 1:
+		authenticator = authenticators[0]
+	default:
+		authenticator = authenticators
+	}
+
+	if text := os.Getenv("APPSTORE_ANONYMOUS_READ"); text != "" {
+		value, err := strconv.ParseBool(text)
+		if err != nil {
+			return fmt.Errorf("Bad format of APPSTORE_ANONYMOUS_READ '%s'", text)
+		}
+		anonymousRead = value
+	}
+	return nil
+}
+
+// errAPIKeysDisabled is returned by the keys command when APPSTORE_API_KEYS_FILE is not set.
+var errAPIKeysDisabled = errors.New("APPSTORE_API_KEYS_FILE must be set to manage API keys")
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zzn2/demo/appstore/auth"
+)
+
+func TestAPIKeyAuthentication(t *testing.T) {
+	ts := newTestServer(t)
+	anonymousRead = false
+
+	adminKey, _, _ := apiKeys.Create("admin", "", []auth.Scope{auth.ScopeAdmin})
+	readerKey, _, _ := apiKeys.Create("reader", "", []auth.Scope{auth.ScopeAppsRead})
+	writerKey, _, _ := apiKeys.Create("writer", "", []auth.Scope{auth.ScopeAppsRead, auth.ScopeAppsWrite})
+
+	send := func(method string, path string, header string, key string, data string) (int, http.Header, string) {
+		req, _ := http.NewRequest(method, ts.URL+path, strings.NewReader(data))
+		if key != "" {
+			req.Header.Set(header, key)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Error occurred during %s %s, detail: %e", method, path, err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		return resp.StatusCode, resp.Header, string(body)
+	}
+
+	var tests = []struct {
+		name                    string
+		method                  string
+		path                    string
+		header                  string
+		key                     string
+		data                    string
+		anonymousRead           bool
+		expectedCode            int
+		expectedWWWAuthenticate string
+		expectedBody            string
+	}{
+		{
+			"Without credentials",
+			"GET", "/v1/apps", "", "", "", false,
+			http.StatusUnauthorized,
+			`Bearer realm="appstore"`,
+			`{"detail":"Credentials with the scope 'apps:read' are required. Present an API key or a JWT in the Authorization header as a bearer token.","status":401,"title":"Invalid credentials","type":"/problems/invalid-credentials"}`,
+		},
+		{
+			"Malformed key",
+			"GET", "/v1/apps", "Authorization", "Bearer dummy", "", false,
+			http.StatusUnauthorized,
+			`Bearer realm="appstore"`,
+			`{"detail":"Invalid API key: Malformed key.","status":401,"title":"Invalid credentials","type":"/problems/invalid-credentials"}`,
+		},
+		{
+			"Unknown key",
+			"GET", "/v1/apps", "X-API-Key", "ask_0000000000000000_0000", "", false,
+			http.StatusUnauthorized,
+			`Bearer realm="appstore"`,
+			`{"detail":"Invalid API key: Unknown or revoked key.","status":401,"title":"Invalid credentials","type":"/problems/invalid-credentials"}`,
+		},
+		{
+			"Read with bearer token",
+			"GET", "/v1/apps", "Authorization", "Bearer " + readerKey, "", false,
+			http.StatusOK,
+			"",
+			`[]`,
+		},
+		{
+			"Read with X-API-Key",
+			"GET", "/v1/apps", "X-API-Key", readerKey, "", false,
+			http.StatusOK,
+			"",
+			`[]`,
+		},
+		{
+			"Anonymous read",
+			"GET", "/v1/apps", "", "", "", true,
+			http.StatusOK,
+			"",
+			`[]`,
+		},
+		{
+			"Anonymous write",
+			"POST", "/v1/searches", "", "", "name: s1", true,
+			http.StatusUnauthorized,
+			`Bearer realm="appstore"`,
+			`{"detail":"Credentials with the scope 'apps:write' are required. Present an API key or a JWT in the Authorization header as a bearer token.","status":401,"title":"Invalid credentials","type":"/problems/invalid-credentials"}`,
+		},
+		{
+			"Write without scope",
+			"POST", "/v1/searches", "Authorization", "Bearer " + readerKey, "name: s1", false,
+			http.StatusForbidden,
+			`Bearer realm="appstore", error="insufficient_scope", scope="apps:write"`,
+			`{"detail":"'reader' is not granted the scope 'apps:write'.","status":403,"title":"Insufficient scope","type":"/problems/insufficient-scope"}`,
+		},
+		{
+			"Write with scope",
+			"POST", "/v1/searches", "Authorization", "Bearer " + writerKey, "name: s1", false,
+			http.StatusCreated,
+			"",
+			`{"Name":"s1","Filter":{"rules":[]},"Sort":null,"Fields":null}`,
+		},
+		{
+			"Admin without scope",
+			"GET", "/v1/_snapshots", "Authorization", "Bearer " + writerKey, "", false,
+			http.StatusForbidden,
+			`Bearer realm="appstore", error="insufficient_scope", scope="admin"`,
+			`{"detail":"'writer' is not granted the scope 'admin'.","status":403,"title":"Insufficient scope","type":"/problems/insufficient-scope"}`,
+		},
+		{
+			"Admin implies other scopes",
+			"GET", "/v1/searches", "Authorization", "Bearer " + adminKey, "", false,
+			http.StatusOK,
+			"",
+			`[{"Name":"s1","Filter":{"rules":[]},"Sort":null,"Fields":null}]`,
+		},
+		{
+			"Export requires admin",
+			"GET", "/v1/_export", "Authorization", "Bearer " + readerKey, "", false,
+			http.StatusForbidden,
+			`Bearer realm="appstore", error="insufficient_scope", scope="admin"`,
+			`{"detail":"'reader' is not granted the scope 'admin'.","status":403,"title":"Insufficient scope","type":"/problems/insufficient-scope"}`,
+		},
+		{
+			"Create key with unknown scope",
+			"POST", "/v1/_keys", "Authorization", "Bearer " + adminKey, "name: ci\nscopes: [apps:delete]", false,
+			http.StatusBadRequest,
+			"",
+			`{"detail":"Unknown scope 'apps:delete'. Available scopes are: apps:read, apps:write, admin","status":400,"title":"Bad Request","type":"about:blank"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			anonymousRead = tt.anonymousRead
+			code, header, body := send(tt.method, tt.path, tt.header, tt.key, tt.data)
+			if code != tt.expectedCode {
+				t.Errorf("Expected status code %d but got %d", tt.expectedCode, code)
+			}
+			if header.Get("WWW-Authenticate") != tt.expectedWWWAuthenticate {
+				t.Errorf("Expected WWW-Authenticate '%s' but got '%s'", tt.expectedWWWAuthenticate, header.Get("WWW-Authenticate"))
+			}
+			if strings.TrimSpace(body) != tt.expectedBody {
+				t.Errorf("Expected body '%s' but got '%s'", tt.expectedBody, body)
+			}
+		})
+	}
+	anonymousRead = false
+
+	// Create a key by the admin API, use and revoke it.
+	code, _, body := send("POST", "/v1/_keys", "Authorization", "Bearer "+adminKey, "name: ci\nscopes: [apps:read]")
+	if code != http.StatusCreated {
+		t.Fatalf("Expected status code %d but got %d: %s", http.StatusCreated, code, body)
+	}
+	var created struct {
+		ID     string
+		Name   string
+		Scopes []string
+		Key    string
+	}
+	json.Unmarshal([]byte(body), &created)
+	if created.Name != "ci" || len(created.Scopes) != 1 || !strings.HasPrefix(created.Key, "ask_"+created.ID+"_") {
+		t.Errorf("Unexpected created key '%s'", body)
+	}
+
+	if code, _, _ := send("GET", "/v1/apps", "Authorization", "Bearer "+created.Key, ""); code != http.StatusOK {
+		t.Errorf("Expected status code %d with the created key but got %d", http.StatusOK, code)
+	}
+	if _, _, body := send("GET", "/v1/_keys", "Authorization", "Bearer "+adminKey, ""); strings.Contains(body, created.Key) || !strings.Contains(body, created.ID) {
+		t.Errorf("Expected the created key to be listed without its secret but got '%s'", body)
+	}
+	if code, _, _ := send("DELETE", "/v1/_keys/"+created.ID, "Authorization", "Bearer "+adminKey, ""); code != http.StatusNoContent {
+		t.Errorf("Expected status code %d when revoking but got %d", http.StatusNoContent, code)
+	}
+	if code, _, _ := send("GET", "/v1/apps", "Authorization", "Bearer "+created.Key, ""); code != http.StatusUnauthorized {
+		t.Errorf("Expected status code %d with the revoked key but got %d", http.StatusUnauthorized, code)
+	}
+	if code, _, _ := send("DELETE", "/v1/_keys/"+created.ID, "Authorization", "Bearer "+adminKey, ""); code != http.StatusNotFound {
+		t.Errorf("Expected status code %d when revoking again but got %d", http.StatusNotFound, code)
+	}
+}
+
+func TestKeysCommand(t *testing.T) {
+	defer func(keys *auth.KeyStore, file string) { apiKeys, apiKeysFile = keys, file }(apiKeys, apiKeysFile)
+	apiKeysFile = filepath.Join(t.TempDir(), "keys.json")
+	apiKeys = auth.NewKeyStore()
+
+	var out bytes.Buffer
+	if err := runCommand([]string{"keys", "create", "-name", "ci", "-scopes", "apps:read,apps:write"}, &out); err != nil {
+		t.Fatalf("Should not have error but error '%s' occurred.", err.Error())
+	}
+	var created struct {
+		ID  string
+		Key string
+	}
+	json.Unmarshal(out.Bytes(), &created)
+
+	// The key is persisted, so that it could be used by the server.
+	loaded := auth.NewKeyStore()
+	if err := loaded.LoadFile(apiKeysFile); err != nil {
+		t.Fatalf("Should not have error but error '%s' occurred.", err.Error())
+	}
+	principal, err := loaded.Authenticate(created.Key)
+	if err != nil {
+		t.Fatalf("Should not have error but error '%s' occurred.", err.Error())
+	}
+	if principal.Subject != "ci" || !principal.Has(auth.ScopeAppsWrite) {
+		t.Errorf("Unexpected principal %v", principal)
+	}
+
+	out.Reset()
+	if err := runCommand([]string{"keys", "list"}, &out); err != nil {
+		t.Fatalf("Should not have error but error '%s' occurred.", err.Error())
+	}
+	if !strings.Contains(out.String(), created.ID) || strings.Contains(out.String(), created.Key) {
+		t.Errorf("Expected the key to be listed without its secret but got '%s'", out.String())
+	}
+
+	if err := runCommand([]string{"keys", "revoke", created.ID}, &out); err != nil {
+		t.Fatalf("Should not have error but error '%s' occurred.", err.Error())
+	}
+	loaded.LoadFile(apiKeysFile)
+	if _, err := loaded.Authenticate(created.Key); err == nil {
+		t.Errorf("Expected the revoked key to be rejected")
+	}
+
+	if err := runCommand([]string{"keys", "create", "-name", "ci", "-scopes", "dummy"}, &out); err == nil || err.Error() != "Unknown scope 'dummy'. Available scopes are: apps:read, apps:write, admin" {
+		t.Errorf("Expected unknown scope error but got '%v'", err)
+	}
+}
+
+func TestJWTAuthentication(t *testing.T) {
+	ts := newTestServer(t)
+
+	// A locally configured JWKS with a HS256 key, mapping groups to scopes.
+	dir := t.TempDir()
+	secret := []byte("a-secret-of-at-least-32-bytes!!!")
+	jwks := fmt.Sprintf(`{"keys":[{"kty":"oct","kid":"k1","k":"%s"}]}`, base64.RawURLEncoding.EncodeToString(secret))
+	ioutil.WriteFile(filepath.Join(dir, "jwks.json"), []byte(jwks), 0600)
+	ioutil.WriteFile(filepath.Join(dir, "jwt.yaml"), []byte(`
+jwks: `+filepath.Join(dir, "jwks.json")+`
+issuer: https://sso.example.com
+audience: appstore
+clockSkew: 30s
+scopeClaim: groups
+scopes:
+  appstore-publishers: [apps:read, apps:write]
+`), 0600)
+	t.Setenv("APPSTORE_API_KEYS_FILE", filepath.Join(dir, "keys.json"))
+	t.Setenv("APPSTORE_JWT_CONFIG", filepath.Join(dir, "jwt.yaml"))
+	t.Setenv("APPSTORE_ANONYMOUS_READ", "false")
+	if err := setupAuthFromEnv(); err != nil {
+		t.Fatalf("Should not have error but error '%s' occurred.", err.Error())
+	}
+	apiKey, _, _ := apiKeys.Create("ci", "", []auth.Scope{auth.ScopeAppsRead})
+
+	sign := func(claims string) string {
+		encode := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+		signed := encode(`{"alg":"HS256","kid":"k1"}`) + "." + encode(claims)
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(signed))
+		return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+	}
+	exp := time.Now().Add(time.Hour).Unix()
+
+	var tests = []struct {
+		name         string
+		token        string
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			"JWT with scope",
+			sign(fmt.Sprintf(`{"iss":"https://sso.example.com","aud":"appstore","sub":"alice","exp":%d,"groups":["appstore-publishers"]}`, exp)),
+			http.StatusCreated,
+			`{"Name":"s1","Filter":{"rules":[]},"Sort":null,"Fields":null}`,
+		},
+		{
+			"JWT without scope",
+			sign(fmt.Sprintf(`{"iss":"https://sso.example.com","aud":"appstore","sub":"bob","exp":%d,"groups":["staff"]}`, exp)),
+			http.StatusForbidden,
+			`{"detail":"'bob' is not granted the scope 'apps:write'.","status":403,"title":"Insufficient scope","type":"/problems/insufficient-scope"}`,
+		},
+		{
+			"Expired JWT",
+			sign(fmt.Sprintf(`{"iss":"https://sso.example.com","aud":"appstore","sub":"alice","exp":%d,"groups":["appstore-publishers"]}`, time.Now().Add(-time.Minute).Unix())),
+			http.StatusUnauthorized,
+			`{"detail":"Invalid token: Token has expired.","status":401,"title":"Invalid credentials","type":"/problems/invalid-credentials"}`,
+		},
+		{
+			"API key along with JWT",
+			apiKey,
+			http.StatusForbidden,
+			`{"detail":"'ci' is not granted the scope 'apps:write'.","status":403,"title":"Insufficient scope","type":"/problems/insufficient-scope"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("POST", ts.URL+"/v1/searches", strings.NewReader("name: s1"))
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("Error occurred during POST /v1/searches, detail: %e", err)
+			}
+			body, _ := ioutil.ReadAll(resp.Body)
+			if resp.StatusCode != tt.expectedCode {
+				t.Errorf("Expected status code %d but got %d", tt.expectedCode, resp.StatusCode)
+			}
+			if strings.TrimSpace(string(body)) != tt.expectedBody {
+				t.Errorf("Expected body '%s' but got '%s'", tt.expectedBody, body)
+			}
+		})
+	}
+}
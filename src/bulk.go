@@ -0,0 +1,264 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zzn2/demo/appstore/app"
+	"github.com/zzn2/demo/appstore/policy"
+)
+
+// Statuses of the records in a bulk import.
+const (
+	bulkStatusCreated  = "created"
+	bulkStatusConflict = "conflict"
+	bulkStatusInvalid  = "invalid"
+	// bulkStatusForbidden is used for records of existing apps which the publisher is not an owner or maintainer of.
+	bulkStatusForbidden = "forbidden"
+	// bulkStatusSkipped is used in atomic mode for valid records which were not created because other records were rejected.
+	bulkStatusSkipped = "skipped"
+	// bulkStatusFailed is reported after the results of the records when the created apps could not be persisted.
+	bulkStatusFailed = "failed"
+)
+
+// maxBulkRecordSize is the max size of a single line in a bulk request body.
+const maxBulkRecordSize = 1024 * 1024
+
+// bulkRecord is a single app metadata document read from a bulk request body.
+type bulkRecord struct {
+	// line is the line number where the record starts in the request body, starting from 1.
+	line int
+	data []byte
+}
+
+// bulkResult is the result of importing a single record, which is streamed as a line of NDJSON.
+type bulkResult struct {
+	Line    int    `json:"line"`
+	Status  string `json:"status"`
+	Title   string `json:"title,omitempty"`
+	Version string `json:"version,omitempty"`
+	Error   string `json:"error,omitempty"`
+	// Errors are all the invalid fields of an invalid record, the same as the "errors" of a rejected POST /apps.
+	Errors []fieldError `json:"errors,omitempty"`
+	// Warnings are the violated rules of the publishing policy with "warn" severity, the same as the "warnings" of POST /apps.
+	Warnings []policy.Violation `json:"warnings,omitempty"`
+}
+
+// importApps imports apps in bulk. The request body is either multi-document YAML (documents separated by "---")
+// or NDJSON (Content-Type: application/x-ndjson, one app per line).
+// Every record is validated with the same rules as newApp.
+//
+// The response is streamed as NDJSON, one bulkResult per record.
+// By default valid records are created one by one. With "atomic=true", either all the records are created or none of them.
+func importApps(c *gin.Context) {
+	atomic := false
+	if text, ok := c.GetQuery("atomic"); ok {
+		parsed, err := strconv.ParseBool(text)
+		if err != nil {
+			respond(c, http.StatusBadRequest, responseBodyForErrorMessage("Bad format of atomic '%s'", text))
+			return
+		}
+		atomic = parsed
+	}
+	publisher, ok := publisherOf(c)
+	if !ok {
+		return
+	}
+
+	contentType := c.ContentType()
+	ndjson := contentType == mimeNDJSON
+	if !ndjson && contentType != "" && !contains(yamlMimeTypes, contentType) {
+		respond(c, http.StatusUnsupportedMediaType, responseBodyForError(unsupportedMediaTypeError{contentType, append([]string{mimeNDJSON}, yamlMimeTypes...)}))
+		return
+	}
+
+	c.Header("Content-Type", mimeNDJSON)
+	c.Status(http.StatusOK)
+	encoder := json.NewEncoder(c.Writer)
+	write := func(result bulkResult) {
+		encoder.Encode(result)
+		c.Writer.Flush()
+	}
+
+	var pending []bulkRecord
+	var pendingApps []app.Meta
+	var pendingWarnings [][]policy.Violation
+	var invalid []bulkResult
+	created := 0
+	err := readBulkRecords(c.Request.Body, ndjson, func(record bulkRecord) {
+		meta, warnings, err := decodeBulkRecord(record, ndjson)
+		if err != nil {
+			result := bulkResult{Line: record.line, Status: bulkStatusInvalid, Title: meta.Title, Error: err.Error()}
+			var validationErr *validationError
+			if errors.As(err, &validationErr) {
+				result.Errors = validationErr.fields
+			}
+			if atomic {
+				invalid = append(invalid, result)
+			} else {
+				write(result)
+			}
+			return
+		}
+
+		if atomic {
+			pending = append(pending, record)
+			pendingApps = append(pendingApps, meta)
+			pendingWarnings = append(pendingWarnings, warnings)
+			return
+		}
+
+		result := bulkResult{Line: record.line, Status: bulkStatusCreated, Title: meta.Title, Version: meta.Version.String(), Warnings: warnings}
+		if err := store.AddAs(meta, publisher); err != nil {
+			result.Status = bulkStatusOf(err)
+			result.Error = err.Error()
+		} else {
+			created++
+		}
+		write(result)
+	})
+
+	if atomic {
+		created = importAppsAtomically(pending, pendingApps, pendingWarnings, invalid, publisher, write)
+	}
+	if created > 0 {
+		// The response has been streamed already, so the failure is reported as a result of its own.
+		if err := persistStore(); err != nil {
+			write(bulkResult{Status: bulkStatusFailed, Error: err.Error()})
+		}
+	}
+	if err != nil {
+		write(bulkResult{Status: bulkStatusInvalid, Error: err.Error()})
+	}
+}
+
+// importAppsAtomically adds the valid apps into the store only when there were no invalid records and no conflicts.
+// It writes the results ordered by line number and returns the number of created apps.
+// The warnings are the ones of the publishing policy for each of the apps.
+func importAppsAtomically(records []bulkRecord, apps []app.Meta, warnings [][]policy.Violation, invalid []bulkResult, publisher app.Publisher, write func(bulkResult)) int {
+	results := make([]bulkResult, 0, len(records)+len(invalid))
+	results = append(results, invalid...)
+
+	var batchErr *app.BatchError
+	if len(invalid) == 0 {
+		if err := store.AddAllAs(apps, publisher); err != nil {
+			if !errors.As(err, &batchErr) {
+				batchErr = &app.BatchError{Errors: map[int]error{}}
+			}
+		}
+	}
+
+	status := bulkStatusCreated
+	if len(invalid) > 0 || batchErr != nil {
+		status = bulkStatusSkipped
+	}
+	for i, record := range records {
+		result := bulkResult{Line: record.line, Status: status, Title: apps[i].Title, Version: apps[i].Version.String(), Warnings: warnings[i]}
+		if batchErr != nil && batchErr.Errors[i] != nil {
+			result.Status = bulkStatusOf(batchErr.Errors[i])
+			result.Error = batchErr.Errors[i].Error()
+		}
+		results = append(results, result)
+	}
+
+	// Invalid records were collected apart from the valid ones, restore the order of the request body.
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Line < results[j].Line
+	})
+	for _, result := range results {
+		write(result)
+	}
+
+	if status == bulkStatusCreated {
+		return len(records)
+	}
+	return 0
+}
+
+// bulkStatusOf returns the status of a record rejected by the store.
+func bulkStatusOf(err error) string {
+	if errors.Is(err, app.ErrNotOwner) {
+		return bulkStatusForbidden
+	}
+	return bulkStatusConflict
+}
+
+// decodeBulkRecord decodes a record into app metadata, validates it and checks it against the publishing policy.
+// Records are validated the same as bindApp does for POST /apps, including the keys which are not fields of the app.
+// The returned app is partially filled when decoded but failed to validate, so that the title could be reported.
+// The violated rules with "warn" severity are returned as warnings, see policy.Policy.Check.
+func decodeBulkRecord(record bulkRecord, ndjson bool) (app.Meta, []policy.Violation, error) {
+	var meta app.Meta
+	if err := decodeApp(record.data, ndjson, &meta); err != nil {
+		return meta, nil, err
+	}
+
+	warnings, err := publishingPolicy.Check(meta)
+	return meta, warnings, err
+}
+
+// readBulkRecords reads the records from the bulk request body and calls fn for each of them as soon as it is read.
+// For NDJSON every non-blank line is a record. For YAML records are documents separated by "---" lines.
+func readBulkRecords(r io.Reader, ndjson bool, fn func(bulkRecord)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxBulkRecordSize)
+
+	var doc bytes.Buffer
+	docLine := 1
+	flush := func() {
+		if len(bytes.TrimSpace(doc.Bytes())) > 0 {
+			fn(bulkRecord{line: docLine, data: append([]byte(nil), doc.Bytes()...)})
+		}
+		doc.Reset()
+	}
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := scanner.Bytes()
+		if ndjson {
+			if len(bytes.TrimSpace(text)) > 0 {
+				fn(bulkRecord{line: line, data: append([]byte(nil), text...)})
+			}
+			continue
+		}
+
+		if isYAMLDocumentSeparator(string(text)) {
+			flush()
+			docLine = line + 1
+			continue
+		}
+		if doc.Len() == 0 && len(bytes.TrimSpace(text)) == 0 {
+			// Skip the leading blank lines so that the record starts from its first content line.
+			docLine = line + 1
+			continue
+		}
+		doc.Write(text)
+		doc.WriteByte('\n')
+	}
+	flush()
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("Failed to read request body after line %d: %w", line, err)
+	}
+	return nil
+}
+
+// validateApp validates app metadata with the same rules for all the ways of creating apps, see validateFields.
+// All the invalid fields are reported at once by *validationError.
+func validateApp(meta app.Meta) error {
+	return newValidationError(validateFields(meta))
+}
+
+func isYAMLDocumentSeparator(line string) bool {
+	return line == "---" || strings.HasPrefix(line, "--- ")
+}
@@ -0,0 +1,179 @@
+// Package certs serves TLS with certificates which are reloaded automatically when their files change on disk,
+// e.g. when cert-manager renews the certificate in a mounted secret.
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// DefaultCheckInterval is the default min interval of checking whether the files have changed.
+const DefaultCheckInterval = 5 * time.Second
+
+// Config is the files of the TLS configuration.
+type Config struct {
+	// CertFile and KeyFile are the PEM encoded certificate chain and private key of the server.
+	CertFile string
+	KeyFile  string
+	// ClientCAFile is the PEM encoded CA certificates to verify client certificates.
+	// Clients must present certificates signed by them (mutual TLS) when set.
+	ClientCAFile string
+}
+
+// Reloader provides the TLS configuration from the files, which are reloaded when changed. It is safe for concurrent use.
+//
+// The files are checked lazily during TLS handshakes, at most once per CheckInterval, by their modification times and sizes.
+// When the new files could not be loaded, e.g. the certificate has been written but the key has not yet,
+// the previous configuration is kept and the files are checked again later.
+type Reloader struct {
+	config Config
+	// CheckInterval is the min interval of checking the files, DefaultCheckInterval when zero.
+	CheckInterval time.Duration
+	// now returns the current time, which could be replaced in tests.
+	now func() time.Time
+
+	mu        sync.Mutex
+	current   *tls.Config
+	stamps    []fileStamp
+	checkedAt time.Time
+}
+
+// fileStamp identifies the version of a file.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// NewReloader loads the files of the config. The certificate and the key are required.
+func NewReloader(config Config) (*Reloader, error) {
+	if config.CertFile == "" || config.KeyFile == "" {
+		return nil, errors.New("Both the certificate file and the key file are required for TLS.")
+	}
+
+	r := &Reloader{config: config, now: time.Now}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// TLSConfig returns the TLS configuration for servers, which resolves the current configuration for each client.
+// HTTP/2 is offered along with HTTP/1.1.
+//
+// GetCertificate is set as well, because http.Server.ServeTLS before Go 1.18 only accepts certificates
+// from Certificates or GetCertificate, and fails to open the empty file names otherwise.
+func (r *Reloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		NextProtos:         []string{"h2", "http/1.1"},
+		GetConfigForClient: r.getConfigForClient,
+		GetCertificate:     r.getCertificate,
+	}
+}
+
+// getCertificate returns the current certificate. It is only used when GetConfigForClient is not.
+func (r *Reloader) getCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	config, err := r.getConfigForClient(hello)
+	if err != nil {
+		return nil, err
+	}
+	return &config.Certificates[0], nil
+}
+
+func (r *Reloader) getConfigForClient(*tls.ClientHelloInfo) (*tls.Config, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	interval := r.CheckInterval
+	if interval == 0 {
+		interval = DefaultCheckInterval
+	}
+	if now := r.now(); now.Sub(r.checkedAt) >= interval {
+		r.checkedAt = now
+		if stamps, err := r.stat(); err == nil && !sameStamps(stamps, r.stamps) {
+			if err := r.load(stamps); err != nil {
+				log.Printf("Failed to reload TLS certificates, keep using the previous ones: %s", err)
+			}
+		}
+	}
+	return r.current, nil
+}
+
+// Reload loads the files regardless of whether they have changed.
+func (r *Reloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stamps, err := r.stat()
+	if err != nil {
+		return fmt.Errorf("Failed to load TLS certificates: %w", err)
+	}
+	r.checkedAt = r.now()
+	return r.load(stamps)
+}
+
+// load reads the files into a new configuration. The caller must hold r.mu.
+func (r *Reloader) load(stamps []fileStamp) error {
+	cert, err := tls.LoadX509KeyPair(r.config.CertFile, r.config.KeyFile)
+	if err != nil {
+		return fmt.Errorf("Failed to load TLS certificates: %w", err)
+	}
+
+	config := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		NextProtos:   []string{"h2", "http/1.1"},
+		Certificates: []tls.Certificate{cert},
+	}
+	if r.config.ClientCAFile != "" {
+		data, err := ioutil.ReadFile(r.config.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("Failed to load client CA certificates: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return fmt.Errorf("Failed to load client CA certificates: no certificates in '%s'", r.config.ClientCAFile)
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	r.current = config
+	r.stamps = stamps
+	return nil
+}
+
+func (r *Reloader) stat() ([]fileStamp, error) {
+	files := []string{r.config.CertFile, r.config.KeyFile}
+	if r.config.ClientCAFile != "" {
+		files = append(files, r.config.ClientCAFile)
+	}
+
+	stamps := make([]fileStamp, 0, len(files))
+	for _, file := range files {
+		// Stat follows symbolic links, so that the atomic updates of Kubernetes secrets (swapping the "..data" link) are detected.
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, err
+		}
+		stamps = append(stamps, fileStamp{info.ModTime(), info.Size()})
+	}
+	return stamps, nil
+}
+
+func sameStamps(a []fileStamp, b []fileStamp) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].modTime.Equal(b[i].modTime) || a[i].size != b[i].size {
+			return false
+		}
+	}
+	return true
+}
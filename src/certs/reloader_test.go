@@ -0,0 +1,139 @@
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeCert generates a self-signed certificate with the serial number, and writes it with its key into the files.
+func writeCert(t *testing.T, certFile string, keyFile string, serial int64) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IsCA:         true,
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+}
+
+func serialOf(t *testing.T, config *tls.Config) int64 {
+	cert, err := x509.ParseCertificate(config.Certificates[0].Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert.SerialNumber.Int64()
+}
+
+func TestReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	caFile := filepath.Join(dir, "ca.crt")
+	writeCert(t, certFile, keyFile, 1)
+	writeCert(t, caFile, filepath.Join(dir, "ca.key"), 100)
+
+	r, err := NewReloader(Config{CertFile: certFile, KeyFile: keyFile, ClientCAFile: caFile})
+	if err != nil {
+		t.Fatalf("Should not have error but error '%s' occurred.", err.Error())
+	}
+	now := time.Now()
+	r.now = func() time.Time { return now }
+
+	config, _ := r.TLSConfig().GetConfigForClient(nil)
+	// GetCertificate is required by http.Server.ServeTLS of older Go versions.
+	if cert, err := r.TLSConfig().GetCertificate(nil); err != nil || serialOf(t, &tls.Config{Certificates: []tls.Certificate{*cert}}) != 1 {
+		t.Errorf("Expected certificate 1 from GetCertificate but got error '%v'", err)
+	}
+	if serialOf(t, config) != 1 || config.ClientAuth != tls.RequireAndVerifyClientCert || config.ClientCAs == nil {
+		t.Errorf("Expected certificate 1 with client authentication but got %+v", config)
+	}
+
+	// The certificate is renewed, which is only noticed after the check interval.
+	writeCert(t, certFile, keyFile, 2)
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(certFile, later, later)
+	os.Chtimes(keyFile, later, later)
+	if config, _ := r.getConfigForClient(nil); serialOf(t, config) != 1 {
+		t.Errorf("Expected the files not to be checked within the interval")
+	}
+	now = now.Add(DefaultCheckInterval)
+	if config, _ := r.getConfigForClient(nil); serialOf(t, config) != 2 {
+		t.Errorf("Expected the renewed certificate 2 but got %d", serialOf(t, config))
+	}
+
+	// A half-written renewal keeps the previous certificate, until the key is written too.
+	writeCert(t, certFile, keyFile, 3)
+	keyPEM, _ := ioutil.ReadFile(keyFile)
+	ioutil.WriteFile(keyFile, []byte("broken"), 0600)
+	now = now.Add(DefaultCheckInterval)
+	if config, _ := r.getConfigForClient(nil); serialOf(t, config) != 2 {
+		t.Errorf("Expected the previous certificate 2 to be kept but got %d", serialOf(t, config))
+	}
+	ioutil.WriteFile(keyFile, keyPEM, 0600)
+	evenLater := later.Add(time.Minute)
+	os.Chtimes(keyFile, evenLater, evenLater)
+	now = now.Add(DefaultCheckInterval)
+	if config, _ := r.getConfigForClient(nil); serialOf(t, config) != 3 {
+		t.Errorf("Expected the renewed certificate 3 but got %d", serialOf(t, config))
+	}
+}
+
+func TestNewReloader_Errors(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	writeCert(t, certFile, keyFile, 1)
+	emptyFile := filepath.Join(dir, "empty.crt")
+	ioutil.WriteFile(emptyFile, nil, 0600)
+
+	var tests = []struct {
+		name           string
+		config         Config
+		expectedErrMsg string
+	}{
+		{"Without key", Config{CertFile: certFile}, "Both the certificate file and the key file are required for TLS."},
+		{"Missing file", Config{CertFile: filepath.Join(dir, "missing.crt"), KeyFile: keyFile},
+			"Failed to load TLS certificates: stat " + filepath.Join(dir, "missing.crt") + ": no such file or directory"},
+		{"Empty client CA", Config{CertFile: certFile, KeyFile: keyFile, ClientCAFile: emptyFile},
+			"Failed to load client CA certificates: no certificates in '" + emptyFile + "'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewReloader(tt.config)
+			if err == nil || err.Error() != tt.expectedErrMsg {
+				t.Errorf("Expected error '%s' but got '%v'", tt.expectedErrMsg, err)
+			}
+		})
+	}
+}
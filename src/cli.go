@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/zzn2/demo/appstore/app"
+	"github.com/zzn2/demo/appstore/archive"
+	"github.com/zzn2/demo/appstore/auth"
+)
+
+// listenAddr is the address the server listens on.
+const listenAddr = ":3001"
+
+// runCommand runs the subcommand given by args (without the program name):
+//
+//    serve                                    -> start the server (default), serving HTTPS if TLS is configured,
+//                                                until SIGTERM or SIGINT shuts it down gracefully
+//    export [-o catalog.tar.gz]               -> export the store into an archive, or stdout when -o is omitted
+//    import [-mode merge|replace] <archive>   -> import an archive into the store
+//    keys create -name <name> [-email <email>] -scopes <list>
+//                                             -> create an API key and print it, e.g. -scopes apps:read,apps:write
+//    keys list                                -> list the API keys
+//    keys revoke <id>                         -> revoke an API key
+//
+// export and import work on the store persisted in dataFile, so they could be used without a running server.
+// Likewise, keys works on the API keys persisted in apiKeysFile.
+func runCommand(args []string, stdout io.Writer) error {
+	if len(args) == 0 {
+		args = []string{"serve"}
+	}
+
+	switch args[0] {
+	case "serve":
+		setupStore()
+		srv := newHTTPServer(setupServer())
+		ln, err := net.Listen("tcp", srv.Addr)
+		if err != nil {
+			return err
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+		defer stop()
+		return serve(ctx, srv, ln)
+	case "export":
+		return runExport(args[1:], stdout)
+	case "import":
+		return runImport(args[1:], stdout)
+	case "keys":
+		return runKeys(args[1:], stdout)
+	default:
+		return fmt.Errorf("Unknown command '%s'. Available commands are: serve, export, import, keys", args[0])
+	}
+}
+
+func runExport(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	output := flags.String("o", "", "path of the archive to write, default to stdout")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	setupStore()
+	catalog, err := store.Catalog()
+	if err != nil {
+		return err
+	}
+
+	w := stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return fmt.Errorf("Failed to export: %w", err)
+		}
+		defer f.Close()
+		w = f
+	}
+	return archive.Write(w, catalog, time.Now())
+}
+
+func runImport(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	modeText := flags.String("mode", string(app.ImportMerge), "how to import the apps, either merge or replace")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("Usage: import [-mode merge|replace] <archive>")
+	}
+	if dataFile == "" {
+		return errors.New("Failed to import: APPSTORE_DATA_FILE must be set to persist the imported apps")
+	}
+
+	mode, err := app.ParseImportMode(*modeText)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(flags.Arg(0))
+	if err != nil {
+		return fmt.Errorf("Failed to import: %w", err)
+	}
+	defer f.Close()
+
+	setupStore()
+	result, err := importArchive(f, mode)
+	if err != nil {
+		return err
+	}
+	if err := store.SaveFile(dataFile); err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(result)
+}
+
+func runKeys(args []string, stdout io.Writer) error {
+	const usage = "Usage: keys create -name <name> [-email <email>] -scopes <list> | keys list | keys revoke <id>"
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+	if apiKeys == nil {
+		return errAPIKeysDisabled
+	}
+
+	encoder := json.NewEncoder(stdout)
+	encoder.SetIndent("", "  ")
+	switch args[0] {
+	case "create":
+		flags := flag.NewFlagSet("keys create", flag.ContinueOnError)
+		name := flags.String("name", "", "name of the API key, e.g. the client using it")
+		email := flags.String("email", "", "email of the owner or maintainer of apps using the API key")
+		scopesText := flags.String("scopes", string(auth.ScopeAppsRead), "comma separated scopes granted to the API key")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		scopes, err := auth.ParseScopes(*scopesText)
+		if err != nil {
+			return err
+		}
+		key, info, err := apiKeys.Create(*name, *email, scopes)
+		if err != nil {
+			return err
+		}
+		if err := apiKeys.SaveFile(apiKeysFile); err != nil {
+			return err
+		}
+		return encoder.Encode(createdAPIKey{info, key})
+	case "list":
+		return encoder.Encode(apiKeys.List())
+	case "revoke":
+		if len(args) != 2 {
+			return errors.New(usage)
+		}
+		if err := apiKeys.Revoke(args[1]); err != nil {
+			return err
+		}
+		return apiKeys.SaveFile(apiKeysFile)
+	default:
+		return errors.New(usage)
+	}
+}
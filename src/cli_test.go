@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zzn2/demo/appstore/app"
+	"github.com/zzn2/demo/appstore/auth"
+	"github.com/zzn2/demo/appstore/semver"
+)
+
+func TestExportAndImportCommands(t *testing.T) {
+	dir := t.TempDir()
+	defer func(original string) { dataFile = original }(dataFile)
+
+	// Prepare a store with one app in the source data file.
+	dataFile = filepath.Join(dir, "source.json")
+	setupStore()
+	store.Add(app.Meta{Title: "App1", Version: semver.Version{Patch: 1}, Maintainers: []app.Maintainer{{Name: "Alice", Email: "alice@hotmail.com"}},
+		Company: "Random Inc.", Website: "https://website.com", Source: "https://github.com/random/repo", License: "MIT", Description: "desc"})
+	if err := persistStore(); err != nil {
+		t.Fatalf("Should not have error but error '%s' occurred.", err.Error())
+	}
+
+	archivePath := filepath.Join(dir, "catalog.tar.gz")
+	if err := runCommand([]string{"export", "-o", archivePath}, &bytes.Buffer{}); err != nil {
+		t.Fatalf("Should not have error but error '%s' occurred.", err.Error())
+	}
+
+	// Import the archive into another data file.
+	dataFile = filepath.Join(dir, "target.json")
+	var out bytes.Buffer
+	if err := runCommand([]string{"import", "-mode", "replace", archivePath}, &out); err != nil {
+		t.Fatalf("Should not have error but error '%s' occurred.", err.Error())
+	}
+	if !strings.Contains(out.String(), `"Created": 1`) {
+		t.Errorf("Expected 1 app created but got '%s'", out.String())
+	}
+
+	setupStore()
+	if store.GetByTitle("App1") == nil {
+		t.Errorf("Expected App1 to be imported into the target data file")
+	}
+}
+
+// TestCommandsNextToServer covers the import and keys commands run while the server is running on the same files.
+// The commands run in other processes, which are simulated by other stores loaded from the files.
+func TestCommandsNextToServer(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	defer func(original auth.Authenticator, keys *auth.KeyStore, file string, data string) {
+		authenticator, apiKeys, apiKeysFile, dataFile = original, keys, file, data
+	}(authenticator, apiKeys, apiKeysFile, dataFile)
+	dir := t.TempDir()
+	dataFile = filepath.Join(dir, "store.json")
+	apiKeysFile = filepath.Join(dir, "keys.json")
+	setupStore()
+	apiKeys = auth.NewKeyStore()
+	authenticator = apiKeys
+	key, info, _ := apiKeys.Create("admin", "", []auth.Scope{auth.ScopeAdmin})
+	persistAPIKeys()
+	ts := httptest.NewServer(setupServer())
+	defer ts.Close()
+
+	get := func(path string) int {
+		req, _ := http.NewRequest("GET", ts.URL+path, nil)
+		req.Header.Set("Authorization", "Bearer "+key)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Error occurred during GET %s, detail: %e", path, err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// An app is imported into the data file.
+	var imported app.Store
+	imported.LoadFile(dataFile)
+	imported.Add(app.Meta{Title: "App1", Version: semver.Version{Patch: 1}, Maintainers: []app.Maintainer{{Name: "Alice", Email: "alice@hotmail.com"}},
+		Company: "Random Inc.", Website: "https://website.com", Source: "https://github.com/random/repo", License: "MIT", Description: "desc"})
+	if err := imported.SaveFile(dataFile); err != nil {
+		t.Fatalf("Should not have error but error '%s' occurred.", err.Error())
+	}
+	if code := get("/v1/apps/App1"); code != http.StatusOK {
+		t.Errorf("Expected the imported app to be served but got %d", code)
+	}
+
+	// The key of the server is revoked in the key file.
+	revoked := auth.NewKeyStore()
+	revoked.LoadFile(apiKeysFile)
+	revoked.Revoke(info.ID)
+	if err := revoked.SaveFile(apiKeysFile); err != nil {
+		t.Fatalf("Should not have error but error '%s' occurred.", err.Error())
+	}
+	if code := get("/v1/apps/App1"); code != http.StatusUnauthorized {
+		t.Errorf("Expected the revoked key to be rejected but got %d", code)
+	}
+
+	// Neither the import nor the revocation is overwritten when the server shuts down.
+	imported.Add(app.Meta{Title: "App2", Version: semver.Version{Patch: 1}})
+	imported.SaveFile(dataFile)
+	if err := runShutdownHooks(context.Background()); err != nil {
+		t.Fatalf("Should not have error but error '%s' occurred.", err.Error())
+	}
+	var flushed app.Store
+	flushed.LoadFile(dataFile)
+	if flushed.GetByTitle("App2") == nil {
+		t.Errorf("Expected the imported app not to be overwritten on shutdown")
+	}
+	keys := auth.NewKeyStore()
+	keys.LoadFile(apiKeysFile)
+	if _, err := keys.Authenticate(key); err == nil {
+		t.Errorf("Expected the revoked key not to be restored on shutdown")
+	}
+}
+
+func TestRunCommand_Errors(t *testing.T) {
+	defer func(original string) { dataFile = original }(dataFile)
+	dataFile = ""
+
+	var tests = []struct {
+		args           []string
+		expectedErrMsg string
+	}{
+		{[]string{"dummy"}, "Unknown command 'dummy'. Available commands are: serve, export, import, keys"},
+		{[]string{"import"}, "Usage: import [-mode merge|replace] <archive>"},
+		{[]string{"import", "catalog.tar.gz"}, "Failed to import: APPSTORE_DATA_FILE must be set to persist the imported apps"},
+		{[]string{"keys"}, "Usage: keys create -name <name> [-email <email>] -scopes <list> | keys list | keys revoke <id>"},
+		{[]string{"keys", "list"}, "APPSTORE_API_KEYS_FILE must be set to manage API keys"},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			err := runCommand(tt.args, &bytes.Buffer{})
+			if err == nil || err.Error() != tt.expectedErrMsg {
+				t.Errorf("Expected to have error '%s' but got '%v'", tt.expectedErrMsg, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zzn2/demo/appstore/app"
+	"github.com/zzn2/demo/appstore/policy"
+	"github.com/zzn2/demo/appstore/semver"
+)
+
+// etag returns the ETag header value of the app revision in the media type negotiated by negotiateFormat, see etagIn.
+// The revision is the one of the app in the store (see app.Store.RevisionOf), so that yanking or unyanking the app changes its ETag.
+func etag(c *gin.Context, revision string) string {
+	return etagIn(revision, c.GetString(responseFormatKey))
+}
+
+// etagIn returns the ETag header value of the app revision in the media type,
+// which is the quoted revision followed by the media type unless JSON, e.g. "<revision>" or "<revision>-x-yaml".
+// Each representation has its own strong ETag, since they differ in bytes.
+func etagIn(revision string, format string) string {
+	if format == "" || format == mimeJSON {
+		return `"` + revision + `"`
+	}
+	return `"` + revision + "-" + strings.ReplaceAll(strings.TrimPrefix(format, "application/"), "/", "-") + `"`
+}
+
+// respondApp responds the app with its ETag.
+// It responds 304 Not Modified without body when the If-None-Match header matches the representation of the app.
+func respondApp(c *gin.Context, code int, meta app.Meta) {
+	tag := etag(c, store.RevisionOf(meta))
+	c.Header("ETag", tag)
+	if header := c.GetHeader("If-None-Match"); header != "" && matchETags(header, []string{tag}, true) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	respond(c, code, newAppResource(meta))
+}
+
+// updateApp replaces the metadata of an existing app version.
+// When the If-Match header is present, the app is only updated if the current ETag of any representation matches,
+// otherwise it responds 412.
+// Only the owners or the maintainers of the app could update it, otherwise it responds 403.
+// The new metadata must comply with the publishing policy the same as newApp, otherwise it responds 422.
+func updateApp(c *gin.Context) {
+	// Redirect before reading the body, so that it could be sent again to the canonical URL.
+	title, ok := resolveTitle(c)
+	if !ok {
+		return
+	}
+	publisher, ok := publisherOf(c)
+	if !ok {
+		return
+	}
+
+	var meta app.Meta
+	if err := bindApp(c, &meta); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	versionText := c.Param("version")
+	version, err := semver.Parse(versionText)
+	if err != nil {
+		respond(c, http.StatusBadRequest, responseBodyForError(badVersionParam(versionText, err)))
+		return
+	}
+	if meta.Title != title || meta.Version != version {
+		respond(c, http.StatusBadRequest, responseBodyForErrorMessage("Title '%s' and version '%s' in the request body do not match the URL.", meta.Title, meta.Version))
+		return
+	}
+
+	// Updates are checked against the publishing policy as well, otherwise denied contents could replace published ones.
+	warnings, err := publishingPolicy.Check(meta)
+	if errors.Is(err, policy.ErrDenied) {
+		respond(c, http.StatusUnprocessableEntity, responseBodyForError(err))
+		return
+	} else if err != nil {
+		respond(c, http.StatusInternalServerError, responseBodyForError(err))
+		return
+	}
+
+	var precondition func(app.Meta, string) bool
+	if header := c.GetHeader("If-Match"); header != "" {
+		precondition = func(current app.Meta, revision string) bool {
+			// All the representations describe the same revision to be replaced.
+			tags := make([]string, 0, len(offeredMimeTypes))
+			for _, format := range offeredMimeTypes {
+				tags = append(tags, etagIn(revision, format))
+			}
+			return matchETags(header, tags, false)
+		}
+	}
+
+	err = store.UpdateAs(meta, precondition, publisher)
+	var notFound *app.NotFoundError
+	var mismatch *app.RevisionMismatchError
+	switch {
+	case errors.As(err, &notFound):
+		respond(c, http.StatusNotFound, responseBodyForError(err))
+	case errors.Is(err, app.ErrNotOwner):
+		respond(c, http.StatusForbidden, responseBodyForError(err))
+	case errors.As(err, &mismatch):
+		c.Header("ETag", etag(c, mismatch.Revision))
+		respond(c, http.StatusPreconditionFailed, responseBodyForError(err))
+	case err != nil:
+		respond(c, http.StatusBadRequest, responseBodyForError(err))
+	default:
+		if err := persistStore(); err != nil {
+			respond(c, http.StatusInternalServerError, responseBodyForError(err))
+			return
+		}
+		c.Header("ETag", etag(c, store.RevisionOf(meta)))
+		resource := newAppResource(meta)
+		resource.Warnings = warnings
+		respond(c, http.StatusOK, resource)
+	}
+}
+
+// matchETags tells whether the list of entity tags in an If-Match or If-None-Match header matches any of the current ETags.
+// "*" matches any app. Weak entity tags (W/"...") only match in weak comparison, which is used by If-None-Match.
+func matchETags(header string, current []string, weak bool) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return true
+		}
+		if strings.HasPrefix(tag, "W/") {
+			if !weak {
+				continue
+			}
+			tag = strings.TrimPrefix(tag, "W/")
+		}
+		if contains(current, tag) {
+			return true
+		}
+	}
+	return false
+}
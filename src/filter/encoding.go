@@ -0,0 +1,139 @@
+package filter
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/zzn2/demo/appstore/filter/op"
+)
+
+// ruleJSON is the stable JSON representation of a Rule.
+// The value is always kept as text so that it can be parsed against the target type again.
+//
+//    {"field": "version", "op": "gt", "value": "0.0.1"}
+//
+type ruleJSON struct {
+	Field string `json:"field" yaml:"field"`
+	Op    string `json:"op" yaml:"op"`
+	Value string `json:"value" yaml:"value"`
+}
+
+// ruleSetJSON is the stable JSON representation of a RuleSet.
+type ruleSetJSON struct {
+	Rules []ruleJSON `json:"rules" yaml:"rules"`
+}
+
+// Key returns the query string key of this rule, e.g. "title[like]".
+// Rules using the Equals operator are represented without LHS brackets, e.g. "title".
+func (r Rule) Key() string {
+	if r.Op == op.Equals {
+		return r.FieldName
+	}
+	return fmt.Sprintf("%s[%s]", r.FieldName, r.Op.OpText)
+}
+
+// ValueText returns the text representation of the value of this rule.
+// The text could be parsed back into the value by NewRule.
+func (r Rule) ValueText() string {
+	switch value := r.Value.(type) {
+	case string:
+		return value
+	case int:
+		return strconv.Itoa(value)
+	case encoding.TextMarshaler:
+		if text, err := value.MarshalText(); err == nil {
+			return string(text)
+		}
+	}
+	return fmt.Sprint(r.Value)
+}
+
+// MarshalJSON serializes the rule into its stable JSON form.
+func (r Rule) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.toJSON())
+}
+
+// MarshalJSON serializes the rule set into its stable JSON form.
+// An empty rule set is serialized as {"rules":[]}.
+func (rs RuleSet) MarshalJSON() ([]byte, error) {
+	return json.Marshal(rs.toJSON())
+}
+
+// MarshalYAML serializes the rule set into YAML with the same structure as its JSON form.
+func (rs RuleSet) MarshalYAML() (interface{}, error) {
+	return rs.toJSON(), nil
+}
+
+// ParseRuleSetJSON deserializes the JSON form of a RuleSet generated by RuleSet.MarshalJSON.
+// Every rule is validated against applyToObj the same way as NewRule does,
+// so a stored rule set becomes invalid once the target type no longer supports it.
+func ParseRuleSetJSON(data []byte, applyToObj interface{}) (RuleSet, error) {
+	var in ruleSetJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return RuleSet{}, fmt.Errorf("Failed to parse rule set: %w", err)
+	}
+
+	rs := RuleSet{}
+	for _, r := range in.Rules {
+		key := r.Field
+		if r.Op != "" && r.Op != op.Equals.OpText {
+			key = fmt.Sprintf("%s[%s]", r.Field, r.Op)
+		}
+
+		rule, err := NewRule(key, r.Value, applyToObj)
+		if err != nil {
+			return RuleSet{}, err
+		}
+		rs.AddRule(rule)
+	}
+	return rs, nil
+}
+
+// Query returns the query params representation of the rule set.
+// It could be parsed back by CreateRuleSet.
+func (rs RuleSet) Query() url.Values {
+	values := url.Values{}
+	for _, rule := range rs.Rules {
+		values.Add(rule.Key(), rule.ValueText())
+	}
+	return values
+}
+
+// QueryString returns the encoded query string of the rule set, e.g. "title%5Blike%5D=App&version=0.0.1".
+func (rs RuleSet) QueryString() string {
+	return rs.Query().Encode()
+}
+
+// ParseRuleSetQuery parses an encoded query string into a RuleSet.
+func ParseRuleSetQuery(query string, applyToObj interface{}) (RuleSet, error) {
+	return ParseRuleSetQueryWith(nil, query, applyToObj)
+}
+
+// ParseRuleSetQueryWith parses an encoded query string into a RuleSet the same as ParseRuleSetQuery,
+// but looks up the operators in the registry, see NewRuleWith. A nil registry is op.DefaultRegistry.
+func ParseRuleSetQueryWith(registry *op.Registry, query string, applyToObj interface{}) (RuleSet, error) {
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return RuleSet{}, fmt.Errorf("Failed to parse query string: %w", err)
+	}
+	return createRuleSet(values, applyToObj, registry)
+}
+
+func (rs RuleSet) toJSON() ruleSetJSON {
+	out := ruleSetJSON{Rules: make([]ruleJSON, 0, len(rs.Rules))}
+	for _, rule := range rs.Rules {
+		out.Rules = append(out.Rules, rule.toJSON())
+	}
+	return out
+}
+
+func (r Rule) toJSON() ruleJSON {
+	return ruleJSON{
+		Field: r.FieldName,
+		Op:    r.Op.OpText,
+		Value: r.ValueText(),
+	}
+}
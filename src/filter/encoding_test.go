@@ -0,0 +1,98 @@
+package filter
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/zzn2/demo/appstore/filter/op"
+	"github.com/zzn2/demo/appstore/semver"
+)
+
+func TestRuleSetMarshalJSON(t *testing.T) {
+	var u User
+	ruleSet, err := CreateRuleSet(map[string][]string{
+		"version[gt]":     {"0.0.1"},
+		"firstname[like]": {"To"},
+		"age":             {"20"},
+	}, u)
+	if err != nil {
+		t.Fatalf("Failed to create RuleSet: %s", err)
+	}
+
+	data, err := json.Marshal(ruleSet)
+	if err != nil {
+		t.Fatalf("Failed to marshal RuleSet: %s", err)
+	}
+
+	expected := `{"rules":[{"field":"age","op":"eq","value":"20"},{"field":"firstname","op":"like","value":"To"},{"field":"version","op":"gt","value":"0.0.1"}]}`
+	if string(data) != expected {
+		t.Errorf("Expected to be '%s' but got '%s'", expected, string(data))
+	}
+
+	parsed, err := ParseRuleSetJSON(data, u)
+	if err != nil {
+		t.Fatalf("Failed to parse RuleSet: %s", err)
+	}
+	if !reflect.DeepEqual(parsed, ruleSet) {
+		t.Errorf("Expected to be '%s' but got '%s'", ruleSet, parsed)
+	}
+}
+
+func TestRuleSetMarshalJSON_Empty(t *testing.T) {
+	data, err := json.Marshal(RuleSet{})
+	if err != nil {
+		t.Fatalf("Failed to marshal RuleSet: %s", err)
+	}
+	if string(data) != `{"rules":[]}` {
+		t.Errorf("Expected to be '{\"rules\":[]}' but got '%s'", string(data))
+	}
+}
+
+func TestParseRuleSetJSON_Validation(t *testing.T) {
+	var u User
+	var tests = []struct {
+		input          string
+		expectedErrMsg string
+	}{
+		{`{"rules":[{"field":"name","op":"eq","value":"Tom"}]}`, "Failed to create rule: Field with name 'name' does not exist."},
+		{`{"rules":[{"field":"firstname","op":"lt","value":"Tom"}]}`, "Failed to create rule: Type 'string' does not support 'LessThan' operator"},
+		{`{"rules":[{"field":"age","op":"eq","value":"abc"}]}`, `Failed to create rule: Invalid integer format: strconv.ParseInt: parsing "abc": invalid syntax`},
+		{`{"rules":[{"field":"age","op":"dummy","value":"1"}]}`, "Unrecognized operator type 'dummy'"},
+		{`{"rules":`, "Failed to parse rule set: unexpected end of JSON input"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := ParseRuleSetJSON([]byte(tt.input), u)
+			if err == nil || err.Error() != tt.expectedErrMsg {
+				t.Errorf("Expected to have error '%s' but got '%v'", tt.expectedErrMsg, err)
+			}
+		})
+	}
+}
+
+func TestRuleSetQueryString(t *testing.T) {
+	var u User
+	ruleSet := RuleSet{}
+	ruleSet.AddRule(Rule{FieldName: "firstname", Op: op.Like, Value: "To m"})
+	ruleSet.AddRule(Rule{FieldName: "version", Op: op.GreaterThan, Value: semver.Version{Major: 1}})
+	ruleSet.AddRule(Rule{FieldName: "age", Op: op.Equals, Value: 20})
+
+	query := ruleSet.QueryString()
+	expected := "age=20&firstname%5Blike%5D=To+m&version%5Bgt%5D=1.0.0"
+	if query != expected {
+		t.Errorf("Expected to be '%s' but got '%s'", expected, query)
+	}
+
+	parsed, err := ParseRuleSetQuery(query, u)
+	if err != nil {
+		t.Fatalf("Failed to parse query string: %s", err)
+	}
+	if len(parsed.Rules) != 3 {
+		t.Fatalf("Expected to be 3 rules but got %d", len(parsed.Rules))
+	}
+	if parsed.QueryString() != expected {
+		t.Errorf("Expected to be '%s' but got '%s'", expected, parsed.QueryString())
+	}
+}
@@ -0,0 +1,45 @@
+package filter
+
+import "errors"
+
+// Kinds of errors returned by this package, which could be matched by errors.Is.
+// Errors of operators are reported with the kinds defined in package op, e.g. op.ErrOperatorTypeMismatch.
+var (
+	// ErrUnknownField means the field does not exist in the target type.
+	ErrUnknownField = errors.New("unknown field")
+	// ErrMalformedKey means the query string key is not in the "field" or "field[op]" format.
+	ErrMalformedKey = errors.New("malformed key")
+	// ErrDuplicateKey means the same query string key appeared multiple times.
+	ErrDuplicateKey = errors.New("duplicate key")
+	// ErrInvalidValue means the value could not be parsed into the type of the field.
+	ErrInvalidValue = errors.New("invalid value")
+	// ErrUnorderedField means the field could not be used as a sort key.
+	ErrUnorderedField = errors.New("unordered field")
+)
+
+// Error describes an invalid rule, sort key or field selection.
+// errors.Is matches it with its Kind, as well as the kinds of the underlying error.
+type Error struct {
+	// Kind is one of the error kinds of this package, or nil if the kind is defined by the underlying error.
+	Kind error
+	// Field is the name of the offending field, it is empty if unknown.
+	Field string
+	// Param is the offending query string key or sort key, e.g. "title[like]", it is empty if unknown.
+	Param   string
+	Message string
+	// Err is the underlying error, if any.
+	Err error
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is makes errors.Is(err, kind) report true for an Error of the kind.
+func (e *Error) Is(target error) bool {
+	return e.Kind != nil && target == e.Kind
+}
@@ -0,0 +1,84 @@
+package filter
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/zzn2/demo/appstore/filter/op"
+	"github.com/zzn2/demo/appstore/semver"
+)
+
+func TestErrorKinds(t *testing.T) {
+	var u User
+	var tests = []struct {
+		name          string
+		create        func() error
+		expectedKinds []error
+		expectedField string
+		expectedParam string
+	}{
+		{
+			"unknown field in rule",
+			func() error { _, err := ParseRule("name[like]=Tom", u); return err },
+			[]error{ErrUnknownField}, "name", "name[like]",
+		},
+		{
+			"unknown operator",
+			func() error { _, err := ParseRule("age[dummy]=20", u); return err },
+			[]error{op.ErrUnknownOperator}, "age", "age[dummy]",
+		},
+		{
+			"operator type mismatch",
+			func() error { _, err := ParseRule("version[like]=0.0.1", u); return err },
+			[]error{op.ErrOperatorTypeMismatch}, "version", "version[like]",
+		},
+		{
+			"invalid version value",
+			func() error { _, err := ParseRule("version[lt]=0.0.a", u); return err },
+			[]error{ErrInvalidValue, semver.ErrInvalidVersion}, "version", "version[lt]",
+		},
+		{
+			"malformed key",
+			func() error { _, err := ParseRule("illegal/keyformat=1", u); return err },
+			[]error{ErrMalformedKey}, "", "illegal/keyformat",
+		},
+		{
+			"duplicate key",
+			func() error {
+				_, err := CreateRuleSet(url.Values{"age": {"1", "2"}}, u)
+				return err
+			},
+			[]error{ErrDuplicateKey}, "", "age",
+		},
+		{
+			"unknown sort key",
+			func() error { _, err := ParseSortKey("-name", u); return err },
+			[]error{ErrUnknownField}, "name", "-name",
+		},
+		{
+			"unknown selected field",
+			func() error { return ValidateFields([]string{"name"}, u) },
+			[]error{ErrUnknownField}, "name", "name",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.create()
+			for _, kind := range tt.expectedKinds {
+				if !errors.Is(err, kind) {
+					t.Errorf("Expect error '%v' to be '%v'.", err, kind)
+				}
+			}
+
+			var filterErr *Error
+			if !errors.As(err, &filterErr) {
+				t.Fatalf("Expect error to be *Error but got %T.", err)
+			}
+			if filterErr.Field != tt.expectedField || filterErr.Param != tt.expectedParam {
+				t.Errorf("Expect field '%s' and param '%s' but got '%s' and '%s'.", tt.expectedField, tt.expectedParam, filterErr.Field, filterErr.Param)
+			}
+		})
+	}
+}
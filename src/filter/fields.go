@@ -0,0 +1,48 @@
+package filter
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ValidateFields checks that all the given field names exist in applyToObj.
+func ValidateFields(names []string, applyToObj interface{}) error {
+	for _, name := range names {
+		if !getFieldByName(applyToObj, name).IsValid() {
+			return unknownSelectedField(name)
+		}
+	}
+	return nil
+}
+
+// SelectFields returns a map which only contains the given fields of obj.
+// Field names are matched case insensitively, while the keys in the result are the names in the json tags of the fields,
+// or the names declared in the struct for fields without them, so that the result is marshalled like obj.
+func SelectFields(obj interface{}, names []string) (map[string]interface{}, error) {
+	v := reflect.ValueOf(obj)
+	result := make(map[string]interface{}, len(names))
+	for _, name := range names {
+		structField, ok := v.Type().FieldByNameFunc(func(fieldName string) bool {
+			return strings.EqualFold(name, fieldName)
+		})
+		if !ok {
+			return nil, unknownSelectedField(name)
+		}
+		key := structField.Name
+		if tag := strings.Split(structField.Tag.Get("json"), ",")[0]; tag != "" && tag != "-" {
+			key = tag
+		}
+		result[key] = v.FieldByIndex(structField.Index).Interface()
+	}
+	return result, nil
+}
+
+func unknownSelectedField(name string) error {
+	return &Error{
+		Kind:    ErrUnknownField,
+		Field:   name,
+		Param:   name,
+		Message: fmt.Sprintf("Failed to select fields: Field with name '%s' does not exist.", name),
+	}
+}
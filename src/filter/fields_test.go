@@ -0,0 +1,48 @@
+package filter
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/zzn2/demo/appstore/semver"
+)
+
+func TestValidateFields(t *testing.T) {
+	var u User
+	if err := ValidateFields([]string{"firstname", "Age"}, u); err != nil {
+		t.Errorf("Should not have error but error '%s' occurred.", err.Error())
+	}
+
+	err := ValidateFields([]string{"firstname", "name"}, u)
+	expectedErrMsg := "Failed to select fields: Field with name 'name' does not exist."
+	if err == nil || err.Error() != expectedErrMsg {
+		t.Errorf("Expected to have error '%s' but got '%v'", expectedErrMsg, err)
+	}
+}
+
+func TestSelectFields(t *testing.T) {
+	u := User{FirstName: "Tom", LastName: "Smith", Age: 20, Version: semver.Version{Major: 1}}
+
+	selected, err := SelectFields(u, []string{"firstname", "VERSION"})
+	if err != nil {
+		t.Fatalf("Should not have error but error '%s' occurred.", err.Error())
+	}
+
+	expected := map[string]interface{}{"FirstName": "Tom", "Version": semver.Version{Major: 1}}
+	if !reflect.DeepEqual(selected, expected) {
+		t.Errorf("Expected to be '%v' but got '%v'", expected, selected)
+	}
+
+	if _, err := SelectFields(u, []string{"name"}); err == nil {
+		t.Errorf("Expected to have error but had none.")
+	}
+
+	// Fields with json tags are selected by their tagged names.
+	tagged := struct {
+		FirstName string `json:"first_name,omitempty"`
+	}{"Tom"}
+	selected, err = SelectFields(tagged, []string{"FIRSTNAME"})
+	if err != nil || !reflect.DeepEqual(selected, map[string]interface{}{"first_name": "Tom"}) {
+		t.Errorf("Expected to be 'map[first_name:Tom]' but got '%v' with error '%v'", selected, err)
+	}
+}
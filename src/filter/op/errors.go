@@ -0,0 +1,49 @@
+package op
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Kinds of errors returned by this package, which could be matched by errors.Is.
+var (
+	// ErrUnknownOperator means the operator is not registered.
+	ErrUnknownOperator = errors.New("unknown operator")
+	// ErrOperatorTypeMismatch means the operator could not be applied to values of the given type.
+	ErrOperatorTypeMismatch = errors.New("operator type mismatch")
+)
+
+// UnknownOperatorError is returned when the operator text is not registered.
+type UnknownOperatorError struct {
+	Text string
+}
+
+func (e *UnknownOperatorError) Error() string {
+	return fmt.Sprintf("Unrecognized operator type '%s'", e.Text)
+}
+
+// Is makes errors.Is(err, ErrUnknownOperator) report true for an UnknownOperatorError.
+func (e *UnknownOperatorError) Is(target error) bool {
+	return target == ErrUnknownOperator
+}
+
+// TypeMismatchError is returned when the operator could not be applied to the values.
+type TypeMismatchError struct {
+	Operator Operator
+	// Type is the type of the incoming value, e.g. "string".
+	Type string
+	// Expected is the type of the base value when the two values are in different types, otherwise it is empty.
+	Expected string
+}
+
+func (e *TypeMismatchError) Error() string {
+	if e.Expected != "" {
+		return fmt.Sprintf("TypeMismatch: Expects incoming value to be '%s' type but was '%s'", e.Expected, e.Type)
+	}
+	return fmt.Sprintf("Operator '%s' does not support the incoming values in %s type.", e.Operator, e.Type)
+}
+
+// Is makes errors.Is(err, ErrOperatorTypeMismatch) report true for a TypeMismatchError.
+func (e *TypeMismatchError) Is(target error) bool {
+	return target == ErrOperatorTypeMismatch
+}
@@ -1,13 +1,6 @@
 // Package op provides operators used in filter evaluation.
 package op
 
-import (
-	"errors"
-	"fmt"
-	"reflect"
-	"strings"
-)
-
 // Operator defines the operation type in a filter rule.
 // Typically it is defined in a query string using LHS brackets like:
 //
@@ -48,7 +41,14 @@ var (
 		OpText: "gt",
 	}
 
-	// More operators can be added here.
+	// Fuzzy matches strings approximately, e.g. title[fuzzy]=Ap1 matches "App1".
+	Fuzzy = Operator{
+		Name:   "Fuzzy",
+		Symbol: "~",
+		OpText: "fuzzy",
+	}
+
+	// More operators can be registered via Register.
 
 	Unknown = Operator{}
 )
@@ -72,19 +72,10 @@ type ValueComparer interface {
 // Parse parses given text into Operation.
 // It returns the corresponding Operation object when parse succeed
 // and generates error when the operation is unrecognized.
+// Operators are looked up from the DefaultRegistry, so custom operators
+// registered via Register can be parsed as well.
 func Parse(text string) (Operator, error) {
-	switch strings.ToLower(text) {
-	case "":
-		return Equals, nil
-	case "like":
-		return Like, nil
-	case "lt":
-		return LessThan, nil
-	case "gt":
-		return GreaterThan, nil
-	default:
-		return Unknown, errors.New(fmt.Sprintf("Unrecognized operator type '%s'", text))
-	}
+	return DefaultRegistry.Parse(text)
 }
 
 // IsValidType returns whether a given object is valid for this operator.
@@ -92,21 +83,9 @@ func Parse(text string) (Operator, error) {
 //   1. When the operator is "Like", it only accepts value in string type.
 //   2. When the operator is "lt" or "gt", it accepts numbers or comparable objects, but no strings.
 //   3. etc.
+// The operator is looked up from the DefaultRegistry, see Registry.IsValidType for other registries.
 func (op Operator) IsValidType(value interface{}) bool {
-	switch op {
-	case Equals:
-		// 'Equals' operator is available for any type
-		return true
-	case Like:
-		// 'Like' operator only valid for string types
-		return isStringType(value)
-	case LessThan, GreaterThan:
-		// 'LessThan', 'GreaterThan' operators can accept either a number
-		// or an object which implements 'ValueComparer' interface.
-		return isNumberType(value) || isValueComparerType(value)
-	}
-
-	return false
+	return DefaultRegistry.IsValidType(op, value)
 }
 
 // Evaluate applies the incomingValue to the operator and baseValue.
@@ -115,45 +94,9 @@ func (op Operator) IsValidType(value interface{}) bool {
 //   The baseValue is typically set by a filter.Rule object,
 //   which is parsed from querystring like: age[lt]=10
 //   And the incomingValue is the value of the user's input, in this example the value is 5.
+// The operator is looked up from the DefaultRegistry, see Registry.Evaluate for other registries.
 func (op Operator) Evaluate(incomingValue interface{}, baseValue interface{}) (bool, error) {
-	// Make sure incomingValue is the same type with baseValue.
-	if reflect.ValueOf(incomingValue).Type() != reflect.ValueOf(baseValue).Type() {
-		return false, fmt.Errorf("TypeMismatch: Expects incoming value to be '%T' type but was '%T'", baseValue, incomingValue)
-	}
-	// Usually we have already verified the type of baseValue to be compatible to the operator.
-	// And we have already made sure the types of incomingValue and baseValue are the same.
-	// So, the type of incomingValue must be compatible with the operator.
-	// However, we do another type check here to make sure for the assumption.
-	if !op.IsValidType(incomingValue) {
-		return false, fmt.Errorf("Operator '%s' does not support the incoming values in %T type.", op, incomingValue)
-	}
-
-	switch op {
-	case Equals:
-		return incomingValue == baseValue, nil
-	case Like:
-		return strings.Contains(incomingValue.(string), baseValue.(string)), nil
-	case LessThan:
-		if isNumberType(baseValue) {
-			// Assume all number types are 'int'.
-			// TODO: adapt to all the number types.
-			return incomingValue.(int) < baseValue.(int), nil
-		} else if isValueComparerType(baseValue) {
-			return incomingValue.(ValueComparer).LessThan(baseValue.(ValueComparer)), nil
-		} else {
-			panic("Should never fall into this branch.")
-		}
-	case GreaterThan:
-		if isNumberType(baseValue) {
-			return incomingValue.(int) > baseValue.(int), nil
-		} else if isValueComparerType(baseValue) {
-			return incomingValue.(ValueComparer).GreaterThan(baseValue.(ValueComparer)), nil
-		} else {
-			panic("Should never fall into this branch.")
-		}
-	default:
-		return false, fmt.Errorf("Unknown operator '%s'", op)
-	}
+	return DefaultRegistry.Evaluate(op, incomingValue, baseValue)
 }
 
 // String returns a text representation of this object.
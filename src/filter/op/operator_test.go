@@ -1,6 +1,7 @@
 package op
 
 import (
+	"errors"
 	"fmt"
 	"testing"
 )
@@ -52,6 +53,7 @@ func TestParse(t *testing.T) {
 		{"LIKE", Like, ""},
 		{"lt", LessThan, ""},
 		{"gt", GreaterThan, ""},
+		{"fuzzy", Fuzzy, ""},
 		{"other", Unknown, "Unrecognized operator type 'other'"},
 	}
 
@@ -66,6 +68,9 @@ func TestParse(t *testing.T) {
 				if err.Error() != tt.errorMessage {
 					t.Errorf("Expect error message '%s' but got '%s'.", tt.errorMessage, err.Error())
 				}
+				if !errors.Is(err, ErrUnknownOperator) {
+					t.Errorf("Expect error to be ErrUnknownOperator but got %T.", err)
+				}
 			}
 		})
 	}
@@ -90,6 +95,9 @@ func TestIsValidType(t *testing.T) {
 		{Like, s, true},
 		{Like, v, false},
 		{Like, cv, false},
+		{Fuzzy, i, false},
+		{Fuzzy, s, true},
+		{Fuzzy, v, false},
 		{LessThan, i, true},
 		{LessThan, s, false},
 		{LessThan, v, false},
@@ -129,6 +137,9 @@ func TestEvaluate(t *testing.T) {
 		{Like, "abc", "abc", true, ""},
 		{Like, "abc", "abcde", false, ""},
 		{Like, 1, 2, false, "Operator 'Like' does not support the incoming values in int type."},
+		{Fuzzy, "App1", "Ap1", true, ""},
+		{Fuzzy, "App2", "Ap1", false, ""},
+		{Fuzzy, 1, 2, false, "Operator 'Fuzzy' does not support the incoming values in int type."},
 		{LessThan, 1, 2, true, ""},
 		{LessThan, 2, 1, false, ""},
 		{LessThan, Version{Major: 1, Minor: 0}, Version{Major: 1, Minor: 1}, false, "Operator 'LessThan' does not support the incoming values in op.Version type."},
@@ -151,6 +162,9 @@ func TestEvaluate(t *testing.T) {
 				if err.Error() != tt.errorMessage {
 					t.Errorf("Expect error message '%s' but got '%s'.", tt.errorMessage, err.Error())
 				}
+				if !errors.Is(err, ErrOperatorTypeMismatch) {
+					t.Errorf("Expect error to be ErrOperatorTypeMismatch but got %T.", err)
+				}
 			}
 		})
 	}
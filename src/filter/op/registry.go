@@ -0,0 +1,213 @@
+package op
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/zzn2/demo/appstore/fuzzy"
+)
+
+// TypePredicate tells whether a value is acceptable by an operator.
+type TypePredicate func(value interface{}) bool
+
+// EvaluateFunc applies an operator to the incomingValue and the baseValue.
+// It is only called after both values have been verified to be of the same type
+// and accepted by the TypePredicate of the operator.
+type EvaluateFunc func(incomingValue interface{}, baseValue interface{}) (bool, error)
+
+// Entry is an operator registered in a Registry together with its behaviors.
+type Entry struct {
+	Operator    Operator
+	IsValidType TypePredicate
+	Evaluate    EvaluateFunc
+}
+
+// Registry holds the operators which can be parsed and evaluated.
+// It is safe for concurrent use.
+type Registry struct {
+	mu      sync.RWMutex
+	entries map[string]Entry
+}
+
+// DefaultRegistry is the registry used by Parse, Operator.IsValidType and Operator.Evaluate.
+// It contains the built-in operators (eq, like, fuzzy, lt, gt).
+var DefaultRegistry = NewRegistry()
+
+func init() {
+	registerBuiltins(DefaultRegistry)
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{entries: make(map[string]Entry)}
+}
+
+// NewBuiltinRegistry creates a Registry with the built-in operators,
+// which could be extended with operators for a specific use without affecting the DefaultRegistry.
+func NewBuiltinRegistry() *Registry {
+	r := NewRegistry()
+	registerBuiltins(r)
+	return r
+}
+
+// Register adds a new operator into the DefaultRegistry.
+// See Registry.Register for details.
+func Register(operator Operator, isValidType TypePredicate, evaluate EvaluateFunc) error {
+	return DefaultRegistry.Register(operator, isValidType, evaluate)
+}
+
+// Register adds a new operator into the registry.
+// The operator is identified by its OpText (case insensitive), which is the text used in LHS brackets, e.g. name[like]=Tom.
+// It returns error if the operator is malformed or an operator with the same OpText was already registered.
+func (r *Registry) Register(operator Operator, isValidType TypePredicate, evaluate EvaluateFunc) error {
+	if operator.Name == "" || operator.OpText == "" {
+		return errors.New("Failed to register operator: Name and OpText must not be empty")
+	}
+	if isValidType == nil || evaluate == nil {
+		return fmt.Errorf("Failed to register operator '%s': Type predicate and evaluate function must not be nil", operator)
+	}
+
+	key := strings.ToLower(operator.OpText)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.entries[key]; exists {
+		return fmt.Errorf("Failed to register operator '%s': Operator '%s' already registered", operator, operator.OpText)
+	}
+	r.entries[key] = Entry{
+		Operator:    operator,
+		IsValidType: isValidType,
+		Evaluate:    evaluate,
+	}
+	return nil
+}
+
+// Parse parses given text into Operator by looking up the registered operators.
+// Empty text is treated as Equals. Equals could not be given explicitly, i.e. name[eq]=Tom is rejected,
+// since precise matches are only represented without LHS brackets.
+func (r *Registry) Parse(text string) (Operator, error) {
+	key := strings.ToLower(text)
+	switch key {
+	case Equals.OpText:
+		return Unknown, &UnknownOperatorError{text}
+	case "":
+		key = Equals.OpText
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	entry, ok := r.entries[key]
+	if !ok {
+		return Unknown, &UnknownOperatorError{text}
+	}
+	return entry.Operator, nil
+}
+
+// IsValidType returns whether a given object is valid for the operator, see Operator.IsValidType.
+// It returns false if the operator is not registered in the registry.
+func (r *Registry) IsValidType(operator Operator, value interface{}) bool {
+	entry, ok := r.lookup(operator)
+	if !ok {
+		return false
+	}
+
+	return entry.IsValidType(value)
+}
+
+// Evaluate applies the incomingValue to the operator and baseValue, see Operator.Evaluate.
+// It returns UnknownOperatorError if the operator is not registered in the registry.
+func (r *Registry) Evaluate(operator Operator, incomingValue interface{}, baseValue interface{}) (bool, error) {
+	// Make sure incomingValue is the same type with baseValue.
+	if reflect.ValueOf(incomingValue).Type() != reflect.ValueOf(baseValue).Type() {
+		return false, &TypeMismatchError{operator, fmt.Sprintf("%T", incomingValue), fmt.Sprintf("%T", baseValue)}
+	}
+
+	entry, ok := r.lookup(operator)
+	if !ok {
+		return false, &UnknownOperatorError{operator.OpText}
+	}
+
+	// Usually we have already verified the type of baseValue to be compatible to the operator.
+	// And we have already made sure the types of incomingValue and baseValue are the same.
+	// So, the type of incomingValue must be compatible with the operator.
+	// However, we do another type check here to make sure for the assumption.
+	if !entry.IsValidType(incomingValue) {
+		return false, &TypeMismatchError{Operator: operator, Type: fmt.Sprintf("%T", incomingValue)}
+	}
+
+	return entry.Evaluate(incomingValue, baseValue)
+}
+
+// Operators returns all the registered operators.
+func (r *Registry) Operators() []Operator {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	result := make([]Operator, 0, len(r.entries))
+	for _, entry := range r.entries {
+		result = append(result, entry.Operator)
+	}
+	return result
+}
+
+// lookup gets the registered entry of the given operator.
+// The operator must be exactly the registered one, not only have the same OpText.
+func (r *Registry) lookup(operator Operator) (Entry, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	entry, ok := r.entries[strings.ToLower(operator.OpText)]
+	if !ok || entry.Operator != operator {
+		return Entry{}, false
+	}
+	return entry, true
+}
+
+// registerBuiltins registers the built-in operators into the given registry.
+func registerBuiltins(r *Registry) {
+	mustRegister := func(operator Operator, isValidType TypePredicate, evaluate EvaluateFunc) {
+		if err := r.Register(operator, isValidType, evaluate); err != nil {
+			panic(err)
+		}
+	}
+
+	// 'Equals' operator is available for any type
+	mustRegister(Equals, func(value interface{}) bool {
+		return true
+	}, func(incomingValue interface{}, baseValue interface{}) (bool, error) {
+		return incomingValue == baseValue, nil
+	})
+
+	// 'Like' operator only valid for string types
+	mustRegister(Like, isStringType, func(incomingValue interface{}, baseValue interface{}) (bool, error) {
+		return strings.Contains(incomingValue.(string), baseValue.(string)), nil
+	})
+
+	// 'Fuzzy' operator only valid for string types
+	mustRegister(Fuzzy, isStringType, func(incomingValue interface{}, baseValue interface{}) (bool, error) {
+		return fuzzy.Match(incomingValue.(string), baseValue.(string)), nil
+	})
+
+	// 'LessThan', 'GreaterThan' operators can accept either a number
+	// or an object which implements 'ValueComparer' interface.
+	isComparable := func(value interface{}) bool {
+		return isNumberType(value) || isValueComparerType(value)
+	}
+
+	mustRegister(LessThan, isComparable, func(incomingValue interface{}, baseValue interface{}) (bool, error) {
+		if isNumberType(baseValue) {
+			// Assume all number types are 'int'.
+			// TODO: adapt to all the number types.
+			return incomingValue.(int) < baseValue.(int), nil
+		}
+		return incomingValue.(ValueComparer).LessThan(baseValue.(ValueComparer)), nil
+	})
+
+	mustRegister(GreaterThan, isComparable, func(incomingValue interface{}, baseValue interface{}) (bool, error) {
+		if isNumberType(baseValue) {
+			return incomingValue.(int) > baseValue.(int), nil
+		}
+		return incomingValue.(ValueComparer).GreaterThan(baseValue.(ValueComparer)), nil
+	})
+}
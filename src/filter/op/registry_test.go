@@ -0,0 +1,149 @@
+package op
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+var startsWith = Operator{
+	Name:   "StartsWith",
+	Symbol: "^=",
+	OpText: "sw",
+}
+
+func evaluateStartsWith(incomingValue interface{}, baseValue interface{}) (bool, error) {
+	return strings.HasPrefix(incomingValue.(string), baseValue.(string)), nil
+}
+
+func TestRegistryRegister(t *testing.T) {
+	r := NewRegistry()
+
+	if err := r.Register(startsWith, isStringType, evaluateStartsWith); err != nil {
+		t.Fatalf("Should not have error but error '%s' occurred.", err.Error())
+	}
+
+	var tests = []struct {
+		operator     Operator
+		errorMessage string
+	}{
+		{startsWith, "Failed to register operator 'StartsWith': Operator 'sw' already registered"},
+		{Operator{Name: "StartsWith2", OpText: "SW"}, "Failed to register operator 'StartsWith2': Operator 'SW' already registered"},
+		{Operator{Name: "NoOpText"}, "Failed to register operator: Name and OpText must not be empty"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.operator.Name, func(t *testing.T) {
+			err := r.Register(tt.operator, isStringType, evaluateStartsWith)
+			if err == nil || err.Error() != tt.errorMessage {
+				t.Errorf("Expect error message '%s' but got '%v'.", tt.errorMessage, err)
+			}
+		})
+	}
+
+	err := r.Register(Operator{Name: "NilFuncs", OpText: "nil"}, nil, nil)
+	if err == nil {
+		t.Errorf("Expect error when registering operator without functions.")
+	}
+}
+
+func TestRegistryParse(t *testing.T) {
+	r := NewBuiltinRegistry()
+	r.Register(startsWith, isStringType, evaluateStartsWith)
+
+	var tests = []struct {
+		input        string
+		want         Operator
+		errorMessage string
+	}{
+		{"", Equals, ""},
+		{"eq", Unknown, "Unrecognized operator type 'eq'"},
+		{"like", Like, ""},
+		{"sw", startsWith, ""},
+		{"SW", startsWith, ""},
+		{"other", Unknown, "Unrecognized operator type 'other'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			op, err := r.Parse(tt.input)
+			if op != tt.want {
+				t.Errorf("Expect '%s' but got '%s'.", tt.want, op)
+			}
+			if err != nil && err.Error() != tt.errorMessage {
+				t.Errorf("Expect error message '%s' but got '%s'.", tt.errorMessage, err.Error())
+			}
+		})
+	}
+
+	if len(r.Operators()) != 6 {
+		t.Errorf("Expect 6 operators registered but got %d.", len(r.Operators()))
+	}
+}
+
+func TestCustomOperator(t *testing.T) {
+	notLike := Operator{Name: "NotLike", Symbol: "!like", OpText: "nlike"}
+	err := Register(notLike, isStringType, func(incomingValue interface{}, baseValue interface{}) (bool, error) {
+		return !strings.Contains(incomingValue.(string), baseValue.(string)), nil
+	})
+	if err != nil {
+		t.Fatalf("Should not have error but error '%s' occurred.", err.Error())
+	}
+
+	parsed, err := Parse("nlike")
+	if err != nil || parsed != notLike {
+		t.Fatalf("Expect '%s' but got '%s' with error '%v'.", notLike, parsed, err)
+	}
+	if parsed.IsValidType(1) {
+		t.Errorf("Expect 'NotLike' to reject int values.")
+	}
+
+	res, err := parsed.Evaluate("abc", "x")
+	if !res || err != nil {
+		t.Errorf("Expect to be 'true' but got '%v' with error '%v'.", res, err)
+	}
+}
+
+func TestRegistryEvaluate(t *testing.T) {
+	r := NewBuiltinRegistry()
+	r.Register(startsWith, isStringType, evaluateStartsWith)
+
+	if !r.IsValidType(startsWith, "abc") || r.IsValidType(startsWith, 1) {
+		t.Errorf("Expect 'StartsWith' to accept only string values in its registry.")
+	}
+	res, err := r.Evaluate(startsWith, "abc", "ab")
+	if !res || err != nil {
+		t.Errorf("Expect to be 'true' but got '%v' with error '%v'.", res, err)
+	}
+
+	// The operator is not registered in the DefaultRegistry.
+	if startsWith.IsValidType("abc") {
+		t.Errorf("Expect 'StartsWith' to be invalid in the DefaultRegistry.")
+	}
+	if _, err := startsWith.Evaluate("abc", "ab"); err == nil || err.Error() != "Unrecognized operator type 'sw'" {
+		t.Errorf("Expect error message 'Unrecognized operator type 'sw'' but got '%v'.", err)
+	}
+}
+
+func TestRegistryConcurrentUse(t *testing.T) {
+	r := NewBuiltinRegistry()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			r.Register(Operator{Name: fmt.Sprintf("Op%d", i), OpText: fmt.Sprintf("op%d", i)}, isStringType, evaluateStartsWith)
+		}(i)
+		go func() {
+			defer wg.Done()
+			r.Parse("like")
+		}()
+	}
+	wg.Wait()
+
+	if len(r.Operators()) != 55 {
+		t.Errorf("Expect 55 operators registered but got %d.", len(r.Operators()))
+	}
+}
@@ -0,0 +1,70 @@
+package filter
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Field names could be paths separated by dots to refer to the fields of nested structs,
+// e.g. "maintainers.email" refers to the email of every maintainer of an app.
+// Slices along the path are stepped into, so a path could refer to many values, or none for an empty slice.
+
+// fieldTypeByPath gets the type of the field at the path in the given struct type.
+// It returns false if any field on the path does not exist.
+func fieldTypeByPath(t reflect.Type, path string) (reflect.Type, bool) {
+	names := strings.Split(path, ".")
+	for i, name := range names {
+		if i > 0 {
+			for t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Ptr {
+				t = t.Elem()
+			}
+		}
+		if t.Kind() != reflect.Struct {
+			return nil, false
+		}
+		field, ok := t.FieldByNameFunc(func(fieldName string) bool {
+			return strings.EqualFold(name, fieldName)
+		})
+		if !ok {
+			return nil, false
+		}
+		t = field.Type
+	}
+	return t, true
+}
+
+// fieldValuesByPath gets the values of the field at the path in the given object.
+func fieldValuesByPath(v interface{}, path string) []interface{} {
+	values := []reflect.Value{reflect.ValueOf(v)}
+	for i, name := range strings.Split(path, ".") {
+		next := make([]reflect.Value, 0, len(values))
+		for _, value := range values {
+			if i > 0 {
+				value = reflect.Indirect(value)
+			}
+			if i > 0 && (value.Kind() == reflect.Slice || value.Kind() == reflect.Array) {
+				for j := 0; j < value.Len(); j++ {
+					next = append(next, reflect.Indirect(value.Index(j)))
+				}
+			} else {
+				next = append(next, value)
+			}
+		}
+
+		values = values[:0]
+		for _, value := range next {
+			field := value.FieldByNameFunc(func(fieldName string) bool {
+				return strings.EqualFold(name, fieldName)
+			})
+			if field.IsValid() {
+				values = append(values, field)
+			}
+		}
+	}
+
+	result := make([]interface{}, 0, len(values))
+	for _, value := range values {
+		result = append(result, value.Interface())
+	}
+	return result
+}
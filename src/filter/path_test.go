@@ -0,0 +1,61 @@
+package filter
+
+import (
+	"testing"
+)
+
+type Contact struct {
+	Name  string
+	Email string
+}
+
+type Team struct {
+	Name    string
+	Lead    Contact
+	Members []Contact
+}
+
+func TestMatchPath(t *testing.T) {
+	team := Team{
+		Name:    "Team1",
+		Lead:    Contact{Name: "Alice", Email: "alice@corp.com"},
+		Members: []Contact{{Name: "Bob", Email: "bob@hotmail.com"}, {Name: "Carol", Email: "carol@corp.com"}},
+	}
+
+	var tests = []struct {
+		input          string
+		obj            Team
+		expected       bool
+		expectedErrMsg string
+	}{
+		{"lead.email=alice@corp.com", team, true, ""},
+		{"Lead.Name[like]=Bob", team, false, ""},
+		{"members.email[like]=@corp.com", team, true, ""},
+		{"members.name=Bob", team, true, ""},
+		{"members.name=Dave", team, false, ""},
+		{"members.email[like]=@corp.com", Team{Name: "Team2"}, false, ""},
+		{"members.phone=1", team, false, "Failed to create rule: Field with name 'members.phone' does not exist."},
+		{"name.first=Team1", team, false, "Failed to create rule: Field with name 'name.first' does not exist."},
+		{"members=Bob", team, false, "Failed to create rule: Unable to parse 'Bob' into given type ''"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			rule, err := ParseRule(tt.input, Team{})
+			if err != nil {
+				if err.Error() != tt.expectedErrMsg {
+					t.Errorf("Expect err to be '%s' but got '%s'.", tt.expectedErrMsg, err.Error())
+				}
+				return
+			}
+
+			matched, err := rule.Match(tt.obj)
+			if err != nil {
+				t.Fatalf("Should not have error but error '%s' occurred.", err.Error())
+			}
+			if matched != tt.expected {
+				t.Errorf("Expect '%s' to match '%v' to be %t but got %t.", tt.input, tt.obj, tt.expected, matched)
+			}
+		})
+	}
+}
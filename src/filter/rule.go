@@ -23,6 +23,8 @@ type Rule struct {
 	FieldName string
 	Op        op.Operator
 	Value     interface{}
+	// registry is where Op is looked up, nil for op.DefaultRegistry.
+	registry *op.Registry
 }
 
 var (
@@ -40,29 +42,60 @@ var (
 //    age[gt]=25       -> age > 25
 //
 func NewRule(nameAndOp string, value string, applyToObj interface{}) (Rule, error) {
-	name, operator, error := getNameAndOp(nameAndOp)
-	if error != nil {
-		return Rule{}, error
+	return newRule(nameAndOp, value, applyToObj, nil)
+}
+
+// NewRuleWith creates a new instance of Rule the same as NewRule, but looks up the operator in the registry
+// instead of op.DefaultRegistry, e.g. to support operators which are not part of the public query language.
+func NewRuleWith(registry *op.Registry, nameAndOp string, value string, applyToObj interface{}) (Rule, error) {
+	return newRule(nameAndOp, value, applyToObj, registry)
+}
+
+// newRule creates a new instance of Rule with the operator looked up in the registry, or op.DefaultRegistry if nil.
+func newRule(nameAndOp string, value string, applyToObj interface{}, registry *op.Registry) (Rule, error) {
+	name, operator, err := getNameAndOp(nameAndOp, registryOrDefault(registry))
+	var filterErr *Error
+	if errors.As(err, &filterErr) {
+		return Rule{}, err
+	} else if err != nil {
+		return Rule{}, &Error{Field: name, Param: nameAndOp, Message: err.Error(), Err: err}
 	}
 
-	field := getFieldByName(applyToObj, name)
-	if !field.IsValid() {
-		return Rule{}, fmt.Errorf("Failed to create rule: Field with name '%s' does not exist.", name)
+	fieldType, ok := fieldTypeByPath(reflect.TypeOf(applyToObj), name)
+	if !ok {
+		return Rule{}, &Error{
+			Kind:    ErrUnknownField,
+			Field:   name,
+			Param:   nameAndOp,
+			Message: fmt.Sprintf("Failed to create rule: Field with name '%s' does not exist.", name),
+		}
 	}
 
-	parsedValue, err := parseText(value, field.Type())
+	parsedValue, err := parseText(value, fieldType)
 	if err != nil {
-		return Rule{}, fmt.Errorf("Failed to create rule: %w", err)
+		return Rule{}, &Error{
+			Kind:    ErrInvalidValue,
+			Field:   name,
+			Param:   nameAndOp,
+			Message: fmt.Sprintf("Failed to create rule: %s", err),
+			Err:     err,
+		}
 	}
 
-	if !operator.IsValidType(parsedValue) {
-		return Rule{}, fmt.Errorf("Failed to create rule: Type '%T' does not support '%s' operator", parsedValue, operator)
+	if !registryOrDefault(registry).IsValidType(operator, parsedValue) {
+		return Rule{}, &Error{
+			Field:   name,
+			Param:   nameAndOp,
+			Message: fmt.Sprintf("Failed to create rule: Type '%T' does not support '%s' operator", parsedValue, operator),
+			Err:     &op.TypeMismatchError{Operator: operator, Type: fmt.Sprintf("%T", parsedValue)},
+		}
 	}
 
 	return Rule{
 		FieldName: name,
 		Op:        operator,
 		Value:     parsedValue,
+		registry:  registry,
 	}, nil
 }
 
@@ -79,14 +112,21 @@ func ParseRule(text string, applyToObj interface{}) (Rule, error) {
 }
 
 // Match checks whether the given obj satisfies the rule.
+// When the field name is a path which refers to many values, e.g. "maintainers.email",
+// the obj satisfies the rule if any of the values does, see fieldValuesByPath.
 func (r Rule) Match(obj interface{}) (bool, error) {
-	field := r.getField(obj).Interface()
-	return r.Evaluate(field)
+	for _, value := range fieldValuesByPath(obj, r.FieldName) {
+		matched, err := r.Evaluate(value)
+		if err != nil || matched {
+			return matched, err
+		}
+	}
+	return false, nil
 }
 
 // Evaluate evaluates whether a given value satisfies the rule.
 func (r Rule) Evaluate(value interface{}) (bool, error) {
-	succeed, err := r.Op.Evaluate(value, r.Value)
+	succeed, err := registryOrDefault(r.registry).Evaluate(r.Op, value, r.Value)
 	if err != nil {
 		return false, fmt.Errorf("Failed to evaluate '%s': %w", r, err)
 	}
@@ -105,8 +145,9 @@ func (r Rule) String() string {
 //     param[like]  returns param, op.Like
 //     param[gt]    returns param, op.GreaterThan
 //
+// The operator is looked up in the registry.
 // For unrecognized operator names or illegal input formats, return error.
-func getNameAndOp(text string) (name string, operator op.Operator, err error) {
+func getNameAndOp(text string, registry *op.Registry) (name string, operator op.Operator, err error) {
 	bytes := []byte(text)
 	if regexForPlainParam.Match(bytes) {
 		return text, op.Equals, nil
@@ -117,19 +158,22 @@ func getNameAndOp(text string) (name string, operator op.Operator, err error) {
 		//   [2]: like
 		// So, index 1 will be the param and index 2 will be the operator
 		match := regexForParamWithLhsBracket.FindStringSubmatch(text)
-		if operator, err = op.Parse(match[2]); err != nil {
+		if operator, err = registry.Parse(match[2]); err != nil {
 			return match[1], operator, err
 		} else {
 			return match[1], operator, nil
 		}
 	} else {
-		return "", op.Unknown, errors.New(fmt.Sprintf("Malformed input key format: '%s'", text))
+		return "", op.Unknown, &Error{Kind: ErrMalformedKey, Param: text, Message: fmt.Sprintf("Malformed input key format: '%s'", text)}
 	}
 }
 
-// getField gets the field from the given object with the name of this rule.
-func (r Rule) getField(v interface{}) reflect.Value {
-	return getFieldByName(v, r.FieldName)
+// registryOrDefault returns the registry, or op.DefaultRegistry if nil.
+func registryOrDefault(registry *op.Registry) *op.Registry {
+	if registry == nil {
+		return op.DefaultRegistry
+	}
+	return registry
 }
 
 // getFieldByName gets the field from given object with specific field name.
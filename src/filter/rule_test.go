@@ -91,6 +91,34 @@ func TestParseRule(t *testing.T) {
 	}
 }
 
+func TestNewRuleWith(t *testing.T) {
+	var u User
+	prefix := op.Operator{Name: "Prefix", Symbol: "^=", OpText: "prefix"}
+	registry := op.NewBuiltinRegistry()
+	registry.Register(prefix, func(value interface{}) bool {
+		_, ok := value.(string)
+		return ok
+	}, func(incomingValue interface{}, baseValue interface{}) (bool, error) {
+		return strings.HasPrefix(incomingValue.(string), baseValue.(string)), nil
+	})
+
+	rule, err := NewRuleWith(registry, "firstname[prefix]", "To", u)
+	if err != nil {
+		t.Fatalf("Should not have error but error '%s' occurred.", err.Error())
+	}
+	if match, err := rule.Match(User{FirstName: "Tom"}); !match || err != nil {
+		t.Errorf("Expect to match but got '%v' with error '%v'.", match, err)
+	}
+	if _, err := NewRuleWith(registry, "age[prefix]", "1", u); err == nil {
+		t.Errorf("Expect the type of the value to be checked against the registry.")
+	}
+
+	// The operator is not part of the DefaultRegistry.
+	if _, err := NewRule("firstname[prefix]", "To", u); err == nil || err.Error() != "Unrecognized operator type 'prefix'" {
+		t.Errorf("Expect error message 'Unrecognized operator type 'prefix'' but got '%v'.", err)
+	}
+}
+
 func TestEvaluate(t *testing.T) {
 	var u User
 	var tests = []struct {
@@ -232,7 +260,7 @@ func TestGetNameAndOp(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.testName, func(t *testing.T) {
-			name, op, err := getNameAndOp(tt.input)
+			name, op, err := getNameAndOp(tt.input, op.DefaultRegistry)
 			if name != tt.expectedName {
 				t.Errorf("Expect name to be '%s' but got '%s'.", tt.expectedName, name)
 			}
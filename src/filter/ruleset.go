@@ -2,9 +2,11 @@
 package filter
 
 import (
-	"errors"
 	"fmt"
+	"sort"
 	"strings"
+
+	"github.com/zzn2/demo/appstore/filter/op"
 )
 
 // RuleSet consists a set of Rules.
@@ -14,14 +16,31 @@ type RuleSet struct {
 
 // Create accepts a map generated from query params and parse them
 // as a set of rules and build them inside the RuleSet.
+// The rules are added in the order of the keys so that the same query always results in the same RuleSet.
 func CreateRuleSet(queryParams map[string][]string, applyToObj interface{}) (RuleSet, error) {
+	return createRuleSet(queryParams, applyToObj, nil)
+}
+
+// createRuleSet creates a RuleSet the same as CreateRuleSet, with the operators looked up in the registry, see newRule.
+func createRuleSet(queryParams map[string][]string, applyToObj interface{}, registry *op.Registry) (RuleSet, error) {
 	rs := RuleSet{}
-	for key, value := range queryParams {
+	keys := make([]string, 0, len(queryParams))
+	for key := range queryParams {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := queryParams[key]
 		if len(value) > 1 {
-			return rs, errors.New(fmt.Sprintf("Key '%s' appeared multiple times with values of '%s'. Currently this case is not unsupported.", key, strings.Join(value, ", ")))
+			return rs, &Error{
+				Kind:    ErrDuplicateKey,
+				Param:   key,
+				Message: fmt.Sprintf("Key '%s' appeared multiple times with values of '%s'. Currently this case is not unsupported.", key, strings.Join(value, ", ")),
+			}
 		}
 
-		rule, err := NewRule(key, value[0], applyToObj)
+		rule, err := newRule(key, value[0], applyToObj, registry)
 		if err != nil {
 			return rs, err
 		}
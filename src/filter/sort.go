@@ -0,0 +1,133 @@
+package filter
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/zzn2/demo/appstore/filter/op"
+)
+
+// SortKey describes how to order objects by one of their fields.
+// Typically it is defined as text like:
+//
+//    title     -> order by title ascending
+//    -version  -> order by version descending
+//
+type SortKey struct {
+	FieldName  string
+	Descending bool
+}
+
+// ParseSortKey parses text representation (samples listed in SortKey) into new instance of SortKey.
+// It returns error if the field does not exist in applyToObj or its type could not be ordered.
+func ParseSortKey(text string, applyToObj interface{}) (SortKey, error) {
+	key := SortKey{FieldName: text}
+	if strings.HasPrefix(text, "-") {
+		key = SortKey{FieldName: text[1:], Descending: true}
+	}
+
+	field := getFieldByName(applyToObj, key.FieldName)
+	if !field.IsValid() {
+		return SortKey{}, &Error{
+			Kind:    ErrUnknownField,
+			Field:   key.FieldName,
+			Param:   text,
+			Message: fmt.Sprintf("Failed to create sort key: Field with name '%s' does not exist.", key.FieldName),
+		}
+	}
+	if !isOrderable(field.Type()) {
+		return SortKey{}, &Error{
+			Kind:    ErrUnorderedField,
+			Field:   key.FieldName,
+			Param:   text,
+			Message: fmt.Sprintf("Failed to create sort key: Type '%s' of field '%s' could not be ordered.", field.Type(), key.FieldName),
+		}
+	}
+
+	return key, nil
+}
+
+// ParseSortKeys parses a list of texts into sort keys.
+func ParseSortKeys(texts []string, applyToObj interface{}) ([]SortKey, error) {
+	keys := make([]SortKey, 0, len(texts))
+	for _, text := range texts {
+		key, err := ParseSortKey(text, applyToObj)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// String returns the text representation of the sort key, which could be parsed by ParseSortKey.
+func (k SortKey) String() string {
+	if k.Descending {
+		return "-" + k.FieldName
+	}
+	return k.FieldName
+}
+
+// SortSlice sorts the given slice of structs by the given keys.
+// Earlier keys take precedence. Objects equal by all the keys keep their original order.
+func SortSlice(slice interface{}, keys []SortKey) {
+	if len(keys) == 0 {
+		return
+	}
+
+	v := reflect.ValueOf(slice)
+	sort.SliceStable(slice, func(i, j int) bool {
+		for _, key := range keys {
+			a := getFieldByName(v.Index(i).Interface(), key.FieldName)
+			b := getFieldByName(v.Index(j).Interface(), key.FieldName)
+			c := compareValues(a, b)
+			if c == 0 {
+				continue
+			}
+			if key.Descending {
+				return c > 0
+			}
+			return c < 0
+		}
+		return false
+	})
+}
+
+// isOrderable tells whether values of the given type could be compared by compareValues.
+func isOrderable(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return t.Implements(reflect.TypeOf((*op.ValueComparer)(nil)).Elem())
+}
+
+// compareValues returns -1 if a < b, 1 if a > b, otherwise 0.
+func compareValues(a reflect.Value, b reflect.Value) int {
+	switch a.Kind() {
+	case reflect.String:
+		return strings.Compare(a.String(), b.String())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return compareOrdered(a.Int() < b.Int(), a.Int() > b.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return compareOrdered(a.Uint() < b.Uint(), a.Uint() > b.Uint())
+	}
+
+	if comparer, ok := a.Interface().(op.ValueComparer); ok {
+		return compareOrdered(comparer.LessThan(b.Interface()), comparer.GreaterThan(b.Interface()))
+	}
+	return 0
+}
+
+func compareOrdered(less bool, greater bool) int {
+	if less {
+		return -1
+	} else if greater {
+		return 1
+	}
+	return 0
+}
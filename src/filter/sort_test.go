@@ -0,0 +1,72 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/zzn2/demo/appstore/semver"
+)
+
+func TestParseSortKey(t *testing.T) {
+	var u User
+	var tests = []struct {
+		input          string
+		expectedKey    SortKey
+		expectedErrMsg string
+	}{
+		{"firstname", SortKey{FieldName: "firstname"}, ""},
+		{"-version", SortKey{FieldName: "version", Descending: true}, ""},
+		{"-age", SortKey{FieldName: "age", Descending: true}, ""},
+		{"name", SortKey{}, "Failed to create sort key: Field with name 'name' does not exist."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			key, err := ParseSortKey(tt.input, u)
+			if key != tt.expectedKey {
+				t.Errorf("Expected to be '%v' but got '%v'", tt.expectedKey, key)
+			}
+			if err != nil && err.Error() != tt.expectedErrMsg {
+				t.Errorf("Expected to have error '%s' but got '%s'", tt.expectedErrMsg, err.Error())
+			}
+			if err == nil && key.String() != tt.input {
+				t.Errorf("Expected String() to be '%s' but got '%s'", tt.input, key.String())
+			}
+		})
+	}
+}
+
+func TestParseSortKey_NotOrderable(t *testing.T) {
+	type withSlice struct {
+		Tags []string
+	}
+
+	_, err := ParseSortKey("tags", withSlice{})
+	expectedErrMsg := "Failed to create sort key: Type '[]string' of field 'tags' could not be ordered."
+	if err == nil || err.Error() != expectedErrMsg {
+		t.Errorf("Expected to have error '%s' but got '%v'", expectedErrMsg, err)
+	}
+}
+
+func TestSortSlice(t *testing.T) {
+	users := []User{
+		{FirstName: "Tom", Age: 20, Version: semver.Version{Major: 1}},
+		{FirstName: "Amy", Age: 30, Version: semver.Version{Minor: 2}},
+		{FirstName: "Tom", Age: 25, Version: semver.Version{Patch: 3}},
+	}
+
+	SortSlice(users, []SortKey{{FieldName: "firstname"}, {FieldName: "age", Descending: true}})
+	expected := []int{30, 25, 20}
+	for i, age := range expected {
+		if users[i].Age != age {
+			t.Errorf("Expected users[%d] to be %d years old but got %d", i, age, users[i].Age)
+		}
+	}
+
+	SortSlice(users, []SortKey{{FieldName: "version"}})
+	expected = []int{25, 30, 20}
+	for i, age := range expected {
+		if users[i].Age != age {
+			t.Errorf("Expected users[%d] to be %d years old but got %d", i, age, users[i].Age)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+// Package fuzzy provides approximate string matching based on edit distance and trigram similarity.
+// It is used to match misspelled texts, e.g. "Ap1" matches "App1".
+package fuzzy
+
+import (
+	"sort"
+	"strings"
+)
+
+// Distance returns the Levenshtein edit distance between a and b,
+// i.e. the minimum number of single character insertions, deletions or substitutions to change a into b.
+// The comparison is case insensitive.
+func Distance(a string, b string) int {
+	ra := []rune(strings.ToLower(a))
+	rb := []rune(strings.ToLower(b))
+
+	// Only keep two rows of the distance matrix.
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(rb)]
+}
+
+// Similarity returns the trigram similarity between a and b, ranged from 0 (nothing in common) to 1 (identical).
+// It is the number of shared trigrams divided by the number of distinct trigrams of both texts.
+// The comparison is case insensitive.
+func Similarity(a string, b string) float64 {
+	ta := trigrams(a)
+	tb := trigrams(b)
+	if len(ta) == 0 && len(tb) == 0 {
+		return 1
+	}
+
+	shared := 0
+	for t := range ta {
+		if tb[t] {
+			shared++
+		}
+	}
+
+	return float64(shared) / float64(len(ta)+len(tb)-shared)
+}
+
+// Match tells whether text approximately matches the pattern.
+// Texts match when they are within a small edit distance proportional to the length of the pattern,
+// or when they share most of their trigrams.
+func Match(text string, pattern string) bool {
+	return Distance(text, pattern) <= maxDistance(pattern, 4) || Similarity(text, pattern) >= 0.5
+}
+
+// Suggest returns at most n candidates which are close to the query, most similar first.
+// It uses a looser threshold than Match since it is aimed to answer "did you mean" questions.
+// Duplicated candidates are only returned once.
+func Suggest(query string, candidates []string, n int) []string {
+	type scored struct {
+		text       string
+		distance   int
+		similarity float64
+	}
+
+	seen := make(map[string]bool)
+	matches := make([]scored, 0)
+	for _, candidate := range candidates {
+		if seen[candidate] {
+			continue
+		}
+		seen[candidate] = true
+
+		s := scored{candidate, Distance(query, candidate), Similarity(query, candidate)}
+		if s.distance <= maxDistance(query, 3) || s.similarity >= 0.3 {
+			matches = append(matches, s)
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].distance != matches[j].distance {
+			return matches[i].distance < matches[j].distance
+		}
+		if matches[i].similarity != matches[j].similarity {
+			return matches[i].similarity > matches[j].similarity
+		}
+		return matches[i].text < matches[j].text
+	})
+
+	result := make([]string, 0, n)
+	for i := 0; i < len(matches) && i < n; i++ {
+		result = append(result, matches[i].text)
+	}
+	return result
+}
+
+// maxDistance returns the edit distance tolerated for the given text,
+// which is one edit per every divisor characters, and at least one.
+func maxDistance(text string, divisor int) int {
+	d := len([]rune(text)) / divisor
+	if d < 1 {
+		return 1
+	}
+	return d
+}
+
+// trigrams returns the set of trigrams of the lower cased text.
+// The text is padded with spaces so that short texts and word boundaries are also taken into account.
+func trigrams(text string) map[string]bool {
+	runes := []rune("  " + strings.ToLower(text) + " ")
+	result := make(map[string]bool)
+	for i := 0; i+3 <= len(runes); i++ {
+		result[string(runes[i:i+3])] = true
+	}
+	return result
+}
+
+func min(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
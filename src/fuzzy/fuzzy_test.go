@@ -0,0 +1,98 @@
+package fuzzy
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestDistance(t *testing.T) {
+	var tests = []struct {
+		a        string
+		b        string
+		expected int
+	}{
+		{"", "", 0},
+		{"App1", "App1", 0},
+		{"App1", "app1", 0},
+		{"Ap1", "App1", 1},
+		{"App1", "App2", 1},
+		{"kitten", "sitting", 3},
+		{"", "abc", 3},
+		{"日本語", "日本", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s-%s", tt.a, tt.b), func(t *testing.T) {
+			if d := Distance(tt.a, tt.b); d != tt.expected {
+				t.Errorf("Expected to be %d but got %d", tt.expected, d)
+			}
+			if d := Distance(tt.b, tt.a); d != tt.expected {
+				t.Errorf("Expected to be symmetric %d but got %d", tt.expected, d)
+			}
+		})
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	if s := Similarity("App1", "app1"); s != 1 {
+		t.Errorf("Expected to be 1 but got %f", s)
+	}
+	if s := Similarity("abc", "xyz"); s != 0 {
+		t.Errorf("Expected to be 0 but got %f", s)
+	}
+
+	close := Similarity("App3 with space in title", "App3 with spaces in title")
+	far := Similarity("App3 with space in title", "App1")
+	if close <= far {
+		t.Errorf("Expected %f to be greater than %f", close, far)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	var tests = []struct {
+		text     string
+		pattern  string
+		expected bool
+	}{
+		{"App1", "Ap1", true},
+		{"App1", "app1", true},
+		{"App2", "Ap1", false},
+		{"App3 with space in title", "app3 with spase in title", true},
+		{"App3 with space in title", "App1", false},
+		{"Random", "App1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s-%s", tt.text, tt.pattern), func(t *testing.T) {
+			if m := Match(tt.text, tt.pattern); m != tt.expected {
+				t.Errorf("Expected to be %v but got %v", tt.expected, m)
+			}
+		})
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	candidates := []string{"App1", "App2", "App1", "Another", "App3 with space in title"}
+
+	var tests = []struct {
+		query    string
+		n        int
+		expected []string
+	}{
+		{"Ap1", 5, []string{"App1"}},
+		{"App6", 5, []string{"App1", "App2"}},
+		{"App6", 1, []string{"App1"}},
+		{"App3 with space", 5, []string{"App3 with space in title"}},
+		{"Zzz", 5, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			result := Suggest(tt.query, candidates, tt.n)
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("Expected to be %v but got %v", tt.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zzn2/demo/appstore/auth"
+)
+
+// defaultIdempotencyWindow is how long an Idempotency-Key is remembered by default.
+const defaultIdempotencyWindow = 24 * time.Hour
+
+// idempotencyWindow is how long an Idempotency-Key is remembered, configured by APPSTORE_IDEMPOTENCY_WINDOW.
+var idempotencyWindow = defaultIdempotencyWindow
+
+// replayedHeaders are the response headers kept to replay a response.
+var replayedHeaders = []string{"Content-Type", "ETag", "Location"}
+
+// idempotentResponse is a response remembered for an Idempotency-Key.
+type idempotentResponse struct {
+	// requestHash is the hash of the request which the key was first used with.
+	requestHash [sha256.Size]byte
+	// done is false while the first request is still in progress.
+	done      bool
+	status    int
+	header    http.Header
+	body      []byte
+	expiresAt time.Time
+}
+
+// idempotencyCache remembers the responses by Idempotency-Key for a time window.
+type idempotencyCache struct {
+	mu        sync.Mutex
+	responses map[string]*idempotentResponse
+	window    time.Duration
+	clock     func() time.Time
+}
+
+func newIdempotencyCache(window time.Duration) *idempotencyCache {
+	return &idempotencyCache{
+		responses: make(map[string]*idempotentResponse),
+		window:    window,
+		clock:     time.Now,
+	}
+}
+
+// responseRecorder keeps a copy of the response body written through it.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// idempotent returns a middleware which makes requests with an Idempotency-Key header safe to retry.
+// The first response for a key is remembered together with the hash of the request, and replayed to the retries.
+// Reusing a key with a different request responds 422, while reusing a key whose first request is still in progress responds 409.
+// Responses of server errors are not remembered, so that the request could be retried.
+func idempotent(cache *idempotencyCache) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader("Idempotency-Key")
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		data, err := ioutil.ReadAll(c.Request.Body)
+		if err != nil {
+			respond(c, http.StatusBadRequest, responseBodyForErrorMessage("Failed to read request body: %s", err))
+			c.Abort()
+			return
+		}
+		c.Request.Body = ioutil.NopCloser(bytes.NewReader(data))
+		hash := requestHash(c.Request, data)
+		// Keys are chosen by clients, so each client has its own keys and never gets the responses of others.
+		scopedKey := idempotencyScope(c) + "\n" + key
+
+		existing, found := cache.reserve(scopedKey, hash)
+		switch {
+		case found && existing.requestHash != hash:
+			respond(c, http.StatusUnprocessableEntity, responseBodyForErrorMessage("Idempotency-Key '%s' has been used with a different request.", key))
+			c.Abort()
+			return
+		case found && !existing.done:
+			respond(c, http.StatusConflict, responseBodyForErrorMessage("A request with Idempotency-Key '%s' is still in progress.", key))
+			c.Abort()
+			return
+		case found:
+			for name, values := range existing.header {
+				c.Writer.Header()[name] = values
+			}
+			c.Header("Idempotent-Replayed", "true")
+			c.Status(existing.status)
+			c.Writer.Write(existing.body)
+			c.Abort()
+			return
+		}
+
+		// Release the reservation unless the response is remembered, even if the handler panics,
+		// otherwise the key would be reported in progress until it expires.
+		completed := false
+		defer func() {
+			if !completed {
+				cache.release(scopedKey)
+			}
+		}()
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		status := c.Writer.Status()
+		if status >= http.StatusInternalServerError {
+			return
+		}
+		header := make(http.Header)
+		for _, name := range replayedHeaders {
+			if value := c.Writer.Header().Get(name); value != "" {
+				header.Set(name, value)
+			}
+		}
+		cache.complete(scopedKey, status, header, recorder.body.Bytes())
+		completed = true
+	}
+}
+
+// idempotencyScope identifies the client of the request by its authenticated principal.
+// Anonymous requests share the same scope, which only happens when authentication is disabled.
+func idempotencyScope(c *gin.Context) string {
+	if value, ok := c.Get(principalKey); ok {
+		return value.(auth.Principal).ID
+	}
+	return ""
+}
+
+// reserve returns the response remembered for the key if found.
+// Otherwise it reserves the key for the request with the hash, which must be completed or released later.
+func (cache *idempotencyCache) reserve(key string, hash [sha256.Size]byte) (idempotentResponse, bool) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	now := cache.clock()
+	cache.prune(now)
+	if existing, ok := cache.responses[key]; ok {
+		return *existing, true
+	}
+
+	cache.responses[key] = &idempotentResponse{requestHash: hash, expiresAt: now.Add(cache.window)}
+	return idempotentResponse{}, false
+}
+
+// complete remembers the response for the reserved key.
+func (cache *idempotencyCache) complete(key string, status int, header http.Header, body []byte) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if response, ok := cache.responses[key]; ok {
+		response.done = true
+		response.status = status
+		response.header = header
+		response.body = append([]byte(nil), body...)
+	}
+}
+
+// release forgets the reserved key.
+func (cache *idempotencyCache) release(key string) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	delete(cache.responses, key)
+}
+
+// prune forgets the expired keys. The caller must hold cache.mu.
+func (cache *idempotencyCache) prune(now time.Time) {
+	for key, response := range cache.responses {
+		if response.done && !now.Before(response.expiresAt) {
+			delete(cache.responses, key)
+		}
+	}
+}
+
+// requestHash identifies a request by its method, path, content type, accepted media types and body.
+// Accept is included since the remembered response was negotiated for it.
+func requestHash(req *http.Request, body []byte) [sha256.Size]byte {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s\n%s\n%s\n", req.Method, req.URL.RequestURI(), req.Header.Get("Content-Type"), req.Header.Get("Accept"))
+	h.Write(body)
+
+	var sum [sha256.Size]byte
+	copy(sum[:], h.Sum(nil))
+	return sum
+}
+
+// idempotencyWindowFromEnv reads the Idempotency-Key window from APPSTORE_IDEMPOTENCY_WINDOW, e.g. "1h".
+// It returns defaultIdempotencyWindow when not set.
+func idempotencyWindowFromEnv() (time.Duration, error) {
+	text := os.Getenv("APPSTORE_IDEMPOTENCY_WINDOW")
+	if text == "" {
+		return defaultIdempotencyWindow, nil
+	}
+
+	window, err := time.ParseDuration(text)
+	if err != nil || window <= 0 {
+		return 0, fmt.Errorf("Bad format of APPSTORE_IDEMPOTENCY_WINDOW '%s'", text)
+	}
+	return window, nil
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zzn2/demo/appstore/auth"
+)
+
+func TestIdempotencyKey(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	ts := httptest.NewServer(setupServer())
+	defer ts.Close()
+
+	post := func(key string, data string) (*http.Response, string) {
+		req, _ := http.NewRequest("POST", ts.URL+"/v1/apps", strings.NewReader(data))
+		req.Header.Set("Content-Type", "application/x-yaml")
+		if key != "" {
+			req.Header.Set("Idempotency-Key", key)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Error occurred during POST /apps, detail: %e", err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		return resp, string(body)
+	}
+
+	setupStore()
+	first, firstBody := post("key-1", app1v1)
+	if first.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected status code to be 201 but got %d", first.StatusCode)
+	}
+
+	retry, retryBody := post("key-1", app1v1)
+	if retry.StatusCode != http.StatusCreated || retryBody != firstBody {
+		t.Errorf("Expected the original response replayed but got %d '%s'", retry.StatusCode, retryBody)
+	}
+	if retry.Header.Get("Idempotent-Replayed") != "true" || retry.Header.Get("ETag") != first.Header.Get("ETag") {
+		t.Errorf("Expected replayed headers but got %v", retry.Header)
+	}
+
+	resp, body := post("key-1", app2v1)
+	expected := `{"detail":"Idempotency-Key 'key-1' has been used with a different request.","status":422,"title":"Unprocessable Entity","type":"about:blank"}`
+	if resp.StatusCode != http.StatusUnprocessableEntity || body != expected {
+		t.Errorf("Expected 422 '%s' but got %d '%s'", expected, resp.StatusCode, body)
+	}
+
+	// Failed requests are replayed as well, since retrying them would fail the same way.
+	post("key-2", "title: App2")
+	resp, _ = post("key-2", "title: App2")
+	if resp.StatusCode != http.StatusBadRequest || resp.Header.Get("Idempotent-Replayed") != "true" {
+		t.Errorf("Expected 400 replayed but got %d", resp.StatusCode)
+	}
+
+	resp, _ = post("", app2v1)
+	if resp.StatusCode != http.StatusCreated || resp.Header.Get("Idempotent-Replayed") != "" {
+		t.Errorf("Expected 201 without Idempotency-Key but got %d", resp.StatusCode)
+	}
+}
+
+func TestIdempotencyCache(t *testing.T) {
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	cache := newIdempotencyCache(time.Hour)
+	cache.clock = func() time.Time { return now }
+	hash := requestHash(httptest.NewRequest("POST", "/v1/apps", nil), []byte("data"))
+
+	if _, found := cache.reserve("key", hash); found {
+		t.Fatalf("Expected key not found in an empty cache")
+	}
+	if existing, found := cache.reserve("key", hash); !found || existing.done {
+		t.Errorf("Expected key found in progress but got %v %v", existing, found)
+	}
+
+	cache.complete("key", http.StatusCreated, http.Header{}, []byte("body"))
+	now = now.Add(59 * time.Minute)
+	if existing, found := cache.reserve("key", hash); !found || !existing.done || string(existing.body) != "body" {
+		t.Errorf("Expected response found within the window but got %v %v", existing, found)
+	}
+
+	now = now.Add(time.Minute)
+	if _, found := cache.reserve("key", hash); found {
+		t.Errorf("Expected key forgotten after the window")
+	}
+
+	cache.release("key")
+	if _, found := cache.reserve("key", hash); found {
+		t.Errorf("Expected key forgotten after released")
+	}
+}
+
+func TestIdempotencyWindowFromEnv(t *testing.T) {
+	t.Setenv("APPSTORE_IDEMPOTENCY_WINDOW", "")
+	if window, err := idempotencyWindowFromEnv(); err != nil || window != defaultIdempotencyWindow {
+		t.Errorf("Expected default window but got %v %v", window, err)
+	}
+
+	t.Setenv("APPSTORE_IDEMPOTENCY_WINDOW", "90m")
+	if window, err := idempotencyWindowFromEnv(); err != nil || window != 90*time.Minute {
+		t.Errorf("Expected 90m but got %v %v", window, err)
+	}
+
+	t.Setenv("APPSTORE_IDEMPOTENCY_WINDOW", "forever")
+	if _, err := idempotencyWindowFromEnv(); err == nil || err.Error() != "Bad format of APPSTORE_IDEMPOTENCY_WINDOW 'forever'" {
+		t.Errorf("Expected error but got %v", err)
+	}
+}
+
+func TestIdempotencyKey_Isolation(t *testing.T) {
+	ts := newTestServer(t)
+
+	write := []auth.Scope{auth.ScopeAppsRead, auth.ScopeAppsWrite}
+	// API keys with the same name are still different clients.
+	alice, _, _ := apiKeys.Create("ci", "alice@random.com", write)
+	bob, _, _ := apiKeys.Create("ci", "bob@random.com", write)
+
+	post := func(token string, data string) (*http.Response, string) {
+		req, _ := http.NewRequest("POST", ts.URL+"/v1/apps", strings.NewReader(data))
+		req.Header.Set("Content-Type", "application/x-yaml")
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Idempotency-Key", "key-1")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Error occurred during POST /apps, detail: %e", err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		return resp, string(body)
+	}
+
+	if resp, _ := post(alice, app1v1); resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected status code to be 201 but got %d", resp.StatusCode)
+	}
+	resp, body := post(bob, app2v1)
+	if resp.StatusCode != http.StatusCreated || resp.Header.Get("Idempotent-Replayed") != "" || !strings.Contains(body, `"title":"App2"`) {
+		t.Errorf("Expected the key of another client not to be replayed but got %d '%s'", resp.StatusCode, body)
+	}
+}
+
+func TestIdempotent(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	router := gin.New()
+	router.Use(gin.RecoveryWithWriter(ioutil.Discard))
+	calls := 0
+	router.POST("/", idempotent(newIdempotencyCache(time.Hour)), func(c *gin.Context) {
+		calls++
+		if calls == 1 {
+			panic("boom")
+		}
+		c.String(http.StatusCreated, "created")
+	})
+
+	post := func(accept string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/", strings.NewReader("data"))
+		req.Header.Set("Idempotency-Key", "key-1")
+		req.Header.Set("Accept", accept)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := post("application/json"); w.Code != http.StatusInternalServerError {
+		t.Fatalf("Expected status code to be 500 but got %d", w.Code)
+	}
+	if w := post("application/json"); w.Code != http.StatusCreated || w.Body.String() != "created" {
+		t.Errorf("Expected the key to be released after the panic but got %d '%s'", w.Code, w.Body.String())
+	}
+
+	// The response was negotiated for the Accept header, so it could not be replayed for another one.
+	if w := post("application/x-yaml"); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected 422 for a different Accept header but got %d", w.Code)
+	}
+}
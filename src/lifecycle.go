@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultShutdownTimeout is the default max time for finishing in-flight requests and running the shutdown hooks.
+const defaultShutdownTimeout = 30 * time.Second
+
+// drainPeriod is how long the server keeps serving after a shutdown signal, while /readyz reports it is not ready,
+// so that load balancers (e.g. Kubernetes endpoints) stop sending new requests before the listener is closed.
+var drainPeriod time.Duration
+
+// shutdownTimeout is the max time for finishing in-flight requests and running the shutdown hooks.
+var shutdownTimeout = defaultShutdownTimeout
+
+// draining is set to 1 once the server starts shutting down.
+var draining int32
+
+// shutdownHook is a function registered by a subsystem to be run when the server shuts down.
+type shutdownHook struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+var (
+	shutdownHooksMu sync.Mutex
+	shutdownHooks   []shutdownHook
+)
+
+// onShutdown registers fn to be run after the server has stopped serving requests.
+// Hooks run in the order of registration. Registering a hook with the name of an existing one replaces it,
+// so that a subsystem could be set up more than once.
+func onShutdown(name string, fn func(ctx context.Context) error) {
+	shutdownHooksMu.Lock()
+	defer shutdownHooksMu.Unlock()
+
+	for i := range shutdownHooks {
+		if shutdownHooks[i].name == name {
+			shutdownHooks[i].fn = fn
+			return
+		}
+	}
+	shutdownHooks = append(shutdownHooks, shutdownHook{name, fn})
+}
+
+// runShutdownHooks runs all the hooks, even if some of them fail, and returns the first error.
+func runShutdownHooks(ctx context.Context) error {
+	shutdownHooksMu.Lock()
+	hooks := append([]shutdownHook(nil), shutdownHooks...)
+	shutdownHooksMu.Unlock()
+
+	var firstErr error
+	for _, hook := range hooks {
+		if err := hook.fn(ctx); err != nil {
+			log.Printf("Shutdown hook '%s' failed: %s", hook.name, err)
+			if firstErr == nil {
+				firstErr = fmt.Errorf("Shutdown hook '%s' failed: %w", hook.name, err)
+			}
+		}
+	}
+	return firstErr
+}
+
+// shutdownFromEnv loads the shutdown settings from the environment variables:
+//
+//    APPSTORE_SHUTDOWN_DRAIN    -> drain period before closing the listener, e.g. 10s, default to 0
+//    APPSTORE_SHUTDOWN_TIMEOUT  -> max time for in-flight requests and shutdown hooks, default to 30s
+func shutdownFromEnv() (drain time.Duration, timeout time.Duration, err error) {
+	drain, timeout = 0, defaultShutdownTimeout
+	if text := os.Getenv("APPSTORE_SHUTDOWN_DRAIN"); text != "" {
+		drain, err = time.ParseDuration(text)
+		if err != nil || drain < 0 {
+			return 0, 0, fmt.Errorf("Bad format of APPSTORE_SHUTDOWN_DRAIN '%s'", text)
+		}
+	}
+	if text := os.Getenv("APPSTORE_SHUTDOWN_TIMEOUT"); text != "" {
+		timeout, err = time.ParseDuration(text)
+		if err != nil || timeout <= 0 {
+			return 0, 0, fmt.Errorf("Bad format of APPSTORE_SHUTDOWN_TIMEOUT '%s'", text)
+		}
+	}
+	return drain, timeout, nil
+}
+
+// serve serves on the listener until ctx is done (e.g. on SIGTERM), then shuts down gracefully:
+//
+//    1. /readyz starts reporting 503, while requests are still served for drainPeriod
+//    2. the listener is closed, and in-flight requests are waited for
+//    3. the shutdown hooks are run, e.g. to flush the store
+//
+// Steps 2 and 3 share shutdownTimeout. Connections still open after it are closed forcibly,
+// but the hooks are run anyway so that nothing is lost.
+func serve(ctx context.Context, srv *http.Server, ln net.Listener) error {
+	atomic.StoreInt32(&draining, 0)
+	errc := make(chan error, 1)
+	go func() {
+		errc <- serveOn(srv, ln)
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Printf("Shutting down, draining for %s", drainPeriod)
+	atomic.StoreInt32(&draining, 1)
+	time.Sleep(drainPeriod)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	shutdownErr := srv.Shutdown(shutdownCtx)
+	if shutdownErr != nil {
+		log.Printf("Failed to finish in-flight requests: %s", shutdownErr)
+		srv.Close()
+	}
+	if err := <-errc; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Printf("Server stopped with error: %s", err)
+	}
+
+	hooksErr := runShutdownHooks(shutdownCtx)
+	if shutdownErr != nil {
+		return fmt.Errorf("Failed to finish in-flight requests: %w", shutdownErr)
+	}
+	return hooksErr
+}
+
+// healthz reports the server is alive.
+func healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// readyz reports whether the server accepts new requests, which it does not once it starts shutting down.
+func readyz(c *gin.Context) {
+	if atomic.LoadInt32(&draining) == 1 {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "draining"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
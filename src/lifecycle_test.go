@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zzn2/demo/appstore/app"
+	"gopkg.in/yaml.v2"
+)
+
+// startServer serves the router with a slow endpoint on a random port until the returned cancel is called.
+// The slow endpoint signals started when a request arrives, and responds after delay.
+func startServer(t *testing.T, delay time.Duration) (url string, started chan struct{}, cancel context.CancelFunc, done chan error) {
+	router := setupServer()
+	started = make(chan struct{}, 1)
+	router.GET("/slow", func(c *gin.Context) {
+		started <- struct{}{}
+		time.Sleep(delay)
+		c.String(http.StatusOK, "done")
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done = make(chan error, 1)
+	go func() {
+		done <- serve(ctx, newHTTPServer(router), ln)
+	}()
+	return "http://" + ln.Addr().String(), started, cancel, done
+}
+
+func restoreLifecycle(t *testing.T) {
+	drain, timeout := drainPeriod, shutdownTimeout
+	hooks := append([]shutdownHook(nil), shutdownHooks...)
+	file := dataFile
+	t.Cleanup(func() {
+		drainPeriod, shutdownTimeout, dataFile = drain, timeout, file
+		shutdownHooks = hooks
+	})
+}
+
+func TestGracefulShutdown(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	restoreLifecycle(t)
+	dataFile = filepath.Join(t.TempDir(), "store.yaml")
+	setupStore()
+	drainPeriod, shutdownTimeout = 300*time.Millisecond, 5*time.Second
+
+	hookRan := false
+	onShutdown("test", func(context.Context) error {
+		hookRan = true
+		return nil
+	})
+
+	url, started, cancel, done := startServer(t, 500*time.Millisecond)
+
+	if resp, err := http.Get(url + "/readyz"); err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected to be ready before shutdown but got %v, %v", resp, err)
+	}
+
+	// Changes made without persisting are flushed by the store hook.
+	var meta app.Meta
+	yaml.Unmarshal([]byte(app1v1), &meta)
+	if err := store.Add(meta); err != nil {
+		t.Fatalf("Should not have error but error '%s' occurred.", err.Error())
+	}
+
+	inFlight := make(chan *http.Response, 1)
+	go func() {
+		resp, err := http.Get(url + "/slow")
+		if err != nil {
+			t.Errorf("Should not have error but error '%s' occurred.", err.Error())
+		}
+		inFlight <- resp
+	}()
+	<-started
+	cancel()
+
+	// During the drain period requests are still served, but the server is not ready.
+	time.Sleep(100 * time.Millisecond)
+	resp, err := http.Get(url + "/readyz")
+	if err != nil {
+		t.Fatalf("Should not have error but error '%s' occurred.", err.Error())
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || strings.TrimSpace(string(body)) != `{"status":"draining"}` {
+		t.Errorf("Expected 503 while draining but got %d: %s", resp.StatusCode, body)
+	}
+
+	if err := <-done; err != nil {
+		t.Errorf("Should not have error but error '%s' occurred.", err.Error())
+	}
+	if resp := <-inFlight; resp == nil || resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected the in-flight request to finish but got %v", resp)
+	}
+	if !hookRan {
+		t.Errorf("Expected the shutdown hook to run")
+	}
+	if _, err := http.Get(url + "/healthz"); err == nil {
+		t.Errorf("Expected new connections to be refused after shutdown")
+	}
+
+	var flushed app.Store
+	if err := flushed.LoadFile(dataFile); err != nil {
+		t.Fatalf("Should not have error but error '%s' occurred.", err.Error())
+	}
+	if flushed.GetByTitleAndVersion("App1", meta.Version) == nil {
+		t.Errorf("Expected the store to be flushed on shutdown")
+	}
+}
+
+func TestGracefulShutdown_Timeout(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	restoreLifecycle(t)
+	setupStore()
+	drainPeriod, shutdownTimeout = 0, 100*time.Millisecond
+
+	hookErr := errors.New("disk full")
+	onShutdown("failing", func(context.Context) error { return hookErr })
+	hookRan := false
+	onShutdown("test", func(context.Context) error {
+		hookRan = true
+		return nil
+	})
+
+	url, started, cancel, done := startServer(t, 2*time.Second)
+	go http.Get(url + "/slow")
+	<-started
+	cancel()
+
+	err := <-done
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the shutdown to time out but got '%v'", err)
+	}
+	if !hookRan {
+		t.Errorf("Expected the hooks to run even though the shutdown timed out")
+	}
+
+	// Without in-flight requests, the error of the failing hook is returned.
+	_, _, cancel, done = startServer(t, 0)
+	cancel()
+	if err := <-done; !errors.Is(err, hookErr) || err.Error() != "Shutdown hook 'failing' failed: disk full" {
+		t.Errorf("Expected the error of the hook but got '%v'", err)
+	}
+}
+
+func TestShutdownFromEnv(t *testing.T) {
+	var tests = []struct {
+		drain           string
+		timeout         string
+		expectedDrain   time.Duration
+		expectedTimeout time.Duration
+		expectedErrMsg  string
+	}{
+		{"", "", 0, defaultShutdownTimeout, ""},
+		{"10s", "1m", 10 * time.Second, time.Minute, ""},
+		{"soon", "", 0, 0, "Bad format of APPSTORE_SHUTDOWN_DRAIN 'soon'"},
+		{"", "0s", 0, 0, "Bad format of APPSTORE_SHUTDOWN_TIMEOUT '0s'"},
+	}
+
+	for _, tt := range tests {
+		t.Setenv("APPSTORE_SHUTDOWN_DRAIN", tt.drain)
+		t.Setenv("APPSTORE_SHUTDOWN_TIMEOUT", tt.timeout)
+		drain, timeout, err := shutdownFromEnv()
+		if tt.expectedErrMsg != "" {
+			if err == nil || err.Error() != tt.expectedErrMsg {
+				t.Errorf("Expected error '%s' but got '%v'", tt.expectedErrMsg, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Should not have error but error '%s' occurred.", err.Error())
+		}
+		if drain != tt.expectedDrain || timeout != tt.expectedTimeout {
+			t.Errorf("Expected %s and %s but got %s and %s", tt.expectedDrain, tt.expectedTimeout, drain, timeout)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"net/url"
+
+	"github.com/zzn2/demo/appstore/app"
+	"github.com/zzn2/demo/appstore/policy"
+)
+
+// appLinks are the links of an app in responses, e.g. for "App3 with space in title" version 0.0.1:
+//
+//    self:     /v1/apps/app3-with-space-in-title/versions/0.0.1
+//    latest:   /v1/apps/app3-with-space-in-title
+//    versions: /v1/apps?title=App3+with+space+in+title
+//
+type appLinks struct {
+	Self     string `json:"self" yaml:"self"`
+	Latest   string `json:"latest" yaml:"latest"`
+	Versions string `json:"versions" yaml:"versions"`
+}
+
+// appResource is the representation of an app in responses, which is the metadata with its slug and links.
+type appResource struct {
+	app.Meta `yaml:",inline"`
+	Slug     string   `json:"slug" yaml:"slug"`
+	Links    appLinks `json:"links" yaml:"links"`
+	// Yanked tells the version has been yanked by its owners or maintainers, see app.Store.YankAs.
+	Yanked bool `json:"yanked,omitempty" yaml:"yanked,omitempty"`
+	// Warnings are the violated publishing rules with "warn" severity, only reported when the app is created or updated.
+	Warnings []policy.Violation `json:"warnings,omitempty" yaml:"warnings,omitempty"`
+}
+
+func newAppResource(meta app.Meta) appResource {
+	return appResource{Meta: meta, Slug: meta.Slug(), Links: newAppLinks(meta), Yanked: store.IsYanked(meta.Title, meta.Version)}
+}
+
+func newAppResources(apps []app.Meta) []appResource {
+	result := make([]appResource, 0, len(apps))
+	for _, meta := range apps {
+		result = append(result, newAppResource(meta))
+	}
+	return result
+}
+
+func newAppLinks(meta app.Meta) appLinks {
+	return appLinks{
+		Self:     appVersionPath(meta),
+		Latest:   "/v1/apps/" + url.PathEscape(meta.Slug()),
+		Versions: "/v1/apps?" + url.Values{"title": {meta.Title}}.Encode(),
+	}
+}
+
+// appVersionPath returns the URL path of the app version, which is used as the Location of a created app.
+func appVersionPath(meta app.Meta) string {
+	return "/v1/apps/" + url.PathEscape(meta.Slug()) + "/versions/" + url.PathEscape(meta.Version.String())
+}
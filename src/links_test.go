@@ -0,0 +1,56 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zzn2/demo/appstore/app"
+	"github.com/zzn2/demo/appstore/semver"
+)
+
+func TestNewAppLinks(t *testing.T) {
+	tests := []struct {
+		title    string
+		expected appLinks
+	}{
+		{"App1", appLinks{"/v1/apps/app1/versions/1.0.0", "/v1/apps/app1", "/v1/apps?title=App1"}},
+		{"App3 with space in title", appLinks{
+			"/v1/apps/app3-with-space-in-title/versions/1.0.0",
+			"/v1/apps/app3-with-space-in-title",
+			"/v1/apps?title=App3+with+space+in+title",
+		}},
+		{"A/B & C?", appLinks{"/v1/apps/a-b-c/versions/1.0.0", "/v1/apps/a-b-c", "/v1/apps?title=A%2FB+%26+C%3F"}},
+		{"Überapp", appLinks{"/v1/apps/%C3%BCberapp/versions/1.0.0", "/v1/apps/%C3%BCberapp", "/v1/apps?title=%C3%9Cberapp"}},
+	}
+
+	version := semver.Version{Major: 1}
+	for _, test := range tests {
+		if links := newAppLinks(app.Meta{Title: test.title, Version: version}); links != test.expected {
+			t.Errorf("Expected links %+v for '%s' but got %+v.", test.expected, test.title, links)
+		}
+	}
+}
+
+func TestLocationOfCreatedApp(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	ts := httptest.NewServer(setupServer())
+	defer ts.Close()
+
+	setupStore()
+	resp, err := http.Post(ts.URL+"/v1/apps", "application/x-yaml", strings.NewReader(app3WithSpaceInTitle))
+	if err != nil {
+		t.Fatalf("Error occurred during POST /apps, detail: %e", err)
+	}
+	location := resp.Header.Get("Location")
+	if location != "/v1/apps/app3-with-space-in-title/versions/0.0.1" {
+		t.Errorf("Unexpected Location '%s'", location)
+	}
+
+	resp, err = http.Get(ts.URL + location)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected the app could be got from its Location but got %v %v", resp, err)
+	}
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// Media types supported by the APIs.
+const (
+	mimeJSON   = "application/json"
+	mimeYAML   = "application/x-yaml"
+	mimeNDJSON = "application/x-ndjson"
+)
+
+// yamlMimeTypes are the media types accepted as YAML request bodies.
+var yamlMimeTypes = []string{mimeYAML, "application/yaml", "text/yaml", "text/x-yaml"}
+
+// offeredMimeTypes are the media types which responses could be rendered in, the first one is the default.
+var offeredMimeTypes = []string{mimeJSON, mimeYAML, "application/yaml", "text/yaml", mimeNDJSON}
+
+// responseFormatKey is the key in gin.Context to keep the negotiated response media type.
+const responseFormatKey = "responseFormat"
+
+// bodyMimeTypes are the media types accepted by bindBody.
+var bodyMimeTypes = append([]string{mimeJSON}, yamlMimeTypes...)
+
+// unsupportedMediaTypeError is returned when the request body is in an unsupported format.
+type unsupportedMediaTypeError struct {
+	contentType string
+	supported   []string
+}
+
+func (e unsupportedMediaTypeError) Error() string {
+	return fmt.Sprintf("Content-Type '%s' is not supported. Supported content types are: %s", e.contentType, strings.Join(e.supported, ", "))
+}
+
+// negotiateFormat is a middleware which chooses the response media type according to the Accept header.
+// It responds 406 Not Acceptable when none of the offered media types is acceptable.
+// The responses vary by the Accept header, so that caches would not serve one media type for another.
+func negotiateFormat(c *gin.Context) {
+	c.Header("Vary", "Accept")
+	format := negotiate(c.GetHeader("Accept"), offeredMimeTypes)
+	if format == "" {
+		c.Set(responseFormatKey, mimeJSON)
+		respond(c, http.StatusNotAcceptable, responseBodyForErrorMessage("None of the accepted media types '%s' is supported. Supported media types are: %s", c.GetHeader("Accept"), strings.Join(offeredMimeTypes, ", ")))
+		c.Abort()
+		return
+	}
+
+	c.Set(responseFormatKey, format)
+	c.Next()
+}
+
+// respond renders obj in the media type chosen by negotiateFormat.
+// Slices are rendered one item per line in NDJSON, other objects are rendered as a single line.
+// Error responses (problemDetails) in JSON are rendered as application/problem+json.
+func respond(c *gin.Context, code int, obj interface{}) {
+	problem, isProblem := obj.(problemDetails)
+	if isProblem {
+		problem.complete(code)
+	}
+
+	switch format := c.GetString(responseFormatKey); format {
+	case mimeYAML, "application/yaml", "text/yaml":
+		// c.YAML always sends application/x-yaml, which might not be accepted by the client.
+		c.Header("Content-Type", format+"; charset=utf-8")
+		c.YAML(code, obj)
+	case mimeNDJSON:
+		c.Status(code)
+		c.Header("Content-Type", mimeNDJSON)
+		encoder := json.NewEncoder(c.Writer)
+		v := reflect.ValueOf(obj)
+		if v.Kind() != reflect.Slice {
+			encoder.Encode(obj)
+			return
+		}
+		for i := 0; i < v.Len(); i++ {
+			encoder.Encode(v.Index(i).Interface())
+		}
+	default:
+		if isProblem {
+			c.Header("Content-Type", mimeProblemJSON)
+		}
+		c.JSON(code, obj)
+	}
+}
+
+// bindBody decodes the request body into obj according to the Content-Type header and validates it.
+// Both JSON and YAML bodies are supported. A body without Content-Type is treated as YAML.
+// It returns unsupportedMediaTypeError when the Content-Type is not supported.
+func bindBody(c *gin.Context, obj interface{}) error {
+	contentType := c.ContentType()
+	switch {
+	case contentType == mimeJSON:
+		return c.ShouldBindWith(obj, binding.JSON)
+	case contentType == "" || contains(yamlMimeTypes, contentType):
+		return c.ShouldBindWith(obj, binding.YAML)
+	default:
+		return unsupportedMediaTypeError{contentType, bodyMimeTypes}
+	}
+}
+
+// respondBindError responds the error returned by bindBody or bindApp with the proper status code.
+func respondBindError(c *gin.Context, err error) {
+	var unsupported unsupportedMediaTypeError
+	if errors.As(err, &unsupported) {
+		respond(c, http.StatusUnsupportedMediaType, responseBodyForError(err))
+		return
+	}
+	respond(c, http.StatusBadRequest, responseBodyForError(err))
+}
+
+// mediaRange is a media range with its quality value in an Accept header.
+type mediaRange struct {
+	mimeType string
+	quality  float64
+}
+
+// negotiate returns the best offered media type for the Accept header.
+// An empty Accept header accepts everything, so the first offered media type is returned.
+// It returns empty string if none of the offered media types is acceptable.
+func negotiate(accept string, offered []string) string {
+	if strings.TrimSpace(accept) == "" {
+		return offered[0]
+	}
+
+	ranges := parseAccept(accept)
+	// Ranges with "q=0" are exclusions (RFC 7231 section 5.3.2): "application/json;q=0, */*" accepts anything but JSON.
+	var excluded []string
+	for _, r := range ranges {
+		if r.quality <= 0 {
+			excluded = append(excluded, r.mimeType)
+		}
+	}
+
+	for _, r := range ranges {
+		if r.quality <= 0 {
+			continue
+		}
+		for _, offer := range offered {
+			if matchMediaRange(r.mimeType, offer) && !isExcluded(offer, r.mimeType, excluded) {
+				return offer
+			}
+		}
+	}
+	return ""
+}
+
+// isExcluded tells whether the media type, matched by the media range, is excluded by a more specific media range,
+// e.g. "text/yaml;q=0" excludes "text/yaml" from "text/*", but "text/*;q=0" does not exclude it from "text/yaml".
+func isExcluded(mimeType string, matchedBy string, excluded []string) bool {
+	for _, exclusion := range excluded {
+		if matchMediaRange(exclusion, mimeType) && specificity(exclusion) >= specificity(matchedBy) {
+			return true
+		}
+	}
+	return false
+}
+
+// specificity ranks media ranges: "*/*" < "type/*" < "type/subtype".
+func specificity(mediaRange string) int {
+	switch {
+	case mediaRange == "*/*":
+		return 0
+	case strings.HasSuffix(mediaRange, "/*"):
+		return 1
+	default:
+		return 2
+	}
+}
+
+// parseAccept parses an Accept header into media ranges, ordered by quality from high to low.
+// Malformed media ranges are ignored.
+func parseAccept(accept string) []mediaRange {
+	ranges := make([]mediaRange, 0)
+	for _, part := range strings.Split(accept, ",") {
+		mimeType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			if quality, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+		ranges = append(ranges, mediaRange{mimeType, quality})
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].quality > ranges[j].quality
+	})
+	return ranges
+}
+
+// matchMediaRange tells whether the media type is in the media range, e.g. "application/*" contains "application/json".
+func matchMediaRange(mediaRange string, mimeType string) bool {
+	if mediaRange == "*/*" || mediaRange == mimeType {
+		return true
+	}
+	if strings.HasSuffix(mediaRange, "/*") {
+		return strings.HasPrefix(mimeType, strings.TrimSuffix(mediaRange, "*"))
+	}
+	return false
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+package main
+
+import "testing"
+
+func TestNegotiate(t *testing.T) {
+	var tests = []struct {
+		accept   string
+		expected string
+	}{
+		{"", mimeJSON},
+		{"*/*", mimeJSON},
+		{"application/json", mimeJSON},
+		{"application/x-yaml", mimeYAML},
+		{"text/yaml", "text/yaml"},
+		{"text/*", "text/yaml"},
+		{"application/x-ndjson", mimeNDJSON},
+		{"application/json;q=0.5, application/x-yaml", mimeYAML},
+		{"application/x-yaml;q=0, */*;q=0.1", mimeJSON},
+		{"text/html, application/xhtml+xml, */*;q=0.8", mimeJSON},
+		{"text/html", ""},
+		{"application/jsonx", ""},
+		{"application/json;q=0", ""},
+		{"application/json;q=0, */*", mimeYAML},
+		{"application/json;q=0, application/x-yaml;q=0, */*", "application/yaml"},
+		{"text/yaml;q=0, text/*", ""},
+		{"text/*;q=0, text/yaml", "text/yaml"},
+		{"*/*;q=0, application/json", mimeJSON},
+		{"malformed/", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			if format := negotiate(tt.accept, offeredMimeTypes); format != tt.expected {
+				t.Errorf("Expected to be '%s' but got '%s'", tt.expected, format)
+			}
+		})
+	}
+}
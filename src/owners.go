@@ -0,0 +1,109 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zzn2/demo/appstore/app"
+	"github.com/zzn2/demo/appstore/auth"
+)
+
+// appOwners is the representation of the owners of an app in responses.
+type appOwners struct {
+	Title  string   `json:"title" yaml:"title"`
+	Owners []string `json:"owners" yaml:"owners"`
+}
+
+// ownerRequest is the request body to add or transfer the ownership of an app.
+type ownerRequest struct {
+	Email string `binding:"required,email"`
+}
+
+// missingEmailError is returned when a principal without email publishes apps, which could not be checked against the owners.
+type missingEmailError struct {
+	subject string
+}
+
+func (e missingEmailError) Error() string {
+	return fmt.Sprintf("'%s' has no email, so it could not own or maintain apps.", e.subject)
+}
+
+// Is makes errors.Is(err, app.ErrNotOwner) report true for a missingEmailError.
+func (e missingEmailError) Is(target error) bool {
+	return target == app.ErrNotOwner
+}
+
+// publisherOf returns the publisher of the request, which is checked against the owners and the maintainers of apps.
+// The publisher is unidentified when authentication is disabled. Admins are privileged publishers.
+// It responds 403 and returns false when the principal has no email and is not an admin.
+func publisherOf(c *gin.Context) (app.Publisher, bool) {
+	value, ok := c.Get(principalKey)
+	if !ok {
+		return app.Publisher{}, true
+	}
+
+	principal := value.(auth.Principal)
+	publisher := app.Publisher{Email: principal.Email, Privileged: principal.Has(auth.ScopeAdmin)}
+	if publisher.Email == "" && !publisher.Privileged {
+		respond(c, http.StatusForbidden, responseBodyForError(missingEmailError{principal.Subject}))
+		return publisher, false
+	}
+	return publisher, true
+}
+
+func getOwners(c *gin.Context) {
+	title, ok := resolveTitle(c)
+	if !ok {
+		return
+	}
+	if store.GetByTitle(title) == nil {
+		respond(c, http.StatusNotFound, responseBodyForError(&app.NotFoundError{Title: title}))
+		return
+	}
+	respond(c, http.StatusOK, appOwners{title, store.Owners(title)})
+}
+
+// addOwner adds an owner to the app. Only the owners could add owners.
+func addOwner(c *gin.Context) {
+	changeOwners(c, store.AddOwner)
+}
+
+// transferOwnership makes the given email the only owner of the app. Only the owners could transfer the ownership.
+func transferOwnership(c *gin.Context) {
+	changeOwners(c, store.TransferOwnership)
+}
+
+func changeOwners(c *gin.Context, change func(title string, email string, publisher app.Publisher) ([]string, error)) {
+	title, ok := resolveTitle(c)
+	if !ok {
+		return
+	}
+	publisher, ok := publisherOf(c)
+	if !ok {
+		return
+	}
+
+	var req ownerRequest
+	if err := bindBody(c, &req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	owners, err := change(title, req.Email, publisher)
+	switch {
+	case errors.Is(err, app.ErrNotFound):
+		respond(c, http.StatusNotFound, responseBodyForError(err))
+	case errors.Is(err, app.ErrNotOwner):
+		respond(c, http.StatusForbidden, responseBodyForError(err))
+	case err != nil:
+		respond(c, http.StatusBadRequest, responseBodyForError(err))
+	default:
+		if err := persistStore(); err != nil {
+			respond(c, http.StatusInternalServerError, responseBodyForError(err))
+			return
+		}
+		respond(c, http.StatusOK, appOwners{title, owners})
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/zzn2/demo/appstore/auth"
+)
+
+func TestOwnership(t *testing.T) {
+	ts := newTestServer(t)
+
+	write := []auth.Scope{auth.ScopeAppsRead, auth.ScopeAppsWrite}
+	owner, _, _ := apiKeys.Create("owner", "owner@random.com", write)
+	maintainer, _, _ := apiKeys.Create("maintainer", "secondmaintainer@gmail.com", write)
+	other, _, _ := apiKeys.Create("other", "other@random.com", write)
+	anonymous, _, _ := apiKeys.Create("ci", "", write)
+	admin, _, _ := apiKeys.Create("admin", "", []auth.Scope{auth.ScopeAdmin})
+
+	runAPITests(t, ts, []apiTest{
+		{
+			"Publisher without email",
+			"POST", "/v1/apps", anonymous, app1v1,
+			http.StatusForbidden,
+			`{"detail":"'ci' has no email, so it could not own or maintain apps.","status":403,"title":"Not an owner or maintainer of the app","type":"/problems/not-owner"}`,
+		},
+		{
+			"First publisher becomes the owner",
+			"POST", "/v1/apps", owner, app1v1,
+			http.StatusCreated,
+			"",
+		},
+		{
+			"Get owners",
+			"GET", "/v1/apps/app1/owners", other, "",
+			http.StatusOK,
+			`{"title":"App1","owners":["owner@random.com"]}`,
+		},
+		{
+			"Other publishes new version",
+			"POST", "/v1/apps", other, app1v2,
+			http.StatusForbidden,
+			`{"detail":"'other@random.com' is neither an owner nor a maintainer of the app 'App1'.","status":403,"title":"Not an owner or maintainer of the app","type":"/problems/not-owner"}`,
+		},
+		{
+			"Other edits",
+			"PUT", "/v1/apps/app1/versions/0.0.1", other, app1v1,
+			http.StatusForbidden,
+			`{"detail":"'other@random.com' is neither an owner nor a maintainer of the app 'App1'.","status":403,"title":"Not an owner or maintainer of the app","type":"/problems/not-owner"}`,
+		},
+		{
+			"Other imports new version in bulk",
+			"POST", "/v1/apps/_bulk", other, app1v2,
+			http.StatusOK,
+			`{"line":2,"status":"forbidden","title":"App1","version":"0.0.2","error":"'other@random.com' is neither an owner nor a maintainer of the app 'App1'."}`,
+		},
+		{
+			"Maintainer publishes new version",
+			"POST", "/v1/apps", maintainer, app1v2,
+			http.StatusCreated,
+			"",
+		},
+		{
+			"Maintainer adds owner",
+			"POST", "/v1/apps/app1/owners", maintainer, "email: other@random.com",
+			http.StatusForbidden,
+			`{"detail":"'secondmaintainer@gmail.com' is not an owner of the app 'App1'.","status":403,"title":"Not an owner or maintainer of the app","type":"/problems/not-owner"}`,
+		},
+		{
+			"Owner adds owner",
+			"POST", "/v1/apps/app1/owners", owner, "email: other@random.com",
+			http.StatusOK,
+			`{"title":"App1","owners":["owner@random.com","other@random.com"]}`,
+		},
+		{
+			"Added owner edits",
+			"PUT", "/v1/apps/app1/versions/0.0.1", other, strings.Replace(app1v1, "Random Inc.", "Other Inc.", 1),
+			http.StatusOK,
+			"",
+		},
+		{
+			"Owner transfers ownership",
+			"POST", "/v1/apps/app1/owners/_transfer", other, "email: new@random.com",
+			http.StatusOK,
+			`{"title":"App1","owners":["new@random.com"]}`,
+		},
+		{
+			"Former owner adds owner",
+			"POST", "/v1/apps/app1/owners", owner, "email: owner@random.com",
+			http.StatusForbidden,
+			`{"detail":"'owner@random.com' is not an owner of the app 'App1'.","status":403,"title":"Not an owner or maintainer of the app","type":"/problems/not-owner"}`,
+		},
+		{
+			"Admin transfers ownership",
+			"POST", "/v1/apps/app1/owners/_transfer", admin, "email: owner@random.com",
+			http.StatusOK,
+			`{"title":"App1","owners":["owner@random.com"]}`,
+		},
+		{
+			"Owners of missing app",
+			"POST", "/v1/apps/app9/owners", owner, "email: other@random.com",
+			http.StatusNotFound,
+			`{"detail":"App with title 'app9' does not exist.","status":404,"title":"App not found","type":"/problems/app-not-found"}`,
+		},
+	})
+}
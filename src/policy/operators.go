@@ -0,0 +1,105 @@
+package policy
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/zzn2/demo/appstore/filter"
+	"github.com/zzn2/demo/appstore/filter/op"
+)
+
+var (
+	// In matches strings in a comma separated list, e.g. company[in]=Random Inc.,Other Inc.
+	In = op.Operator{
+		Name:   "In",
+		Symbol: "in",
+		OpText: "in",
+	}
+
+	// MinLength matches strings with at least the given number of characters, e.g. description[minlen]=50.
+	MinLength = op.Operator{
+		Name:   "MinLength",
+		Symbol: "len>=",
+		OpText: "minlen",
+	}
+
+	// Domain matches email addresses of exactly the given domain, case insensitively,
+	// e.g. email[domain]=random.com matches "bob@random.com" but neither "bob@random.com.evil.io" nor "bob@eng.random.com".
+	Domain = op.Operator{
+		Name:   "Domain",
+		Symbol: "@",
+		OpText: "domain",
+	}
+)
+
+// operators is the registry of the operators in the conditions of rules,
+// which are the built-in operators of filters and the operators above.
+// The operators above are only available in policies, they are not part of the query language of the APIs.
+var operators = newOperators()
+
+func newOperators() *op.Registry {
+	r := op.NewBuiltinRegistry()
+	mustRegister := func(operator op.Operator, evaluate op.EvaluateFunc) {
+		if err := r.Register(operator, isString, evaluate); err != nil {
+			panic(err)
+		}
+	}
+
+	// The base value is a comma separated list.
+	mustRegister(In, func(incomingValue interface{}, baseValue interface{}) (bool, error) {
+		for _, item := range strings.Split(baseValue.(string), ",") {
+			if incomingValue.(string) == strings.TrimSpace(item) {
+				return true, nil
+			}
+		}
+		return false, nil
+	})
+
+	// The base value is the number of characters, see parseMinLength.
+	mustRegister(MinLength, func(incomingValue interface{}, baseValue interface{}) (bool, error) {
+		length, err := parseMinLength(baseValue.(string))
+		if err != nil {
+			return false, err
+		}
+		return utf8.RuneCountInString(incomingValue.(string)) >= length, nil
+	})
+
+	// The base value is a domain with or without the leading "@".
+	mustRegister(Domain, func(incomingValue interface{}, baseValue interface{}) (bool, error) {
+		at := strings.LastIndex(incomingValue.(string), "@")
+		if at < 0 {
+			return false, nil
+		}
+		return strings.EqualFold(incomingValue.(string)[at+1:], strings.TrimPrefix(baseValue.(string), "@")), nil
+	})
+	return r
+}
+
+func isString(value interface{}) bool {
+	_, ok := value.(string)
+	return ok
+}
+
+// parseMinLength parses the base value of MinLength, which must be a non-negative integer.
+func parseMinLength(text string) (int, error) {
+	length, err := strconv.Atoi(text)
+	if err != nil || length < 0 {
+		return 0, fmt.Errorf("Invalid length '%s' of operator '%s', expects a non-negative integer.", text, MinLength)
+	}
+	return length, nil
+}
+
+// validateOperands checks the base values of the rules which could not be checked by their types,
+// so that a bad condition is reported when the policy is parsed instead of when apps are checked.
+func validateOperands(rs filter.RuleSet) error {
+	for _, rule := range rs.Rules {
+		if rule.Op == MinLength {
+			if _, err := parseMinLength(rule.Value.(string)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
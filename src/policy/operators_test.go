@@ -0,0 +1,52 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/zzn2/demo/appstore/filter/op"
+)
+
+func TestOperators(t *testing.T) {
+	var tests = []struct {
+		operator      op.Operator
+		incomingValue interface{}
+		baseValue     interface{}
+		expected      bool
+		errorMessage  string
+	}{
+		{In, "Random Inc.", "Random Inc., Other Inc.", true, ""},
+		{In, "Other Inc.", "Random Inc.,Other Inc.", true, ""},
+		{In, "Random", "Random Inc.,Other Inc.", false, ""},
+		{In, 1, 2, false, "Operator 'In' does not support the incoming values in int type."},
+		{MinLength, "abc", "3", true, ""},
+		{MinLength, "日本語", "3", true, ""},
+		{MinLength, "ab", "3", false, ""},
+		{MinLength, "ab", "abc", false, "Invalid length 'abc' of operator 'MinLength', expects a non-negative integer."},
+		{Domain, "bob@random.com", "random.com", true, ""},
+		{Domain, "Bob@Random.COM", "@random.com", true, ""},
+		{Domain, "evil@random.com.attacker.io", "random.com", false, ""},
+		{Domain, "x@random.community", "random.com", false, ""},
+		{Domain, "random.com", "random.com", false, ""},
+		{Domain, 1, 2, false, "Operator 'Domain' does not support the incoming values in int type."},
+		{op.Like, "abc", "b", true, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.operator.Name, func(t *testing.T) {
+			res, err := operators.Evaluate(tt.operator, tt.incomingValue, tt.baseValue)
+			if res != tt.expected {
+				t.Errorf("Expect to be '%v' but got '%v'.", tt.expected, res)
+			}
+			if err != nil && err.Error() != tt.errorMessage {
+				t.Errorf("Expect error message '%s' but got '%s'.", tt.errorMessage, err.Error())
+			}
+		})
+	}
+
+	// The operators are only available in policies.
+	for _, operator := range []op.Operator{In, MinLength, Domain} {
+		if _, err := op.Parse(operator.OpText); err == nil {
+			t.Errorf("Expect '%s' not to be available in the DefaultRegistry.", operator)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+// Package policy checks apps against the publishing rules of an organization before they are added into the store,
+// e.g. descriptions must have at least 50 characters, or the company must be an approved one.
+//
+// A policy is loaded from a YAML (or JSON) file like:
+//
+//    rules:
+//    - name: description-length
+//      severity: deny
+//      require: description[minlen]=50
+//      message: The description must have at least 50 characters.
+//    - name: corporate-maintainer
+//      severity: warn
+//      when: company=Random Inc.
+//      require: maintainers.email[domain]=random.com
+//
+// Conditions ("when" and "require") use the same syntax as the query string of filters, see filter.ParseRuleSetQuery,
+// with the operators In, MinLength and Domain available in addition to the ones of filters.
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/zzn2/demo/appstore/app"
+	"github.com/zzn2/demo/appstore/filter"
+	"gopkg.in/yaml.v2"
+)
+
+// Severity tells what happens when an app violates a rule.
+type Severity string
+
+const (
+	// SeverityWarn only reports the violation, the app is still published.
+	SeverityWarn Severity = "warn"
+	// SeverityDeny rejects the app.
+	SeverityDeny Severity = "deny"
+)
+
+// ErrDenied is the kind of DeniedError, matched by errors.Is.
+var ErrDenied = errors.New("denied by policy")
+
+// Rule is a publishing rule, which requires the apps matching When (or all the apps if When is empty) to match Require.
+type Rule struct {
+	Name     string
+	Severity Severity
+	When     filter.RuleSet
+	Require  filter.RuleSet
+	// Message is reported when the rule is violated.
+	Message string
+}
+
+// Policy is a set of publishing rules.
+// A nil Policy has no rules, so that every app complies with it.
+type Policy struct {
+	Rules []Rule
+}
+
+// Violation is a rule violated by an app.
+type Violation struct {
+	Rule     string   `json:"rule" yaml:"rule"`
+	Severity Severity `json:"severity" yaml:"severity"`
+	Message  string   `json:"message" yaml:"message"`
+}
+
+// DeniedError is returned by Check when an app violates any rule with SeverityDeny.
+type DeniedError struct {
+	// Violations are all the rules violated by the app, including the ones with SeverityWarn.
+	Violations []Violation
+}
+
+func (e *DeniedError) Error() string {
+	messages := make([]string, 0, len(e.Violations))
+	for _, violation := range e.Violations {
+		if violation.Severity == SeverityDeny {
+			messages = append(messages, violation.Message)
+		}
+	}
+	return fmt.Sprintf("App is denied by %d publishing rule(s): %s", len(messages), strings.Join(messages, " "))
+}
+
+// Is makes errors.Is(err, ErrDenied) report true for a DeniedError.
+func (e *DeniedError) Is(target error) bool {
+	return target == ErrDenied
+}
+
+// ruleFile is a rule in a policy file.
+type ruleFile struct {
+	Name     string
+	Severity Severity
+	When     string
+	Require  string
+	Message  string
+}
+
+// Load loads a policy from a YAML or JSON file.
+func Load(path string) (*Policy, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read policy file: %w", err)
+	}
+	return Parse(data)
+}
+
+// Parse parses a policy in YAML or JSON, since JSON is also valid YAML.
+// Every rule must have a unique name and a "require" condition. The severity is "deny" by default,
+// and the message defaults to the name and the condition of the rule.
+func Parse(data []byte) (*Policy, error) {
+	var file struct {
+		Rules []ruleFile
+	}
+	if err := yaml.UnmarshalStrict(data, &file); err != nil {
+		return nil, fmt.Errorf("Failed to parse policy: %w", err)
+	}
+
+	var meta app.Meta
+	policy := &Policy{Rules: make([]Rule, 0, len(file.Rules))}
+	names := make(map[string]bool)
+	for i, r := range file.Rules {
+		if r.Name == "" {
+			return nil, fmt.Errorf("Rule #%d of the policy lacks of name.", i+1)
+		}
+		if names[r.Name] {
+			return nil, fmt.Errorf("Rule '%s' appeared multiple times in the policy.", r.Name)
+		}
+		names[r.Name] = true
+
+		switch r.Severity {
+		case "":
+			r.Severity = SeverityDeny
+		case SeverityWarn, SeverityDeny:
+		default:
+			return nil, fmt.Errorf("Rule '%s' has unknown severity '%s', expects '%s' or '%s'.", r.Name, r.Severity, SeverityWarn, SeverityDeny)
+		}
+
+		if strings.TrimSpace(r.Require) == "" {
+			return nil, fmt.Errorf("Rule '%s' lacks of the require condition.", r.Name)
+		}
+		require, err := parseCondition(r.Require, meta)
+		if err != nil {
+			return nil, fmt.Errorf("Rule '%s' has bad require condition: %w", r.Name, err)
+		}
+		when, err := parseCondition(r.When, meta)
+		if err != nil {
+			return nil, fmt.Errorf("Rule '%s' has bad when condition: %w", r.Name, err)
+		}
+
+		if r.Message == "" {
+			r.Message = fmt.Sprintf("Rule '%s' requires '%s'.", r.Name, r.Require)
+		}
+		policy.Rules = append(policy.Rules, Rule{Name: r.Name, Severity: r.Severity, When: when, Require: require, Message: r.Message})
+	}
+	return policy, nil
+}
+
+// parseCondition parses a condition of a rule with the operators available in policies, see operators.
+func parseCondition(text string, meta app.Meta) (filter.RuleSet, error) {
+	rs, err := filter.ParseRuleSetQueryWith(operators, text, meta)
+	if err != nil {
+		return rs, err
+	}
+	return rs, validateOperands(rs)
+}
+
+// Check checks the app against all the rules of the policy and returns the violations.
+// It returns *DeniedError together with the violations if any rule with SeverityDeny is violated.
+func (p *Policy) Check(meta app.Meta) ([]Violation, error) {
+	violations := make([]Violation, 0)
+	if p == nil {
+		return violations, nil
+	}
+
+	denied := false
+	for _, rule := range p.Rules {
+		applies, err := rule.When.Match(meta)
+		if err != nil {
+			return violations, fmt.Errorf("Failed to check rule '%s': %w", rule.Name, err)
+		}
+		if !applies {
+			continue
+		}
+
+		complies, err := rule.Require.Match(meta)
+		if err != nil {
+			return violations, fmt.Errorf("Failed to check rule '%s': %w", rule.Name, err)
+		}
+		if !complies {
+			violations = append(violations, Violation{Rule: rule.Name, Severity: rule.Severity, Message: rule.Message})
+			denied = denied || rule.Severity == SeverityDeny
+		}
+	}
+
+	if denied {
+		return violations, &DeniedError{violations}
+	}
+	return violations, nil
+}
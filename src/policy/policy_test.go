@@ -0,0 +1,142 @@
+package policy
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/zzn2/demo/appstore/app"
+	"github.com/zzn2/demo/appstore/semver"
+)
+
+const samplePolicy = `
+rules:
+- name: description-length
+  require: description[minlen]=50
+  message: The description must have at least 50 characters.
+- name: approved-company
+  severity: deny
+  require: company[in]=Random Inc.,Other Inc.
+- name: corporate-maintainer
+  severity: warn
+  when: company=Random Inc.
+  require: maintainers.email[domain]=random.com
+`
+
+var sampleApp = app.Meta{
+	Title:       "App1",
+	Version:     semver.Version{Patch: 1},
+	Maintainers: []app.Maintainer{{Name: "Alice", Email: "alice@hotmail.com"}, {Name: "Bob", Email: "bob@random.com"}},
+	Company:     "Random Inc.",
+	Description: strings.Repeat("a", 50),
+}
+
+func TestCheck(t *testing.T) {
+	policy, err := Parse([]byte(samplePolicy))
+	if err != nil {
+		t.Fatalf("Should not have error but error '%s' occurred.", err.Error())
+	}
+
+	var tests = []struct {
+		name               string
+		modify             func(meta *app.Meta)
+		expectedViolations []string
+		expectedDenied     bool
+	}{
+		{"Complies with all the rules", func(meta *app.Meta) {}, []string{}, false},
+		{"Short description", func(meta *app.Meta) { meta.Description = "desc" }, []string{"description-length"}, true},
+		{"No corporate maintainer", func(meta *app.Meta) { meta.Maintainers = meta.Maintainers[:1] }, []string{"corporate-maintainer"}, false},
+		{"Look-alike domain", func(meta *app.Meta) {
+			meta.Maintainers = []app.Maintainer{{Name: "Bob", Email: "bob@random.com.attacker.io"}}
+		}, []string{"corporate-maintainer"}, false},
+		{"Rule does not apply", func(meta *app.Meta) { meta.Company = "Other Inc."; meta.Maintainers = nil }, []string{}, false},
+		{"Multiple violations", func(meta *app.Meta) { meta.Company = "Unknown Inc."; meta.Description = "" }, []string{"description-length", "approved-company"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			meta := sampleApp
+			tt.modify(&meta)
+			violations, err := policy.Check(meta)
+
+			names := make([]string, 0)
+			for _, violation := range violations {
+				names = append(names, violation.Rule)
+			}
+			if !reflect.DeepEqual(names, tt.expectedViolations) {
+				t.Errorf("Expected violations %v but got %v", tt.expectedViolations, names)
+			}
+
+			var denied *DeniedError
+			if errors.As(err, &denied) != tt.expectedDenied {
+				t.Errorf("Expected denied to be %t but got error '%v'", tt.expectedDenied, err)
+			}
+			if denied != nil && !reflect.DeepEqual(denied.Violations, violations) {
+				t.Errorf("Expected all the violations in the error but got %v", denied.Violations)
+			}
+		})
+	}
+}
+
+func TestCheck_NilPolicy(t *testing.T) {
+	var policy *Policy
+	if violations, err := policy.Check(app.Meta{}); len(violations) != 0 || err != nil {
+		t.Errorf("Expected nil policy to have no violations but got %v %v", violations, err)
+	}
+}
+
+func TestParse(t *testing.T) {
+	var tests = []struct {
+		name           string
+		policy         string
+		expectedErrMsg string
+	}{
+		{"JSON", `{"rules":[{"name":"r1","severity":"warn","require":"license=MIT"}]}`, ""},
+		{"Default message", "rules:\n- name: r1\n  require: license=MIT\n", ""},
+		{"Missing name", "rules:\n- require: license=MIT\n", "Rule #1 of the policy lacks of name."},
+		{"Duplicate name", "rules:\n- name: r1\n  require: license=MIT\n- name: r1\n  require: license=MIT\n", "Rule 'r1' appeared multiple times in the policy."},
+		{"Unknown severity", "rules:\n- name: r1\n  severity: error\n  require: license=MIT\n", "Rule 'r1' has unknown severity 'error', expects 'warn' or 'deny'."},
+		{"Missing require", "rules:\n- name: r1\n", "Rule 'r1' lacks of the require condition."},
+		{"Unknown field", "rules:\n- name: r1\n  require: dummy=1\n", "Rule 'r1' has bad require condition: Failed to create rule: Field with name 'dummy' does not exist."},
+		{"Bad when", "rules:\n- name: r1\n  when: title[dummy]=1\n  require: license=MIT\n", "Rule 'r1' has bad when condition: Unrecognized operator type 'dummy'"},
+		{"Bad length", "rules:\n- name: r1\n  require: description[minlen]=abc\n", "Rule 'r1' has bad require condition: Invalid length 'abc' of operator 'MinLength', expects a non-negative integer."},
+		{"Negative length", "rules:\n- name: r1\n  when: description[minlen]=-1\n  require: license=MIT\n", "Rule 'r1' has bad when condition: Invalid length '-1' of operator 'MinLength', expects a non-negative integer."},
+		{"Unknown key", "rules:\n- name: r1\n  require: license=MIT\n  level: warn\n", "Failed to parse policy: yaml: unmarshal errors:\n  line 4: field level not found in type policy.ruleFile"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := Parse([]byte(tt.policy))
+			if tt.expectedErrMsg == "" {
+				if err != nil {
+					t.Fatalf("Should not have error but error '%s' occurred.", err.Error())
+				}
+				if len(policy.Rules) != 1 || policy.Rules[0].Message == "" {
+					t.Errorf("Expected 1 rule with message but got %+v", policy.Rules)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.expectedErrMsg {
+				t.Errorf("Expected to have error '%s' but got '%v'", tt.expectedErrMsg, err)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	if err := os.WriteFile(path, []byte(samplePolicy), 0644); err != nil {
+		t.Fatal(err)
+	}
+	policy, err := Load(path)
+	if err != nil || len(policy.Rules) != 3 {
+		t.Errorf("Expected 3 rules loaded but got %v %v", policy, err)
+	}
+
+	if _, err := Load(filepath.Join(t.TempDir(), "dummy.yaml")); err == nil || !strings.HasPrefix(err.Error(), "Failed to read policy file: ") {
+		t.Errorf("Expected error reading missing file but got '%v'", err)
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/zzn2/demo/appstore/app"
+	"github.com/zzn2/demo/appstore/auth"
+	"github.com/zzn2/demo/appstore/filter"
+	"github.com/zzn2/demo/appstore/filter/op"
+	"github.com/zzn2/demo/appstore/policy"
+	"github.com/zzn2/demo/appstore/semver"
+)
+
+// mimeProblemJSON is the media type of error responses in JSON, see RFC 7807.
+const mimeProblemJSON = "application/problem+json"
+
+// problemTypePrefix is the prefix of the URI references of problem types, e.g. "/problems/duplicate-version".
+const problemTypePrefix = "/problems/"
+
+// problemDetails is the body of error responses, which is a problem details object defined by RFC 7807:
+//
+//    {
+//      "type": "/problems/unknown-field",
+//      "title": "Unknown field",
+//      "status": 400,
+//      "detail": "Failed to create rule: Field with name 'dummy' does not exist.",
+//      "field": "dummy",
+//      "param": "dummy[like]"
+//    }
+//
+// "status" is filled by respond, so are "type" and "title" when the error has no specific problem type.
+// Extension members could be added, e.g. "suggestions".
+type problemDetails map[string]interface{}
+
+// problemType is the stable code of a kind of errors.
+type problemType struct {
+	kind  error
+	name  string
+	title string
+}
+
+// problemTypes are checked in order, so the kinds of specific contexts go before the general ones.
+var problemTypes = []problemType{
+	{auth.ErrInvalidCredentials, "invalid-credentials", "Invalid credentials"},
+	{auth.ErrInsufficientScope, "insufficient-scope", "Insufficient scope"},
+	{errValidationFailed, "validation-failed", "Validation failed"},
+	{policy.ErrDenied, "policy-violation", "App violates the publishing policy"},
+	{app.ErrDuplicateVersion, "duplicate-version", "App version already exists"},
+	{app.ErrNotFound, "app-not-found", "App not found"},
+	{app.ErrRevisionMismatch, "revision-mismatch", "App has been modified"},
+	{app.ErrTitleConflict, "title-conflict", "App title conflicts with another app"},
+	{app.ErrNotOwner, "not-owner", "Not an owner or maintainer of the app"},
+	{app.ErrMissingVersion, "missing-version", "App version is missing"},
+	{filter.ErrUnknownField, "unknown-field", "Unknown field"},
+	{filter.ErrMalformedKey, "malformed-parameter", "Malformed query parameter"},
+	{filter.ErrDuplicateKey, "duplicate-parameter", "Duplicate query parameter"},
+	{filter.ErrInvalidValue, "invalid-value", "Invalid value"},
+	{filter.ErrUnorderedField, "unordered-field", "Field could not be sorted"},
+	{op.ErrUnknownOperator, "unknown-operator", "Unknown operator"},
+	{op.ErrOperatorTypeMismatch, "operator-type-mismatch", "Operator does not support the field type"},
+	{semver.ErrInvalidVersion, "invalid-version", "Invalid version"},
+}
+
+// paramError is an error of a request parameter which is not covered by the errors of other packages,
+// e.g. a path parameter in bad format.
+type paramError struct {
+	param   string
+	message string
+	err     error
+}
+
+func (e *paramError) Error() string {
+	return e.message
+}
+
+func (e *paramError) Unwrap() error {
+	return e.err
+}
+
+// responseBodyForError formats the response body of the error.
+// The problem type, the offending field and parameter are resolved from the typed errors of app, filter, op and semver.
+func responseBodyForError(err error) problemDetails {
+	body := problemDetails{"detail": err.Error()}
+	for _, t := range problemTypes {
+		if errors.Is(err, t.kind) {
+			body["type"] = problemTypePrefix + t.name
+			body["title"] = t.title
+			break
+		}
+	}
+
+	var filterErr *filter.Error
+	var paramErr *paramError
+	var validationErr *validationError
+	var deniedErr *policy.DeniedError
+	if errors.As(err, &validationErr) {
+		body["errors"] = validationErr.fields
+	} else if errors.As(err, &deniedErr) {
+		body["violations"] = deniedErr.Violations
+	} else if errors.As(err, &filterErr) {
+		if filterErr.Field != "" {
+			body["field"] = filterErr.Field
+		}
+		if filterErr.Param != "" {
+			body["param"] = filterErr.Param
+		}
+	} else if errors.As(err, &paramErr) {
+		body["param"] = paramErr.param
+	}
+	return body
+}
+
+// responseBodyForErrorMessage is aimed to format the response body of bad requests.
+// All the bad requests will go through this function so that the error responses will have the same format.
+// This makes the consumer of our APIs easier to write error handling logic.
+func responseBodyForErrorMessage(format string, a ...interface{}) problemDetails {
+	return problemDetails{"detail": fmt.Sprintf(format, a...)}
+}
+
+// complete fills the members of the problem determined by the status code.
+func (p problemDetails) complete(code int) {
+	p["status"] = code
+	if _, ok := p["type"]; !ok {
+		p["type"] = "about:blank"
+		p["title"] = http.StatusText(code)
+	}
+}
+
+// badVersionParam returns the error of the version path parameter in bad format.
+func badVersionParam(text string, err error) error {
+	return &paramError{"version", fmt.Sprintf("Bad format of version '%s'", text), err}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/zzn2/demo/appstore/app"
+	"github.com/zzn2/demo/appstore/filter"
+	"github.com/zzn2/demo/appstore/semver"
+)
+
+func TestResponseBodyForError(t *testing.T) {
+	var meta app.Meta
+	_, ruleErr := filter.NewRule("version[lt]", "0.0.a", meta)
+	_, versionErr := semver.Parse("0.0.a")
+
+	tests := []struct {
+		name     string
+		err      error
+		expected problemDetails
+	}{
+		{
+			"untyped error",
+			errors.New("Something wrong."),
+			problemDetails{"detail": "Something wrong."},
+		},
+		{
+			"app error wrapped",
+			fmt.Errorf("Failed to import: %w", &app.DuplicateError{Existing: app.Meta{Title: "App1"}}),
+			problemDetails{
+				"type":   "/problems/duplicate-version",
+				"title":  "App version already exists",
+				"detail": "Failed to import: App 'App1' with version '0.0.0' already exists.",
+			},
+		},
+		{
+			"filter error with field and param",
+			ruleErr,
+			problemDetails{
+				"type":   "/problems/invalid-value",
+				"title":  "Invalid value",
+				"detail": ruleErr.Error(),
+				"field":  "version",
+				"param":  "version[lt]",
+			},
+		},
+		{
+			"bad path parameter",
+			badVersionParam("0.0.a", versionErr),
+			problemDetails{
+				"type":   "/problems/invalid-version",
+				"title":  "Invalid version",
+				"detail": "Bad format of version '0.0.a'",
+				"param":  "version",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if body := responseBodyForError(tt.err); !reflect.DeepEqual(body, tt.expected) {
+				t.Errorf("Expected %v but got %v", tt.expected, body)
+			}
+		})
+	}
+}
+
+func TestProblemDetailsComplete(t *testing.T) {
+	problem := responseBodyForErrorMessage("Search '%s' already exists.", "s")
+	problem.complete(409)
+	expected := problemDetails{"type": "about:blank", "title": "Conflict", "status": 409, "detail": "Search 's' already exists."}
+	if !reflect.DeepEqual(problem, expected) {
+		t.Errorf("Expected %v but got %v", expected, problem)
+	}
+
+	problem = responseBodyForError(&app.NotFoundError{Title: "App1"})
+	problem.complete(404)
+	if problem["type"] != "/problems/app-not-found" || problem["title"] != "App not found" || problem["status"] != 404 {
+		t.Errorf("Expected the problem type kept but got %v", problem)
+	}
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"os"
+
+	"github.com/zzn2/demo/appstore/policy"
+)
+
+// publishingPolicy is checked before apps are added into the store.
+// Every app complies with it when nil.
+var publishingPolicy *policy.Policy
+
+// publishingPolicyFromEnv loads the publishing policy from the YAML or JSON file in APPSTORE_POLICY_FILE.
+// It returns nil if the variable is not set.
+func publishingPolicyFromEnv() (*policy.Policy, error) {
+	path := os.Getenv("APPSTORE_POLICY_FILE")
+	if path == "" {
+		return nil, nil
+	}
+	return policy.Load(path)
+}
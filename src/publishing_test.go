@@ -0,0 +1,128 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zzn2/demo/appstore/policy"
+)
+
+func TestPublishingPolicy(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	ts := httptest.NewServer(setupServer())
+	defer ts.Close()
+
+	defer func(original *policy.Policy) { publishingPolicy = original }(publishingPolicy)
+	var err error
+	publishingPolicy, err = policy.Parse([]byte(`
+rules:
+- name: approved-company
+  require: company[in]=Random Inc.,Other Inc.
+  message: The company is not approved.
+- name: corporate-maintainer
+  severity: warn
+  require: maintainers.email[domain]=random.com
+  message: No maintainer has an email of random.com.
+`))
+	if err != nil {
+		t.Fatalf("Should not have error but error '%s' occurred.", err.Error())
+	}
+
+	send := func(method string, path string, contentType string, data string) (int, string) {
+		req, _ := http.NewRequest(method, ts.URL+path, strings.NewReader(data))
+		req.Header.Set("Content-Type", contentType)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Error occurred during %s %s, detail: %e", method, path, err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	var tests = []struct {
+		name         string
+		method       string
+		path         string
+		data         string
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			"Denied",
+			"POST", "/v1/apps",
+			strings.Replace(app1v1, "company: Random Inc.", "company: Unknown Inc.", 1),
+			http.StatusUnprocessableEntity,
+			`{"detail":"App is denied by 1 publishing rule(s): The company is not approved.","status":422,"title":"App violates the publishing policy","type":"/problems/policy-violation",` +
+				`"violations":[{"rule":"approved-company","severity":"deny","message":"The company is not approved."},{"rule":"corporate-maintainer","severity":"warn","message":"No maintainer has an email of random.com."}]}`,
+		},
+		{
+			"Created with warnings",
+			"POST", "/v1/apps",
+			app1v1,
+			http.StatusCreated,
+			`"links":{"self":"/v1/apps/app1/versions/0.0.1","latest":"/v1/apps/app1","versions":"/v1/apps?title=App1"},` +
+				`"warnings":[{"rule":"corporate-maintainer","severity":"warn","message":"No maintainer has an email of random.com."}]}`,
+		},
+		{
+			"Created without warnings",
+			"POST", "/v1/apps",
+			strings.Replace(app2v1, "firstmaintainer@hotmail.com", "firstmaintainer@random.com", 1),
+			http.StatusCreated,
+			`"links":{"self":"/v1/apps/app2/versions/0.0.1","latest":"/v1/apps/app2","versions":"/v1/apps?title=App2"}}`,
+		},
+		{
+			"Denied in bulk",
+			"POST", "/v1/apps/_bulk",
+			strings.Replace(app1v2, "company: Random Inc.", "company: Unknown Inc.", 1),
+			http.StatusOK,
+			`{"line":2,"status":"invalid","title":"App1","error":"App is denied by 1 publishing rule(s): The company is not approved."}`,
+		},
+		{
+			"Created in bulk with warnings",
+			"POST", "/v1/apps/_bulk",
+			app1v2,
+			http.StatusOK,
+			`{"line":2,"status":"created","title":"App1","version":"0.0.2","warnings":[{"rule":"corporate-maintainer","severity":"warn","message":"No maintainer has an email of random.com."}]}`,
+		},
+		{
+			"Created in bulk atomically with warnings",
+			"POST", "/v1/apps/_bulk?atomic=true",
+			app3WithSpaceInTitle,
+			http.StatusOK,
+			`{"line":2,"status":"created","title":"App3 with space in title","version":"0.0.1","warnings":[{"rule":"corporate-maintainer","severity":"warn","message":"No maintainer has an email of random.com."}]}`,
+		},
+		{
+			"Denied on update",
+			"PUT", "/v1/apps/app1/versions/0.0.1",
+			strings.Replace(app1v1, "company: Random Inc.", "company: Unknown Inc.", 1),
+			http.StatusUnprocessableEntity,
+			`{"detail":"App is denied by 1 publishing rule(s): The company is not approved.","status":422,"title":"App violates the publishing policy","type":"/problems/policy-violation",` +
+				`"violations":[{"rule":"approved-company","severity":"deny","message":"The company is not approved."},{"rule":"corporate-maintainer","severity":"warn","message":"No maintainer has an email of random.com."}]}`,
+		},
+		{
+			"Updated with warnings",
+			"PUT", "/v1/apps/app1/versions/0.0.1",
+			strings.Replace(app1v1, "Random Inc.", "Other Inc.", 1),
+			http.StatusOK,
+			`"links":{"self":"/v1/apps/app1/versions/0.0.1","latest":"/v1/apps/app1","versions":"/v1/apps?title=App1"},` +
+				`"warnings":[{"rule":"corporate-maintainer","severity":"warn","message":"No maintainer has an email of random.com."}]}`,
+		},
+	}
+
+	setupStore()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, body := send(tt.method, tt.path, "application/x-yaml", tt.data)
+			if code != tt.expectedCode {
+				t.Errorf("Expected status code to be %d but got %d", tt.expectedCode, code)
+			}
+			if !strings.HasSuffix(strings.TrimSpace(body), tt.expectedBody) {
+				t.Errorf("Expected response body to end with '%s' but got '%s'", tt.expectedBody, body)
+			}
+		})
+	}
+}
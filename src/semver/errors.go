@@ -0,0 +1,32 @@
+package semver
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidVersion is matched by errors.Is for all the errors returned when a text could not be parsed into a Version.
+var ErrInvalidVersion = errors.New("invalid version")
+
+// ParseError is returned when a text could not be parsed into a Version.
+type ParseError struct {
+	Text string
+	// Err is the reason of the failure.
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	if e.Text == "" {
+		return fmt.Sprintf("Failed to parse version: %s", e.Err)
+	}
+	return fmt.Sprintf("Failed to parse version '%s': %s", e.Text, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// Is makes errors.Is(err, ErrInvalidVersion) report true for a ParseError.
+func (e *ParseError) Is(target error) bool {
+	return target == ErrInvalidVersion
+}
@@ -6,6 +6,8 @@
 package semver
 
 import (
+	"database/sql/driver"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"strconv"
@@ -24,23 +26,23 @@ type Version struct {
 var Empty = Version{}
 
 // Parse parses a text into a Version object.
-// errors will be returned if the text is not a valid format.
+// *ParseError will be returned if the text is not a valid format.
 func Parse(s string) (Version, error) {
 	if len(s) == 0 {
-		return Version{}, errors.New("Failed to parse version: Empty string")
+		return Version{}, &ParseError{s, errors.New("Empty string")}
 	}
 
 	// Split into major.minor.(patch+pr+meta)
 	parts := strings.SplitN(s, ".", 3)
 	if len(parts) != 3 {
-		return Version{}, fmt.Errorf("Failed to parse version '%s': Version text must be in 'Major.Minor.Patch' format", s)
+		return Version{}, &ParseError{s, errors.New("Version text must be in 'Major.Minor.Patch' format")}
 	}
 
 	sections := make([]uint64, 3)
 	for i := range sections {
 		val, err := parseSection(parts[i])
 		if err != nil {
-			return Version{}, fmt.Errorf("Failed to parse version '%s': %w", s, err)
+			return Version{}, &ParseError{s, err}
 		}
 		sections[i] = val
 	}
@@ -74,6 +76,11 @@ func (v *Version) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	return v.UnmarshalText([]byte(text))
 }
 
+// MarshalYAML will be called when serializing a Version object into text as part of YAML.
+func (v Version) MarshalYAML() (interface{}, error) {
+	return v.String(), nil
+}
+
 // UnmarshalText reads list of bytes and parse them into Version object.
 func (v *Version) UnmarshalText(text []byte) error {
 	version, err := Parse(string(text))
@@ -87,11 +94,52 @@ func (v *Version) UnmarshalText(text []byte) error {
 	return nil
 }
 
+// MarshalText serializes a Version object into text like "1.2.3".
+// It is also used by encodings which support encoding.TextMarshaler, e.g. gob.
+func (v Version) MarshalText() ([]byte, error) {
+	return []byte(v.String()), nil
+}
+
+// UnmarshalJSON will be called when deserializing a Version object from part of JSON.
+// The version is expected to be a JSON string like "1.2.3". JSON null leaves the version unchanged.
+func (v *Version) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return &ParseError{string(data), errors.New("Version must be a JSON string")}
+	}
+
+	return v.UnmarshalText([]byte(text))
+}
+
 // MarshalJSON will be called when serializing a Version object into text as part of json.
 func (v Version) MarshalJSON() ([]byte, error) {
 	return []byte(fmt.Sprintf("%q", v.String())), nil
 }
 
+// Scan implements sql.Scanner so that a Version object could be read from a text column of a database.
+func (v *Version) Scan(src interface{}) error {
+	switch src := src.(type) {
+	case string:
+		return v.UnmarshalText([]byte(src))
+	case []byte:
+		return v.UnmarshalText(src)
+	case nil:
+		*v = Empty
+		return nil
+	default:
+		return fmt.Errorf("Failed to scan version: Unsupported type '%T'", src)
+	}
+}
+
+// Value implements driver.Valuer so that a Version object could be written into a text column of a database.
+func (v Version) Value() (driver.Value, error) {
+	return v.String(), nil
+}
+
 // String returns the string representation of the Version object.
 func (v Version) String() string {
 	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
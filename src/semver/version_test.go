@@ -1,8 +1,18 @@
 package semver
 
 import (
+	"bytes"
+	"database/sql"
+	"database/sql/driver"
+	"encoding"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"testing"
+	"testing/quick"
+
+	"gopkg.in/yaml.v2"
 )
 
 func TestParse(t *testing.T) {
@@ -30,6 +40,9 @@ func TestParse(t *testing.T) {
 				if err.Error() != tt.errorMessage {
 					t.Errorf("Expect error message '%s' but got '%s'.", tt.errorMessage, err.Error())
 				}
+				if !errors.Is(err, ErrInvalidVersion) {
+					t.Errorf("Expect error to be ErrInvalidVersion but got %T.", err)
+				}
 			}
 		})
 	}
@@ -140,3 +153,153 @@ func TestString(t *testing.T) {
 		})
 	}
 }
+
+// Make sure Version implements all the encoding interfaces.
+var (
+	_ encoding.TextMarshaler   = Version{}
+	_ encoding.TextUnmarshaler = (*Version)(nil)
+	_ json.Marshaler           = Version{}
+	_ json.Unmarshaler         = (*Version)(nil)
+	_ yaml.Marshaler           = Version{}
+	_ yaml.Unmarshaler         = (*Version)(nil)
+	_ sql.Scanner              = (*Version)(nil)
+	_ driver.Valuer            = Version{}
+)
+
+// wrapper is used to test Version as part of another object.
+type wrapper struct {
+	Name    string
+	Version Version
+}
+
+func TestRoundTrip(t *testing.T) {
+	var tests = []struct {
+		name      string
+		roundTrip func(w wrapper) (wrapper, error)
+	}{
+		{"json", func(w wrapper) (wrapper, error) {
+			var out wrapper
+			data, err := json.Marshal(w)
+			if err != nil {
+				return out, err
+			}
+			err = json.Unmarshal(data, &out)
+			return out, err
+		}},
+		{"yaml", func(w wrapper) (wrapper, error) {
+			var out wrapper
+			data, err := yaml.Marshal(w)
+			if err != nil {
+				return out, err
+			}
+			err = yaml.Unmarshal(data, &out)
+			return out, err
+		}},
+		{"text", func(w wrapper) (wrapper, error) {
+			out := wrapper{Name: w.Name}
+			data, err := w.Version.MarshalText()
+			if err != nil {
+				return out, err
+			}
+			err = out.Version.UnmarshalText(data)
+			return out, err
+		}},
+		{"gob", func(w wrapper) (wrapper, error) {
+			var out wrapper
+			var buf bytes.Buffer
+			if err := gob.NewEncoder(&buf).Encode(w); err != nil {
+				return out, err
+			}
+			err := gob.NewDecoder(&buf).Decode(&out)
+			return out, err
+		}},
+		{"sql", func(w wrapper) (wrapper, error) {
+			out := wrapper{Name: w.Name}
+			value, err := w.Version.Value()
+			if err != nil {
+				return out, err
+			}
+			err = out.Version.Scan(value)
+			return out, err
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			property := func(name string, v Version) bool {
+				in := wrapper{Name: name, Version: v}
+				out, err := tt.roundTrip(in)
+				return err == nil && out == in
+			}
+			if err := quick.Check(property, nil); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestMarshalAsString(t *testing.T) {
+	w := wrapper{Name: "App1", Version: Version{1, 2, 3}}
+
+	data, _ := json.Marshal(w)
+	if string(data) != `{"Name":"App1","Version":"1.2.3"}` {
+		t.Errorf("Expected JSON to be '%s' but got '%s'", `{"Name":"App1","Version":"1.2.3"}`, data)
+	}
+
+	data, _ = yaml.Marshal(w)
+	if string(data) != "name: App1\nversion: 1.2.3\n" {
+		t.Errorf("Expected YAML to be '%s' but got '%s'", "name: App1\nversion: 1.2.3\n", data)
+	}
+}
+
+func TestUnmarshalJSON(t *testing.T) {
+	var tests = []struct {
+		input        string
+		expected     Version
+		errorMessage string
+	}{
+		{`"1.2.3"`, Version{1, 2, 3}, ""},
+		{`null`, Version{0, 0, 0}, ""},
+		{`"1.2"`, Version{0, 0, 0}, "Failed to parse version '1.2': Version text must be in 'Major.Minor.Patch' format"},
+		{`{"Major":1}`, Version{0, 0, 0}, `Failed to parse version '{"Major":1}': Version must be a JSON string`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			var v Version
+			err := json.Unmarshal([]byte(tt.input), &v)
+			if v != tt.expected {
+				t.Errorf("Expect to be '%v' but got '%v'", tt.expected, v)
+			}
+			if err != nil && err.Error() != tt.errorMessage {
+				t.Errorf("Expect error message '%s' but got '%s'.", tt.errorMessage, err.Error())
+			}
+		})
+	}
+}
+
+func TestScan(t *testing.T) {
+	var tests = []struct {
+		src          interface{}
+		expected     Version
+		errorMessage string
+	}{
+		{"1.2.3", Version{1, 2, 3}, ""},
+		{[]byte("0.0.1"), Version{0, 0, 1}, ""},
+		{nil, Version{0, 0, 0}, ""},
+		{42, Version{0, 0, 0}, "Failed to scan version: Unsupported type 'int'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%v", tt.src), func(t *testing.T) {
+			var v Version
+			err := v.Scan(tt.src)
+			if v != tt.expected {
+				t.Errorf("Expect to be '%v' but got '%v'", tt.expected, v)
+			}
+			if err != nil && err.Error() != tt.errorMessage {
+				t.Errorf("Expect error message '%s' but got '%s'.", tt.errorMessage, err.Error())
+			}
+		})
+	}
+}
@@ -1,62 +1,117 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"log"
 	"net/http"
+	"os"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/zzn2/demo/appstore/app"
+	"github.com/zzn2/demo/appstore/auth"
 	"github.com/zzn2/demo/appstore/filter"
+	"github.com/zzn2/demo/appstore/policy"
 	"github.com/zzn2/demo/appstore/semver"
+	"github.com/zzn2/demo/appstore/suggest"
 )
 
 var store *app.Store
 
+// defaultSuggestLimit is the default number of type-ahead suggestions.
+const defaultSuggestLimit = 10
+
+// maxSuggestions is the max number of "did you mean" suggestions in a 404 response.
+const maxSuggestions = 5
+
+// dataFile is the path of the file where the store is persisted.
+// The store is kept in memory only when dataFile is empty.
+var dataFile string
+
+// newApp creates a new app version.
+// The app is checked against the publishing policy first: it is rejected with 422 when denied by any rule,
+// otherwise the violated rules with "warn" severity are reported as the warnings of the created app.
+// Posting the same content as an existing app version again responds 200 with the existing one,
+// while posting different content responds 409 with the diff.
+// The publisher of a new title becomes its owner, while new versions of an existing title could only be published
+// by its owners or maintainers, otherwise it responds 403.
 func newApp(c *gin.Context) {
-	var app app.Meta
+	var meta app.Meta
 
-	if err := c.ShouldBindYAML(&app); err != nil {
-		c.JSON(http.StatusBadRequest, responseBodyForError(err))
+	if err := bindApp(c, &meta); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	publisher, ok := publisherOf(c)
+	if !ok {
 		return
 	}
 
-	if app.Version == semver.Empty {
-		err := errors.New(fmt.Sprintf("App '%s' lacks of version or the version could not be '%s'.)", app.Title, app.Version))
-		c.JSON(http.StatusBadRequest, responseBodyForError(err))
+	warnings, err := publishingPolicy.Check(meta)
+	if errors.Is(err, policy.ErrDenied) {
+		respond(c, http.StatusUnprocessableEntity, responseBodyForError(err))
+		return
+	} else if err != nil {
+		respond(c, http.StatusInternalServerError, responseBodyForError(err))
 		return
 	}
 
-	err := store.Add(app)
-	if err != nil {
-		c.JSON(http.StatusConflict, responseBodyForError(err))
+	err = store.AddAs(meta, publisher)
+	var duplicate *app.DuplicateError
+	if errors.Is(err, app.ErrNotOwner) {
+		respond(c, http.StatusForbidden, responseBodyForError(err))
+	} else if errors.As(err, &duplicate) && duplicate.Identical() {
+		respondApp(c, http.StatusOK, duplicate.Existing)
+	} else if duplicate != nil {
+		body := responseBodyForError(err)
+		body["diff"] = duplicate.Diff
+		respond(c, http.StatusConflict, body)
+	} else if err != nil {
+		respond(c, http.StatusConflict, responseBodyForError(err))
+	} else if err := persistStore(); err != nil {
+		respond(c, http.StatusInternalServerError, responseBodyForError(err))
 	} else {
-		c.JSON(http.StatusCreated, app)
+		c.Header("ETag", etag(c, store.RevisionOf(meta)))
+		c.Header("Location", appVersionPath(meta))
+		resource := newAppResource(meta)
+		resource.Warnings = warnings
+		respond(c, http.StatusCreated, resource)
 	}
 }
 
 func getAppByTitle(c *gin.Context) {
-	title := c.Param("title")
-	app := store.GetByTitle(title)
-	if app != nil {
-		c.JSON(http.StatusOK, app)
+	title, ok := resolveTitle(c)
+	if !ok {
+		return
+	}
+	meta := store.GetByTitle(title)
+	if meta != nil {
+		respondApp(c, http.StatusOK, *meta)
 	} else {
-		c.JSON(http.StatusNotFound, responseBodyForErrorMessage("App with title '%s' does not exist.", title))
+		body := responseBodyForError(&app.NotFoundError{Title: title})
+		body["suggestions"] = store.SuggestTitles(title, maxSuggestions)
+		respond(c, http.StatusNotFound, body)
 	}
 }
 
 func getAppByTitleAndVersion(c *gin.Context) {
-	title := c.Param("title")
+	title, ok := resolveTitle(c)
+	if !ok {
+		return
+	}
 	versionText := c.Param("version")
 	version, err := semver.Parse(versionText)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, responseBodyForErrorMessage("Bad format of version '%s'", versionText))
+		respond(c, http.StatusBadRequest, responseBodyForError(badVersionParam(versionText, err)))
+		return
 	}
-	app := store.GetByTitleAndVersion(title, version)
-	if app != nil {
-		c.JSON(http.StatusOK, app)
+	meta := store.GetByTitleAndVersion(title, version)
+	if meta != nil {
+		respondApp(c, http.StatusOK, *meta)
 	} else {
-		c.JSON(http.StatusNotFound, responseBodyForErrorMessage("App with title '%s' and version '%s' does not exist.", title, version))
+		respond(c, http.StatusNotFound, responseBodyForError(&app.NotFoundError{Title: title, Version: version}))
 	}
 }
 
@@ -65,51 +120,256 @@ func listApps(c *gin.Context) {
 	q := c.Request.URL.Query()
 	flt, err := filter.CreateRuleSet(q, app)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, responseBodyForError(err))
+		respond(c, http.StatusBadRequest, responseBodyForError(err))
 		return
 	}
 
 	result, err := store.List(flt)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, responseBodyForError(err))
+		respond(c, http.StatusBadRequest, responseBodyForError(err))
 		return
 	}
-	c.JSON(http.StatusOK, result)
+	respond(c, http.StatusOK, newAppResources(result))
+}
+
+// searchRequest is the request body to save a search.
+// The filter is in query string form, e.g. "title[like]=App&version[gt]=0.0.1",
+// sort keys are field names optionally prefixed with "-" for descending order.
+type searchRequest struct {
+	Name   string `binding:"required"`
+	Filter string
+	Sort   []string
+	Fields []string
 }
 
-func responseBodyForError(err error) map[string]interface{} {
-	return responseBodyForErrorMessage(err.Error())
+func newSearch(c *gin.Context) {
+	var req searchRequest
+	if err := bindBody(c, &req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	var meta app.Meta
+	ruleSet, err := filter.ParseRuleSetQuery(req.Filter, meta)
+	if err != nil {
+		respond(c, http.StatusBadRequest, responseBodyForError(err))
+		return
+	}
+
+	search, err := app.NewSearch(req.Name, ruleSet, req.Sort, req.Fields)
+	if err != nil {
+		respond(c, http.StatusBadRequest, responseBodyForError(err))
+		return
+	}
+
+	if err := store.AddSearch(search); err != nil {
+		respond(c, http.StatusConflict, responseBodyForError(err))
+		return
+	}
+	if err := persistStore(); err != nil {
+		respond(c, http.StatusInternalServerError, responseBodyForError(err))
+		return
+	}
+	respond(c, http.StatusCreated, search)
+}
+
+func listSearches(c *gin.Context) {
+	respond(c, http.StatusOK, store.ListSearches())
+}
+
+func getSearchResults(c *gin.Context) {
+	name := c.Param("name")
+	search := store.GetSearch(name)
+	if search == nil {
+		respond(c, http.StatusNotFound, responseBodyForErrorMessage("Search with name '%s' does not exist.", name))
+		return
+	}
+
+	result, err := store.RunSearch(*search)
+	if err != nil {
+		respond(c, http.StatusUnprocessableEntity, responseBodyForError(err))
+		return
+	}
+
+	if len(search.Fields) == 0 {
+		respond(c, http.StatusOK, newAppResources(result))
+		return
+	}
+
+	selected := make([]map[string]interface{}, 0, len(result))
+	for _, app := range result {
+		fields, err := filter.SelectFields(app, search.Fields)
+		if err != nil {
+			respond(c, http.StatusUnprocessableEntity, responseBodyForError(err))
+			return
+		}
+		fields["slug"] = app.Slug()
+		fields["links"] = newAppLinks(app)
+		selected = append(selected, fields)
+	}
+	respond(c, http.StatusOK, selected)
 }
 
-// responseBodyForErrorMessage is aimed to format the response body of bad requests.
-// All the bad requests will go through this function so that the error responses will have the same format.
-// This makes the consumer of our APIs easier to write error handling logic.
-func responseBodyForErrorMessage(format string, a ...interface{}) map[string]interface{} {
-	return gin.H{"error": fmt.Sprintf(format, a...)}
+// suggestValues returns type-ahead suggestions for the search box, e.g.
+//
+//	GET /_suggest?prefix=Ap&field=title&limit=5&rank=recency
+//
+// field could be "title" (default) or "maintainer", rank could be "popularity" (default) or "recency".
+func suggestValues(c *gin.Context) {
+	field := c.DefaultQuery("field", app.SuggestFieldTitle)
+	prefix := c.Query("prefix")
+
+	limit := defaultSuggestLimit
+	if text, ok := c.GetQuery("limit"); ok {
+		parsed, err := strconv.Atoi(text)
+		if err != nil || parsed <= 0 {
+			respond(c, http.StatusBadRequest, responseBodyForErrorMessage("Bad format of limit '%s'", text))
+			return
+		}
+		limit = parsed
+	}
+
+	rank, err := suggest.ParseRank(c.Query("rank"))
+	if err != nil {
+		respond(c, http.StatusBadRequest, responseBodyForError(err))
+		return
+	}
+
+	result, err := store.Suggest(field, prefix, limit, rank)
+	if err != nil {
+		respond(c, http.StatusBadRequest, responseBodyForError(err))
+		return
+	}
+	respond(c, http.StatusOK, result)
 }
 
 func setupServer() *gin.Engine {
 	router := gin.Default()
-	v1 := router.Group("/v1")
+	v1 := router.Group("/v1", negotiateFormat, reloadChangedFiles, authenticate)
 	{
-		v1.POST("/apps", newApp)
-		v1.GET("/apps", listApps)
-		v1.GET("/apps/:title", getAppByTitle)
-		v1.GET("/apps/:title/versions/:version", getAppByTitleAndVersion)
+		read := authorize(auth.ScopeAppsRead)
+		write := authorize(auth.ScopeAppsWrite)
+		admin := authorize(auth.ScopeAdmin)
+		v1.POST("/apps", write, idempotent(newIdempotencyCache(idempotencyWindow)), newApp)
+		v1.POST("/apps/_bulk", write, importApps)
+		v1.GET("/apps", read, listApps)
+		v1.GET("/apps/:title", read, getAppByTitle)
+		v1.GET("/apps/:title/versions/:version", read, getAppByTitleAndVersion)
+		v1.PUT("/apps/:title/versions/:version", write, updateApp)
+		v1.POST("/apps/:title/versions/:version/_yank", write, yankApp)
+		v1.POST("/apps/:title/versions/:version/_unyank", write, unyankApp)
+		v1.GET("/apps/:title/owners", read, getOwners)
+		v1.POST("/apps/:title/owners", write, addOwner)
+		v1.POST("/apps/:title/owners/_transfer", write, transferOwnership)
+		v1.POST("/searches", write, newSearch)
+		v1.GET("/searches", read, listSearches)
+		v1.GET("/searches/:name/results", read, getSearchResults)
+		v1.GET("/_suggest", read, suggestValues)
+		v1.POST("/_import", admin, importCatalog)
+		v1.POST("/_snapshots", admin, newSnapshot)
+		v1.GET("/_snapshots", admin, listSnapshots)
+		v1.POST("/_snapshots/:id/restore", admin, restoreSnapshot)
+		v1.POST("/_keys", admin, newAPIKey)
+		v1.GET("/_keys", admin, listAPIKeys)
+		v1.DELETE("/_keys/:id", admin, revokeAPIKey)
 	}
 
+	// The export API responds archives regardless of the Accept header.
+	router.GET("/v1/_export", reloadChangedFiles, authenticate, authorize(auth.ScopeAdmin), exportCatalog)
+	router.GET("/healthz", healthz)
+	router.GET("/readyz", readyz)
+
 	return router
 }
 
 // This function sets up a new, empty store.
 // It is supposed to be called when the app starts up.
 // It could also be called from the integration test in order to get a clean store for each test scenario.
+// When dataFile is set, the store is loaded from it.
 func setupStore() {
 	var emptyStore app.Store
 	store = &emptyStore
+	store.SnapshotRetention = snapshotRetention
+
+	if dataFile != "" {
+		if err := store.LoadFile(dataFile); err != nil {
+			log.Fatalf("Failed to set up store: %s", err)
+		}
+	}
+	onShutdown("store", flushStore)
+}
+
+// flushStore saves the store into dataFile, if set, when the server shuts down.
+// When the file has been changed by others, e.g. the import command, it is reloaded instead of overwritten,
+// since the changes made by the server have been persisted already.
+func flushStore(context.Context) error {
+	if dataFile == "" {
+		return nil
+	}
+	if reloaded, err := store.ReloadFileIfChanged(dataFile); reloaded || err != nil {
+		return err
+	}
+	return store.SaveFile(dataFile)
+}
+
+// reloadChangedFiles is a middleware which reloads the store and the API keys when their files have been changed by others,
+// e.g. by the import and keys commands next to the running server, so that the changes take effect at once
+// and are not overwritten by the server. It responds 500 when a changed file could not be reloaded.
+func reloadChangedFiles(c *gin.Context) {
+	if dataFile != "" {
+		if _, err := store.ReloadFileIfChanged(dataFile); err != nil {
+			respond(c, http.StatusInternalServerError, responseBodyForError(err))
+			c.Abort()
+			return
+		}
+	}
+	if apiKeys != nil && apiKeysFile != "" {
+		if _, err := apiKeys.ReloadFileIfChanged(apiKeysFile); err != nil {
+			respond(c, http.StatusInternalServerError, responseBodyForError(err))
+			c.Abort()
+			return
+		}
+	}
+	c.Next()
+}
+
+// persistStore saves the store into dataFile, if set.
+// It is supposed to be called after each modification of the store,
+// and the modification should be reported as failed if it returns an error, since it could be lost on restart.
+func persistStore() error {
+	if dataFile == "" {
+		return nil
+	}
+
+	if err := store.SaveFile(dataFile); err != nil {
+		log.Printf("Failed to persist store: %s", err)
+		return fmt.Errorf("Failed to persist store: %w", err)
+	}
+	return nil
 }
 
 func main() {
-	setupStore()
-	setupServer().Run(":3001")
+	dataFile = os.Getenv("APPSTORE_DATA_FILE")
+	var err error
+	if snapshotRetention, err = snapshotRetentionFromEnv(); err != nil {
+		log.Fatal(err)
+	}
+	if idempotencyWindow, err = idempotencyWindowFromEnv(); err != nil {
+		log.Fatal(err)
+	}
+	if publishingPolicy, err = publishingPolicyFromEnv(); err != nil {
+		log.Fatal(err)
+	}
+	if drainPeriod, shutdownTimeout, err = shutdownFromEnv(); err != nil {
+		log.Fatal(err)
+	}
+	if tlsConfig, err = tlsConfigFromEnv(); err != nil {
+		log.Fatal(err)
+	}
+	if err := setupAuthFromEnv(); err != nil {
+		log.Fatal(err)
+	}
+	if err := runCommand(os.Args[1:], os.Stdout); err != nil {
+		log.Fatal(err)
+	}
 }
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zzn2/demo/appstore/app"
+)
+
+// resolveTitle resolves the :title path parameter, which could be either the slug or the title of an app, into the title.
+// When the app exists but the parameter is not its slug, e.g. "App1" or "APP1" for the slug "app1",
+// it redirects to the same URL with the canonical slug and returns false.
+// The parameter itself is returned as the title if the app does not exist, so that the handlers could respond 404 with it.
+func resolveTitle(c *gin.Context) (string, bool) {
+	param := c.Param("title")
+	slug := app.Slugify(param)
+	title, ok := store.ResolveTitle(slug)
+	if !ok {
+		return param, true
+	}
+	if param == slug {
+		return title, true
+	}
+
+	// The path is /v1/apps/:title[/...], only the segment of :title is replaced.
+	segments := strings.SplitN(c.Request.URL.EscapedPath(), "/", 5)
+	segments[3] = url.PathEscape(slug)
+	location := strings.Join(segments, "/")
+	if c.Request.URL.RawQuery != "" {
+		location += "?" + c.Request.URL.RawQuery
+	}
+
+	// 308 keeps the method and the body of the request, while 301 is better understood for GET.
+	code := http.StatusPermanentRedirect
+	if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+		code = http.StatusMovedPermanently
+	}
+	c.Redirect(code, location)
+	return "", false
+}
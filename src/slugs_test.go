@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRedirectToCanonicalSlug(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	ts := httptest.NewServer(setupServer())
+	defer ts.Close()
+
+	// Do not follow redirects, so that they could be checked.
+	client := &http.Client{CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+
+	setupStore()
+	for _, data := range []string{app1v1, app3WithSpaceInTitle} {
+		if resp, err := http.Post(ts.URL+"/v1/apps", "application/x-yaml", strings.NewReader(data)); err != nil || resp.StatusCode != http.StatusCreated {
+			t.Fatalf("Failed to create app, detail: %v %v", resp, err)
+		}
+	}
+
+	var tests = []struct {
+		method           string
+		path             string
+		expectedCode     int
+		expectedLocation string
+	}{
+		{"GET", "/v1/apps/app1", http.StatusOK, ""},
+		{"GET", "/v1/apps/App1", http.StatusMovedPermanently, "/v1/apps/app1"},
+		{"GET", "/v1/apps/APP1/versions/0.0.1?fields=title", http.StatusMovedPermanently, "/v1/apps/app1/versions/0.0.1?fields=title"},
+		{"GET", "/v1/apps/App3%20with%20space%20in%20title", http.StatusMovedPermanently, "/v1/apps/app3-with-space-in-title"},
+		{"GET", "/v1/apps/app3-with-space-in-title/versions/0.0.1", http.StatusOK, ""},
+		{"PUT", "/v1/apps/App1/versions/0.0.1", http.StatusPermanentRedirect, "/v1/apps/app1/versions/0.0.1"},
+		{"PUT", "/v1/apps/app1/versions/0.0.1", http.StatusOK, ""},
+		{"GET", "/v1/apps/App2", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, ts.URL+tt.path, strings.NewReader(app1v1))
+			req.Header.Set("Content-Type", "application/x-yaml")
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("Error occurred during %s %s, detail: %e", tt.method, tt.path, err)
+			}
+			if resp.StatusCode != tt.expectedCode {
+				t.Errorf("Expected status code to be %d but got %d", tt.expectedCode, resp.StatusCode)
+			}
+			if location := resp.Header.Get("Location"); location != tt.expectedLocation {
+				t.Errorf("Expected Location to be '%s' but got '%s'", tt.expectedLocation, location)
+			}
+		})
+	}
+}
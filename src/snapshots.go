@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zzn2/demo/appstore/app"
+)
+
+// snapshotRetention is the retention policy of the snapshots of the store.
+// The default policy of the store is used when nil.
+var snapshotRetention *app.RetentionPolicy
+
+func newSnapshot(c *gin.Context) {
+	respond(c, http.StatusCreated, store.Snapshot(c.Query("label")))
+}
+
+func listSnapshots(c *gin.Context) {
+	respond(c, http.StatusOK, store.ListSnapshots())
+}
+
+func restoreSnapshot(c *gin.Context) {
+	info, err := store.RestoreSnapshot(c.Param("id"))
+	if err != nil {
+		respond(c, http.StatusNotFound, responseBodyForError(err))
+		return
+	}
+
+	if err := persistStore(); err != nil {
+		respond(c, http.StatusInternalServerError, responseBodyForError(err))
+		return
+	}
+	respond(c, http.StatusOK, info)
+}
+
+// snapshotRetentionFromEnv reads the snapshot retention policy from environment variables:
+//
+//    APPSTORE_SNAPSHOT_MAX_COUNT  -> max number of snapshots kept, e.g. 10
+//    APPSTORE_SNAPSHOT_MAX_AGE    -> how long a snapshot is kept, e.g. 24h
+//
+// The default retention policy is used for the one not set. It returns nil when neither is set.
+func snapshotRetentionFromEnv() (*app.RetentionPolicy, error) {
+	maxCount := os.Getenv("APPSTORE_SNAPSHOT_MAX_COUNT")
+	maxAge := os.Getenv("APPSTORE_SNAPSHOT_MAX_AGE")
+	if maxCount == "" && maxAge == "" {
+		return nil, nil
+	}
+
+	policy := app.DefaultSnapshotRetention
+	var err error
+	if maxCount != "" {
+		if policy.MaxCount, err = strconv.Atoi(maxCount); err != nil || policy.MaxCount < 0 {
+			return nil, fmt.Errorf("Bad format of APPSTORE_SNAPSHOT_MAX_COUNT '%s'", maxCount)
+		}
+	}
+	if maxAge != "" {
+		if policy.MaxAge, err = time.ParseDuration(maxAge); err != nil || policy.MaxAge < 0 {
+			return nil, fmt.Errorf("Bad format of APPSTORE_SNAPSHOT_MAX_AGE '%s'", maxAge)
+		}
+	}
+	return &policy, nil
+}
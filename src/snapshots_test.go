@@ -0,0 +1,44 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/zzn2/demo/appstore/app"
+)
+
+func TestSnapshotRetentionFromEnv(t *testing.T) {
+	tests := []struct {
+		maxCount string
+		maxAge   string
+		expected *app.RetentionPolicy
+		err      string
+	}{
+		{"", "", nil, ""},
+		{"5", "", &app.RetentionPolicy{MaxCount: 5, MaxAge: app.DefaultSnapshotRetention.MaxAge}, ""},
+		{"", "24h", &app.RetentionPolicy{MaxCount: app.DefaultSnapshotRetention.MaxCount, MaxAge: 24 * time.Hour}, ""},
+		{"0", "30m", &app.RetentionPolicy{MaxAge: 30 * time.Minute}, ""},
+		{"many", "", nil, "Bad format of APPSTORE_SNAPSHOT_MAX_COUNT 'many'"},
+		{"", "1 day", nil, "Bad format of APPSTORE_SNAPSHOT_MAX_AGE '1 day'"},
+	}
+
+	for _, test := range tests {
+		t.Setenv("APPSTORE_SNAPSHOT_MAX_COUNT", test.maxCount)
+		t.Setenv("APPSTORE_SNAPSHOT_MAX_AGE", test.maxAge)
+
+		policy, err := snapshotRetentionFromEnv()
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("Expected error '%s' but got %v.", test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Should not have error but error '%s' occurred.", err.Error())
+		}
+		if !reflect.DeepEqual(policy, test.expected) {
+			t.Errorf("Expected policy %+v but got %+v.", test.expected, policy)
+		}
+	}
+}
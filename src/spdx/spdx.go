@@ -0,0 +1,196 @@
+// Package spdx validates SPDX license expressions, e.g. "MIT OR Apache-2.0".
+// License and exception identifiers are checked against the lists embedded in this package.
+// See https://spdx.github.io/spdx-spec/v2.3/SPDX-license-expressions/ for the syntax.
+package spdx
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+//go:embed licenses.txt
+var licenseList string
+
+//go:embed exceptions.txt
+var exceptionList string
+
+// licenses and exceptions map the lower case identifiers to the canonical ones, since identifiers are case insensitive.
+var (
+	licenses   = parseList(licenseList)
+	exceptions = parseList(exceptionList)
+)
+
+// licenseRef matches user defined licenses, e.g. "LicenseRef-Proprietary" or "DocumentRef-spdx-tool-1.2:LicenseRef-MIT-Style-2".
+var licenseRef = regexp.MustCompile(`^(DocumentRef-[A-Za-z0-9.-]+:)?LicenseRef-[A-Za-z0-9.-]+$`)
+
+// ErrInvalidExpression is the kind of ParseError, matched by errors.Is.
+var ErrInvalidExpression = errors.New("invalid SPDX license expression")
+
+// ParseError is returned when a text is not a valid SPDX license expression.
+type ParseError struct {
+	Expression string
+	Reason     string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("Invalid SPDX license expression '%s': %s", e.Expression, e.Reason)
+}
+
+// Is makes errors.Is(err, ErrInvalidExpression) report true for a ParseError.
+func (e *ParseError) Is(target error) bool {
+	return target == ErrInvalidExpression
+}
+
+// IsLicense tells whether id is a known SPDX license identifier. The comparison is case insensitive.
+func IsLicense(id string) bool {
+	_, ok := licenses[strings.ToLower(id)]
+	return ok
+}
+
+// IsException tells whether id is a known SPDX license exception identifier. The comparison is case insensitive.
+func IsException(id string) bool {
+	_, ok := exceptions[strings.ToLower(id)]
+	return ok
+}
+
+// Validate checks that the text is a valid SPDX license expression, e.g. "MIT", "GPL-2.0-or-later WITH Classpath-exception-2.0"
+// or "(MIT OR Apache-2.0) AND BSD-3-Clause". Operators are either all upper case or all lower case.
+// It returns *ParseError if the expression is malformed or refers to unknown licenses or exceptions.
+func Validate(expression string) error {
+	p := parser{tokens: tokenize(expression)}
+	reason := p.parseOr()
+	if reason == "" && p.pos < len(p.tokens) {
+		reason = fmt.Sprintf("Unexpected '%s'", p.tokens[p.pos])
+	}
+	if reason != "" {
+		return &ParseError{expression, reason}
+	}
+	return nil
+}
+
+// parser is a recursive descent parser of license expressions, the precedence of the operators is WITH > AND > OR.
+// The parse methods return the reason of the failure, or empty string on success.
+type parser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *parser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+// acceptOperator consumes the next token if it is the operator in either upper or lower case.
+func (p *parser) acceptOperator(operator string) bool {
+	token := p.peek()
+	if token == operator || token == strings.ToLower(operator) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) parseOr() string {
+	if reason := p.parseAnd(); reason != "" {
+		return reason
+	}
+	for p.acceptOperator("OR") {
+		if reason := p.parseAnd(); reason != "" {
+			return reason
+		}
+	}
+	return ""
+}
+
+func (p *parser) parseAnd() string {
+	if reason := p.parseWith(); reason != "" {
+		return reason
+	}
+	for p.acceptOperator("AND") {
+		if reason := p.parseWith(); reason != "" {
+			return reason
+		}
+	}
+	return ""
+}
+
+func (p *parser) parseWith() string {
+	if p.peek() == "(" {
+		p.pos++
+		if reason := p.parseOr(); reason != "" {
+			return reason
+		}
+		if p.peek() != ")" {
+			return "Missing ')'"
+		}
+		p.pos++
+		return ""
+	}
+
+	if reason := p.parseLicense(); reason != "" {
+		return reason
+	}
+	if p.acceptOperator("WITH") {
+		exception := p.peek()
+		if exception == "" {
+			return "Missing license exception after 'WITH'"
+		}
+		p.pos++
+		if !IsException(exception) {
+			return fmt.Sprintf("Unknown license exception '%s'", exception)
+		}
+	}
+	return ""
+}
+
+func (p *parser) parseLicense() string {
+	token := p.peek()
+	switch {
+	case token == "":
+		return "Unexpected end of expression"
+	case token == ")" || isOperator(token):
+		return fmt.Sprintf("Unexpected '%s'", token)
+	}
+	p.pos++
+
+	if licenseRef.MatchString(token) {
+		return ""
+	}
+	// "+" means the license version or any later version, e.g. "LGPL-2.1+".
+	if !IsLicense(strings.TrimSuffix(token, "+")) {
+		return fmt.Sprintf("Unknown license '%s'", token)
+	}
+	return ""
+}
+
+func isOperator(token string) bool {
+	switch strings.ToUpper(token) {
+	case "AND", "OR", "WITH":
+		return token == strings.ToUpper(token) || token == strings.ToLower(token)
+	}
+	return false
+}
+
+// tokenize splits the expression into identifiers, operators and parentheses.
+func tokenize(expression string) []string {
+	spaced := strings.NewReplacer("(", " ( ", ")", " ) ").Replace(expression)
+	return strings.Fields(spaced)
+}
+
+// parseList parses the embedded list of identifiers, one per line. Blank lines and lines starting with "#" are ignored.
+func parseList(text string) map[string]string {
+	ids := make(map[string]string)
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		ids[strings.ToLower(line)] = line
+	}
+	return ids
+}
@@ -0,0 +1,70 @@
+package spdx
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	var tests = []struct {
+		expression     string
+		expectedReason string
+	}{
+		{"MIT", ""},
+		{"mit", ""},
+		{"Apache-2.0", ""},
+		{"MIT OR Apache-2.0", ""},
+		{"MIT or Apache-2.0", ""},
+		{"MPL-1.1+", ""},
+		{"GPL-2.0-or-later WITH Classpath-exception-2.0", ""},
+		{"(MIT OR Apache-2.0) AND BSD-3-Clause", ""},
+		{"((MIT))", ""},
+		{"LicenseRef-Proprietary", ""},
+		{"DocumentRef-spdx-tool-1.2:LicenseRef-MIT-Style-2", ""},
+		{"", "Unexpected end of expression"},
+		{"Dummy", "Unknown license 'Dummy'"},
+		{"MIT OR Dummy-1.0", "Unknown license 'Dummy-1.0'"},
+		{"MIT Or Apache-2.0", "Unexpected 'Or'"},
+		{"MIT Apache-2.0", "Unexpected 'Apache-2.0'"},
+		{"MIT AND", "Unexpected end of expression"},
+		{"OR MIT", "Unexpected 'OR'"},
+		{"(MIT OR Apache-2.0", "Missing ')'"},
+		{"MIT)", "Unexpected ')'"},
+		{"()", "Unexpected ')'"},
+		{"GPL-2.0-only WITH", "Missing license exception after 'WITH'"},
+		{"GPL-2.0-only WITH Dummy-exception", "Unknown license exception 'Dummy-exception'"},
+		{"MIT WITH Classpath-exception-2.0 WITH LLVM-exception", "Unexpected 'WITH'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			err := Validate(tt.expression)
+			if tt.expectedReason == "" {
+				if err != nil {
+					t.Errorf("Should not have error but error '%s' occurred.", err.Error())
+				}
+				return
+			}
+
+			var parseErr *ParseError
+			if !errors.As(err, &parseErr) || parseErr.Reason != tt.expectedReason {
+				t.Errorf("Expected to have error with reason '%s' but got '%v'", tt.expectedReason, err)
+			}
+			if !errors.Is(err, ErrInvalidExpression) {
+				t.Errorf("Expected error to be ErrInvalidExpression but got '%v'", err)
+			}
+		})
+	}
+}
+
+func TestIsLicense(t *testing.T) {
+	if !IsLicense("Apache-2.0") || !IsLicense("apache-2.0") {
+		t.Errorf("Expected 'Apache-2.0' to be a known license")
+	}
+	if IsLicense("Classpath-exception-2.0") || IsLicense("# SPDX license identifiers, see https://spdx.org/licenses/") {
+		t.Errorf("Expected exceptions and comments not to be known licenses")
+	}
+	if !IsException("LLVM-exception") {
+		t.Errorf("Expected 'LLVM-exception' to be a known license exception")
+	}
+}
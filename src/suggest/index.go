@@ -0,0 +1,180 @@
+// Package suggest provides a prefix index to suggest values as users type.
+//
+// The index is a trie of lower cased values. Each node of the trie caches the top ranked values under it,
+// so that looking up a prefix only walks the prefix itself and the latency does not grow with the number of values.
+package suggest
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Rank defines how suggestions are ordered.
+type Rank int
+
+const (
+	// Popularity ranks the values that were added most times first.
+	Popularity Rank = iota
+	// Recency ranks the values that were added most recently first.
+	Recency
+)
+
+// ParseRank parses text into Rank. Empty text is treated as Popularity.
+func ParseRank(text string) (Rank, error) {
+	switch strings.ToLower(text) {
+	case "", "popularity":
+		return Popularity, nil
+	case "recency":
+		return Recency, nil
+	default:
+		return Popularity, fmt.Errorf("Unrecognized rank '%s'", text)
+	}
+}
+
+// MaxResults is the max number of suggestions could be returned by a single lookup.
+const MaxResults = 20
+
+// entry is a distinct value in the index.
+type entry struct {
+	value string
+	count int
+	seq   uint64
+}
+
+// node is a node of the trie.
+type node struct {
+	children map[rune]*node
+	// popular and recent cache at most MaxResults entries under this node, ordered by the rank.
+	popular []*entry
+	recent  []*entry
+}
+
+// Index is a prefix index of values. It is safe for concurrent use.
+// The zero value is an empty index ready to use.
+type Index struct {
+	mu      sync.RWMutex
+	root    *node
+	entries map[string]*entry
+	seq     uint64
+}
+
+// Add adds a value into the index.
+// Values are distinct case insensitively. Adding an existing value makes it more popular and more recent.
+func (idx *Index) Add(value string) {
+	if value == "" {
+		return
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if idx.root == nil {
+		idx.root = &node{}
+		idx.entries = make(map[string]*entry)
+	}
+
+	key := strings.ToLower(value)
+	e, ok := idx.entries[key]
+	if !ok {
+		e = &entry{value: value}
+		idx.entries[key] = e
+	}
+	idx.seq++
+	e.count++
+	e.seq = idx.seq
+
+	n := idx.root
+	n.update(e)
+	for _, r := range key {
+		child, ok := n.children[r]
+		if !ok {
+			if n.children == nil {
+				n.children = make(map[rune]*node)
+			}
+			child = &node{}
+			n.children[r] = child
+		}
+		n = child
+		n.update(e)
+	}
+}
+
+// Reset removes all the values from the index.
+func (idx *Index) Reset() {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.root = nil
+	idx.entries = nil
+	idx.seq = 0
+}
+
+// Lookup returns at most n distinct values starting with the prefix (case insensitive), ordered by the given rank.
+// n is capped by MaxResults.
+func (idx *Index) Lookup(prefix string, n int, rank Rank) []string {
+	if n > MaxResults {
+		n = MaxResults
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	result := make([]string, 0)
+	node := idx.root
+	for _, r := range strings.ToLower(prefix) {
+		if node == nil {
+			return result
+		}
+		node = node.children[r]
+	}
+	if node == nil {
+		return result
+	}
+
+	ranked := node.popular
+	if rank == Recency {
+		ranked = node.recent
+	}
+	for i := 0; i < len(ranked) && i < n; i++ {
+		result = append(result, ranked[i].value)
+	}
+	return result
+}
+
+// update puts the entry into the cached top lists of this node.
+// Since an entry only gets more popular and more recent when added, it is enough to re-rank the updated entry.
+func (n *node) update(e *entry) {
+	n.popular = insertRanked(n.popular, e, func(a, b *entry) bool {
+		if a.count != b.count {
+			return a.count > b.count
+		}
+		return a.seq > b.seq
+	})
+	n.recent = insertRanked(n.recent, e, func(a, b *entry) bool {
+		return a.seq > b.seq
+	})
+}
+
+// insertRanked inserts or moves the entry in the list ordered by less, and keeps at most MaxResults entries.
+func insertRanked(list []*entry, e *entry, less func(a, b *entry) bool) []*entry {
+	for i, existing := range list {
+		if existing == e {
+			list = append(list[:i], list[i+1:]...)
+			break
+		}
+	}
+
+	i := sort.Search(len(list), func(i int) bool {
+		return less(e, list[i])
+	})
+	if i >= MaxResults {
+		return list
+	}
+
+	list = append(list, nil)
+	copy(list[i+1:], list[i:])
+	list[i] = e
+	if len(list) > MaxResults {
+		list = list[:MaxResults]
+	}
+	return list
+}
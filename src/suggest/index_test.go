@@ -0,0 +1,113 @@
+package suggest
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestLookup(t *testing.T) {
+	var idx Index
+	idx.Add("App1")
+	idx.Add("App2")
+	idx.Add("app1")
+	idx.Add("Another")
+	idx.Add("Bpp")
+
+	var tests = []struct {
+		prefix   string
+		n        int
+		rank     Rank
+		expected []string
+	}{
+		{"Ap", 10, Popularity, []string{"App1", "App2"}},
+		{"ap", 10, Recency, []string{"App1", "App2"}},
+		{"A", 10, Recency, []string{"Another", "App1", "App2"}},
+		{"A", 1, Popularity, []string{"App1"}},
+		{"", 10, Recency, []string{"Bpp", "Another", "App1", "App2"}},
+		{"Zz", 10, Popularity, []string{}},
+		{"App12", 10, Popularity, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s-%d-%d", tt.prefix, tt.n, tt.rank), func(t *testing.T) {
+			result := idx.Lookup(tt.prefix, tt.n, tt.rank)
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("Expected to be %v but got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestLookup_Empty(t *testing.T) {
+	var idx Index
+	if result := idx.Lookup("a", 10, Popularity); len(result) != 0 {
+		t.Errorf("Expected to be empty but got %v", result)
+	}
+
+	idx.Add("abc")
+	idx.Reset()
+	if result := idx.Lookup("a", 10, Popularity); len(result) != 0 {
+		t.Errorf("Expected to be empty but got %v", result)
+	}
+}
+
+func TestLookup_MaxResults(t *testing.T) {
+	var idx Index
+	for i := 0; i < MaxResults*2; i++ {
+		idx.Add(fmt.Sprintf("app%d", i))
+	}
+	// Make the earliest value the most popular one.
+	idx.Add("app0")
+
+	result := idx.Lookup("app", MaxResults*2, Popularity)
+	if len(result) != MaxResults {
+		t.Fatalf("Expected to be %d results but got %d", MaxResults, len(result))
+	}
+	if result[0] != "app0" {
+		t.Errorf("Expected the most popular to be 'app0' but got '%s'", result[0])
+	}
+
+	result = idx.Lookup("app", 2, Recency)
+	expected := []string{"app0", fmt.Sprintf("app%d", MaxResults*2-1)}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected to be %v but got %v", expected, result)
+	}
+}
+
+func TestParseRank(t *testing.T) {
+	var tests = []struct {
+		text         string
+		expected     Rank
+		errorMessage string
+	}{
+		{"", Popularity, ""},
+		{"Popularity", Popularity, ""},
+		{"recency", Recency, ""},
+		{"dummy", Popularity, "Unrecognized rank 'dummy'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			rank, err := ParseRank(tt.text)
+			if rank != tt.expected {
+				t.Errorf("Expected to be %v but got %v", tt.expected, rank)
+			}
+			if err != nil && err.Error() != tt.errorMessage {
+				t.Errorf("Expect error message '%s' but got '%s'.", tt.errorMessage, err.Error())
+			}
+		})
+	}
+}
+
+func BenchmarkLookup(b *testing.B) {
+	var idx Index
+	for i := 0; i < 100000; i++ {
+		idx.Add(fmt.Sprintf("app%d", i))
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		idx.Lookup("a", 10, Popularity)
+	}
+}
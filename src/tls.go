@@ -0,0 +1,51 @@
+package main
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/zzn2/demo/appstore/certs"
+)
+
+// tlsConfig is the TLS configuration of the server, which serves plain HTTP when nil.
+var tlsConfig *tls.Config
+
+// tlsConfigFromEnv loads the TLS configuration from the files in environment variables:
+//
+//    APPSTORE_TLS_CERT_FILE       -> PEM encoded certificate chain of the server
+//    APPSTORE_TLS_KEY_FILE        -> PEM encoded private key of the server
+//    APPSTORE_TLS_CLIENT_CA_FILE  -> PEM encoded CA certificates to require and verify client certificates (mutual TLS)
+//
+// The files are reloaded when they change on disk. It returns nil if the certificate and the key are not set.
+func tlsConfigFromEnv() (*tls.Config, error) {
+	config := certs.Config{
+		CertFile:     os.Getenv("APPSTORE_TLS_CERT_FILE"),
+		KeyFile:      os.Getenv("APPSTORE_TLS_KEY_FILE"),
+		ClientCAFile: os.Getenv("APPSTORE_TLS_CLIENT_CA_FILE"),
+	}
+	if config == (certs.Config{}) {
+		return nil, nil
+	}
+
+	reloader, err := certs.NewReloader(config)
+	if err != nil {
+		return nil, err
+	}
+	return reloader.TLSConfig(), nil
+}
+
+// newHTTPServer creates the server listening on listenAddr, with tlsConfig if set.
+func newHTTPServer(handler http.Handler) *http.Server {
+	return &http.Server{Addr: listenAddr, Handler: handler, TLSConfig: tlsConfig}
+}
+
+// serveOn serves HTTPS on the listener, with HTTP/2 enabled, when the server has a TLS configuration, otherwise plain HTTP.
+func serveOn(srv *http.Server, ln net.Listener) error {
+	if srv.TLSConfig != nil {
+		// The certificates are provided by the TLS configuration instead of files.
+		return srv.ServeTLS(ln, "", "")
+	}
+	return srv.Serve(ln)
+}
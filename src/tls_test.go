@@ -0,0 +1,126 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// testCA issues certificates for the TLS tests.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T) testCA {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return testCA{cert, key}
+}
+
+// issue issues a certificate for the server or a client, and writes it with its key into the files in dir.
+func (ca testCA) issue(t *testing.T, dir string, name string, usage x509.ExtKeyUsage) tls.Certificate {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	ioutil.WriteFile(filepath.Join(dir, name+".crt"), certPEM, 0600)
+	ioutil.WriteFile(filepath.Join(dir, name+".key"), keyPEM, 0600)
+	cert, _ := tls.X509KeyPair(certPEM, keyPEM)
+	return cert
+}
+
+func TestServeTLS(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	setupStore()
+	defer func(original *tls.Config) { tlsConfig = original }(tlsConfig)
+
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	ca.issue(t, dir, "server", x509.ExtKeyUsageServerAuth)
+	clientCert := ca.issue(t, dir, "client", x509.ExtKeyUsageClientAuth)
+	ioutil.WriteFile(filepath.Join(dir, "ca.crt"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw}), 0600)
+
+	t.Setenv("APPSTORE_TLS_CERT_FILE", filepath.Join(dir, "server.crt"))
+	t.Setenv("APPSTORE_TLS_KEY_FILE", filepath.Join(dir, "server.key"))
+	t.Setenv("APPSTORE_TLS_CLIENT_CA_FILE", filepath.Join(dir, "ca.crt"))
+	var err error
+	if tlsConfig, err = tlsConfigFromEnv(); err != nil {
+		t.Fatalf("Should not have error but error '%s' occurred.", err.Error())
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := newHTTPServer(setupServer())
+	go srv.ServeTLS(ln, "", "")
+	defer srv.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	get := func(certificates []tls.Certificate) (*http.Response, error) {
+		client := http.Client{Transport: &http.Transport{
+			TLSClientConfig:   &tls.Config{RootCAs: roots, Certificates: certificates},
+			ForceAttemptHTTP2: true,
+		}}
+		return client.Get("https://" + ln.Addr().String() + "/v1/apps")
+	}
+
+	resp, err := get([]tls.Certificate{clientCert})
+	if err != nil {
+		t.Fatalf("Should not have error but error '%s' occurred.", err.Error())
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.ProtoMajor != 2 {
+		t.Errorf("Expected 200 over HTTP/2 but got %d over %s", resp.StatusCode, resp.Proto)
+	}
+
+	if _, err := get(nil); err == nil {
+		t.Errorf("Expected clients without certificates to be rejected")
+	}
+	other := newTestCA(t).issue(t, dir, "other", x509.ExtKeyUsageClientAuth)
+	if _, err := get([]tls.Certificate{other}); err == nil {
+		t.Errorf("Expected clients with certificates of other CAs to be rejected")
+	}
+}
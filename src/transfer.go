@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zzn2/demo/appstore/app"
+	"github.com/zzn2/demo/appstore/archive"
+)
+
+// archiveMimeTypes are the media types accepted as the body of an import request.
+var archiveMimeTypes = []string{"application/gzip", "application/x-gzip", "application/octet-stream"}
+
+// exportCatalog streams the content of the store as an archive, i.e. the apps with their owners and yanked versions,
+// and the saved searches. See package archive for the format.
+func exportCatalog(c *gin.Context) {
+	catalog, err := store.Catalog()
+	if err != nil {
+		respond(c, http.StatusInternalServerError, responseBodyForError(err))
+		return
+	}
+
+	now := time.Now()
+	c.Header("Content-Type", "application/gzip")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="appstore-%s.tar.gz"`, now.UTC().Format("20060102T150405Z")))
+	c.Status(http.StatusOK)
+	if err := archive.Write(c.Writer, catalog, now); err != nil {
+		// The response has been partially written, the client will get a broken archive.
+		c.Error(err)
+	}
+}
+
+// importCatalog imports the apps from an archive generated by exportCatalog.
+// The mode could be "merge" (default) or "replace", see app.Store.Import for details.
+// Nothing is imported if any of the apps in the archive is invalid.
+func importCatalog(c *gin.Context) {
+	mode, err := app.ParseImportMode(c.Query("mode"))
+	if err != nil {
+		respond(c, http.StatusBadRequest, responseBodyForError(err))
+		return
+	}
+
+	if contentType := c.ContentType(); contentType != "" && !contains(archiveMimeTypes, contentType) {
+		respond(c, http.StatusUnsupportedMediaType, responseBodyForError(unsupportedMediaTypeError{contentType, archiveMimeTypes}))
+		return
+	}
+
+	if mode == app.ImportReplace {
+		// Replacing the catalog is risky, keep a snapshot so that it could be rolled back.
+		store.Snapshot("Before import in replace mode")
+	}
+
+	result, err := importArchive(c.Request.Body, mode)
+	if err != nil {
+		respond(c, http.StatusBadRequest, responseBodyForError(err))
+		return
+	}
+
+	// Saved searches, owners and yanked versions may have been imported even if no app was created.
+	if err := persistStore(); err != nil {
+		respond(c, http.StatusInternalServerError, responseBodyForError(err))
+		return
+	}
+	respond(c, http.StatusOK, result)
+}
+
+// importArchive reads the content of the store from the archive and imports it into the store.
+// It is shared by the import API and the import command.
+func importArchive(r io.Reader, mode app.ImportMode) (app.ImportResult, error) {
+	catalog, _, err := archive.Read(r)
+	if err != nil {
+		return app.ImportResult{}, err
+	}
+
+	for _, meta := range catalog.Apps {
+		if err := validateApp(meta); err != nil {
+			return app.ImportResult{}, fmt.Errorf("Invalid app '%s' in archive: %w", meta, err)
+		}
+	}
+
+	return store.Import(catalog, mode), nil
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/zzn2/demo/appstore/app"
+	"github.com/zzn2/demo/appstore/semver"
+	"github.com/zzn2/demo/appstore/spdx"
+	"gopkg.in/yaml.v2"
+)
+
+// errValidationFailed is the kind of validationError, matched by errors.Is.
+var errValidationFailed = errors.New("validation failed")
+
+// fieldError is a failure of validating a field in the request body.
+type fieldError struct {
+	// Path is the path of the field in the request body, e.g. "maintainers[0].email".
+	Path string `json:"path" yaml:"path"`
+	// Rule is the failed validation rule, e.g. "required", or "unknown" for keys which are not fields of the request.
+	Rule    string `json:"rule" yaml:"rule"`
+	Message string `json:"message" yaml:"message"`
+}
+
+// validationError reports all the fields failed to validate in the request body.
+type validationError struct {
+	fields []fieldError
+}
+
+func (e *validationError) Error() string {
+	messages := make([]string, 0, len(e.fields))
+	for _, field := range e.fields {
+		messages = append(messages, field.Message)
+	}
+	return fmt.Sprintf("The request body has %d invalid field(s): %s", len(e.fields), strings.Join(messages, " "))
+}
+
+// Is makes errors.Is(err, errValidationFailed) report true for a validationError.
+func (e *validationError) Is(target error) bool {
+	return target == errValidationFailed
+}
+
+// bindApp decodes the request body into app metadata like bindBody, and validates it.
+// Unlike bindBody, all the invalid fields are reported at once by *validationError,
+// including the keys which are not fields of the app metadata.
+func bindApp(c *gin.Context, meta *app.Meta) error {
+	contentType := c.ContentType()
+	isJSON := contentType == mimeJSON
+	if !isJSON && contentType != "" && !contains(yamlMimeTypes, contentType) {
+		return unsupportedMediaTypeError{contentType, bodyMimeTypes}
+	}
+
+	data, err := ioutil.ReadAll(c.Request.Body)
+	if err != nil {
+		return fmt.Errorf("Failed to read request body: %w", err)
+	}
+	return decodeApp(data, isJSON, meta)
+}
+
+// decodeApp decodes JSON or YAML into app metadata and validates it, reporting all the invalid fields at once
+// by *validationError like bindApp. The app is filled even if it fails to validate, so that the title could be reported.
+func decodeApp(data []byte, isJSON bool, meta *app.Meta) error {
+	var generic interface{}
+	var err error
+	if isJSON {
+		err = json.Unmarshal(data, &generic)
+	} else {
+		err = yaml.Unmarshal(data, &generic)
+	}
+	if err != nil {
+		return err
+	}
+	fields := unknownKeys(generic, reflect.TypeOf(*meta), "", isJSON)
+
+	if isJSON {
+		err = json.Unmarshal(data, meta)
+	} else {
+		err = yaml.Unmarshal(data, meta)
+	}
+	if err != nil {
+		return err
+	}
+
+	return newValidationError(append(fields, validateFields(*meta)...))
+}
+
+// newValidationError returns *validationError with the fields ordered as declared in app.Meta, or nil without fields.
+func newValidationError(fields []fieldError) error {
+	if len(fields) == 0 {
+		return nil
+	}
+	sort.SliceStable(fields, func(i, j int) bool {
+		return fieldOrder(fields[i].Path) < fieldOrder(fields[j].Path)
+	})
+	return &validationError{fields}
+}
+
+// validateFields validates app metadata with the binding tags and the rules not covered by them,
+// i.e. the title could be turned into a slug and the version is present.
+func validateFields(meta app.Meta) []fieldError {
+	fields := make([]fieldError, 0)
+	var validationErrors validator.ValidationErrors
+	if err := binding.Validator.ValidateStruct(meta); errors.As(err, &validationErrors) {
+		for _, fe := range validationErrors {
+			field := newFieldError(fieldPath(fe.Namespace()), fe.Tag(), fe.Param())
+			// Tell which part of the license expression is invalid.
+			var parseErr *spdx.ParseError
+			if fe.Tag() == tagSPDX && errors.As(spdx.Validate(fmt.Sprint(fe.Value())), &parseErr) {
+				field.Message = fmt.Sprintf("'%s' must be a valid SPDX license expression, e.g. 'MIT OR Apache-2.0': %s.", field.Path, parseErr.Reason)
+			}
+			fields = append(fields, field)
+		}
+	}
+
+	if meta.Title != "" && meta.Slug() == "" {
+		fields = append(fields, newFieldError("title", "slug", ""))
+	}
+	if meta.Version == semver.Empty {
+		fields = append(fields, newFieldError("version", "required", ""))
+	}
+	return fields
+}
+
+func newFieldError(path string, rule string, param string) fieldError {
+	var message string
+	switch rule {
+	case "required":
+		message = fmt.Sprintf("'%s' is required.", path)
+	case "email":
+		message = fmt.Sprintf("'%s' must be a valid email address.", path)
+	case tagHTTPURL:
+		message = fmt.Sprintf("'%s' must be an http or https URL.", path)
+	case tagVCSURL:
+		message = fmt.Sprintf("'%s' must be the http or https URL of a repository, e.g. 'https://github.com/owner/repo', or end with '.git'.", path)
+	case tagSPDX:
+		message = fmt.Sprintf("'%s' must be a valid SPDX license expression, e.g. 'MIT OR Apache-2.0'.", path)
+	case "slug":
+		message = fmt.Sprintf("'%s' must contain at least one letter or digit.", path)
+	case "unknown":
+		message = fmt.Sprintf("'%s' is not a known field.", path)
+	default:
+		if param != "" {
+			message = fmt.Sprintf("'%s' failed on the '%s=%s' rule.", path, rule, param)
+		} else {
+			message = fmt.Sprintf("'%s' failed on the '%s' rule.", path, rule)
+		}
+	}
+	return fieldError{path, rule, message}
+}
+
+// fieldPath converts the namespace of a validation error into the path in the request body,
+// e.g. "Meta.Maintainers[0].Email" into "maintainers[0].email".
+func fieldPath(namespace string) string {
+	parts := strings.Split(namespace, ".")
+	return strings.ToLower(strings.Join(parts[1:], "."))
+}
+
+// fieldOrder returns the index of the top level field of the path in app.Meta,
+// so that the invalid fields are reported in the order they are declared. Unknown keys go first.
+func fieldOrder(path string) int {
+	name := strings.SplitN(strings.SplitN(path, ".", 2)[0], "[", 2)[0]
+	if field, ok := reflect.TypeOf(app.Meta{}).FieldByNameFunc(func(fieldName string) bool {
+		return strings.EqualFold(name, fieldName)
+	}); ok {
+		return field.Index[0]
+	}
+	return -1
+}
+
+// unknownKeys returns the keys in the decoded body which are not fields of the struct type t.
+// Keys of JSON bodies are matched to field names case insensitively as encoding/json does,
+// while keys of YAML bodies must be the lower case field names as yaml.v2 requires.
+func unknownKeys(value interface{}, t reflect.Type, path string, isJSON bool) []fieldError {
+	fields := make([]fieldError, 0)
+	textUnmarshaler := reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	if reflect.PtrTo(t).Implements(textUnmarshaler) {
+		return fields
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		entries := make(map[string]interface{})
+		switch value := value.(type) {
+		case map[string]interface{}:
+			entries = value
+		case map[interface{}]interface{}:
+			for k, v := range value {
+				entries[fmt.Sprint(k)] = v
+			}
+		}
+
+		keys := make([]string, 0, len(entries))
+		for key := range entries {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			field, ok := t.FieldByNameFunc(func(fieldName string) bool {
+				if isJSON {
+					return strings.EqualFold(key, fieldName)
+				}
+				return key == strings.ToLower(fieldName)
+			})
+			if !ok {
+				fields = append(fields, newFieldError(joinPath(path, key), "unknown", ""))
+				continue
+			}
+			fields = append(fields, unknownKeys(entries[key], field.Type, joinPath(path, strings.ToLower(field.Name)), isJSON)...)
+		}
+	case reflect.Slice:
+		if items, ok := value.([]interface{}); ok {
+			for i, item := range items {
+				fields = append(fields, unknownKeys(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i), isJSON)...)
+			}
+		}
+	}
+	return fields
+}
+
+func joinPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/zzn2/demo/appstore/app"
+	"github.com/zzn2/demo/appstore/semver"
+	"gopkg.in/yaml.v2"
+)
+
+func TestUnknownKeys(t *testing.T) {
+	var tests = []struct {
+		name     string
+		body     string
+		isJSON   bool
+		expected []string
+	}{
+		{"YAML known keys", "title: App1\nversion: 0.0.1\nmaintainers:\n- name: Alice\n  email: alice@hotmail.com\n", false, []string{}},
+		{"YAML keys are case sensitive", "Title: App1\n", false, []string{"Title"}},
+		{"YAML nested unknown keys", "maintainers:\n- name: Alice\n- phone: 123\nextra:\n  a: 1\n", false, []string{"extra", "maintainers[1].phone"}},
+		{"JSON keys are case insensitive", `{"Title":"App1","version":"0.0.1","Maintainers":[{"NAME":"Alice"}]}`, true, []string{}},
+		{"JSON nested unknown keys", `{"Maintainers":[{"Name":"Alice","Phone":"123"}],"Dummy":1}`, true, []string{"Dummy", "maintainers[0].Phone"}},
+		{"Version is not a struct of fields", `{"Version":{"Major":1}}`, true, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var generic interface{}
+			var err error
+			if tt.isJSON {
+				err = json.Unmarshal([]byte(tt.body), &generic)
+			} else {
+				err = yaml.Unmarshal([]byte(tt.body), &generic)
+			}
+			if err != nil {
+				t.Fatalf("Should not have error but error '%s' occurred.", err.Error())
+			}
+
+			paths := make([]string, 0)
+			for _, field := range unknownKeys(generic, reflect.TypeOf(app.Meta{}), "", tt.isJSON) {
+				if field.Rule != "unknown" {
+					t.Errorf("Expected rule to be 'unknown' but got '%s'", field.Rule)
+				}
+				paths = append(paths, field.Path)
+			}
+			if !reflect.DeepEqual(paths, tt.expected) {
+				t.Errorf("Expected unknown keys to be %v but got %v", tt.expected, paths)
+			}
+		})
+	}
+}
+
+func TestValidateFields(t *testing.T) {
+	meta := app.Meta{
+		Title:       "App1",
+		Maintainers: []app.Maintainer{{Name: "Alice", Email: "alice@hotmail.com"}, {Email: "malformed"}},
+		Company:     "Random Inc.",
+		Website:     "https://website.com",
+		Source:      "https://github.com/random/repo",
+		License:     "MIT",
+		Description: "desc",
+	}
+
+	expected := []fieldError{
+		{"maintainers[1].name", "required", "'maintainers[1].name' is required."},
+		{"maintainers[1].email", "email", "'maintainers[1].email' must be a valid email address."},
+		{"version", "required", "'version' is required."},
+	}
+	if fields := validateFields(meta); !reflect.DeepEqual(fields, expected) {
+		t.Errorf("Expected field errors to be %v but got %v", expected, fields)
+	}
+
+	meta.Version = semver.Version{Patch: 1}
+	meta.Maintainers = meta.Maintainers[:1]
+	if fields := validateFields(meta); len(fields) != 0 {
+		t.Errorf("Expected no field errors but got %v", fields)
+	}
+}
+
+func TestDomainValidators(t *testing.T) {
+	var tests = []struct {
+		text      string
+		isHTTPURL bool
+		isVCSURL  bool
+	}{
+		{"https://website.com", true, false},
+		{"http://website.com/path", true, false},
+		{"ftp://website.com", false, false},
+		{"website.com", false, false},
+		{"https://", false, false},
+		{"https://github.com/random/repo", true, true},
+		{"https://www.github.com/random/repo/", true, true},
+		{"https://gitlab.com/group/subgroup/repo", true, true},
+		{"https://github.com/random", true, false},
+		{"https://git.example.com/repo.git", true, true},
+		{"https://git.example.com/.git", true, false},
+		{"git@github.com:random/repo.git", false, false},
+		{"ssh://git@github.com/random/repo.git", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if actual := isHTTPURL(tt.text); actual != tt.isHTTPURL {
+				t.Errorf("Expected isHTTPURL to be %t but got %t", tt.isHTTPURL, actual)
+			}
+			if actual := isVCSURL(tt.text); actual != tt.isVCSURL {
+				t.Errorf("Expected isVCSURL to be %t but got %t", tt.isVCSURL, actual)
+			}
+		})
+	}
+}
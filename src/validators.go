@@ -0,0 +1,72 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/zzn2/demo/appstore/spdx"
+)
+
+// Tags of the domain validators used in the binding tags of app.Meta.
+const (
+	// tagSPDX validates SPDX license expressions, e.g. "MIT OR Apache-2.0".
+	tagSPDX = "spdx"
+	// tagHTTPURL validates absolute http or https URLs.
+	tagHTTPURL = "httpurl"
+	// tagVCSURL validates http or https URLs of source code repositories.
+	tagVCSURL = "vcsurl"
+)
+
+// vcsHosts are the code hosting services whose repository URLs are recognized by the path "/<owner>/<repo>".
+// Repositories hosted elsewhere are recognized by the ".git" suffix of the path.
+var vcsHosts = []string{
+	"github.com",
+	"gitlab.com",
+	"bitbucket.org",
+	"codeberg.org",
+	"git.sr.ht",
+	"gitee.com",
+	"dev.azure.com",
+	"sourceforge.net",
+}
+
+func init() {
+	engine := binding.Validator.Engine().(*validator.Validate)
+	engine.RegisterValidation(tagSPDX, func(fl validator.FieldLevel) bool {
+		return spdx.Validate(fl.Field().String()) == nil
+	})
+	engine.RegisterValidation(tagHTTPURL, func(fl validator.FieldLevel) bool {
+		return isHTTPURL(fl.Field().String())
+	})
+	engine.RegisterValidation(tagVCSURL, func(fl validator.FieldLevel) bool {
+		return isVCSURL(fl.Field().String())
+	})
+}
+
+// isHTTPURL tells whether the text is an absolute http or https URL with a host.
+func isHTTPURL(text string) bool {
+	u, err := url.Parse(text)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// isVCSURL tells whether the text is an http or https URL of a source code repository,
+// i.e. "/<owner>/<repo>" on one of the vcsHosts, or any URL with a path ending with ".git".
+func isVCSURL(text string) bool {
+	if !isHTTPURL(text) {
+		return false
+	}
+	u, _ := url.Parse(text)
+	path := strings.Trim(u.Path, "/")
+	if strings.HasSuffix(path, ".git") && len(path) > len(".git") {
+		return true
+	}
+
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	segments := strings.Split(path, "/")
+	return contains(vcsHosts, host) && len(segments) >= 2 && segments[0] != "" && segments[1] != ""
+}
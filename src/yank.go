@@ -0,0 +1,54 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zzn2/demo/appstore/app"
+	"github.com/zzn2/demo/appstore/semver"
+)
+
+// yankApp marks an app version as yanked, so that it is no longer resolved as the latest version of the app.
+// Only the owners or the maintainers of the app could yank it, otherwise it responds 403.
+func yankApp(c *gin.Context) {
+	changeYanked(c, store.YankAs)
+}
+
+// unyankApp reverts yankApp, with the same permissions.
+func unyankApp(c *gin.Context) {
+	changeYanked(c, store.UnyankAs)
+}
+
+func changeYanked(c *gin.Context, change func(title string, version semver.Version, publisher app.Publisher) error) {
+	title, ok := resolveTitle(c)
+	if !ok {
+		return
+	}
+	publisher, ok := publisherOf(c)
+	if !ok {
+		return
+	}
+	versionText := c.Param("version")
+	version, err := semver.Parse(versionText)
+	if err != nil {
+		respond(c, http.StatusBadRequest, responseBodyForError(badVersionParam(versionText, err)))
+		return
+	}
+
+	err = change(title, version, publisher)
+	switch {
+	case errors.Is(err, app.ErrNotFound):
+		respond(c, http.StatusNotFound, responseBodyForError(err))
+	case errors.Is(err, app.ErrNotOwner):
+		respond(c, http.StatusForbidden, responseBodyForError(err))
+	case err != nil:
+		respond(c, http.StatusBadRequest, responseBodyForError(err))
+	default:
+		if err := persistStore(); err != nil {
+			respond(c, http.StatusInternalServerError, responseBodyForError(err))
+			return
+		}
+		respondApp(c, http.StatusOK, *store.GetByTitleAndVersion(title, version))
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/zzn2/demo/appstore/auth"
+)
+
+func TestYank(t *testing.T) {
+	ts := newTestServer(t)
+
+	write := []auth.Scope{auth.ScopeAppsRead, auth.ScopeAppsWrite}
+	owner, _, _ := apiKeys.Create("owner", "owner@random.com", write)
+	other, _, _ := apiKeys.Create("other", "other@random.com", write)
+
+	runAPITests(t, ts, []apiTest{
+		{"Publish 0.0.1", "POST", "/v1/apps", owner, app1v1, http.StatusCreated, ""},
+		{"Publish 0.0.2", "POST", "/v1/apps", owner, app1v2, http.StatusCreated, ""},
+		{
+			"Other yanks",
+			"POST", "/v1/apps/app1/versions/0.0.2/_yank", other, "",
+			http.StatusForbidden,
+			`"detail":"'other@random.com' is neither an owner nor a maintainer of the app 'App1'."`,
+		},
+		{
+			"Yank missing version",
+			"POST", "/v1/apps/app1/versions/0.0.9/_yank", owner, "",
+			http.StatusNotFound,
+			`"detail":"App with title 'App1' and version '0.0.9' does not exist."`,
+		},
+		{"Owner yanks", "POST", "/v1/apps/app1/versions/0.0.2/_yank", owner, "", http.StatusOK, `"yanked":true`},
+		{"Latest skips yanked version", "GET", "/v1/apps/app1", owner, "", http.StatusOK, `"version":"0.0.1"`},
+		{"Yanked version is still retrievable", "GET", "/v1/apps/app1/versions/0.0.2", owner, "", http.StatusOK, `"yanked":true`},
+		{"Other unyanks", "POST", "/v1/apps/app1/versions/0.0.2/_unyank", other, "", http.StatusForbidden, ""},
+		{"Owner unyanks", "POST", "/v1/apps/app1/versions/0.0.2/_unyank", owner, "", http.StatusOK, `"version":"0.0.2"`},
+		{"Latest is unyanked version", "GET", "/v1/apps/app1", owner, "", http.StatusOK, `"version":"0.0.2"`},
+	})
+}